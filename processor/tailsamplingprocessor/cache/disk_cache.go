@@ -0,0 +1,353 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cache // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/cache"
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+const (
+	// diskRecordSize is the size of a record of the log: the right half of the trace ID, the value and the expiration
+	// time, in Unix nanoseconds.
+	diskRecordSize = 8 + 1 + 8
+
+	diskValueFalse byte = 0
+	diskValueTrue  byte = 1
+	// diskTombstone marks a deleted trace ID.
+	diskTombstone byte = 2
+
+	// diskFlushInterval is how often the records buffered in memory are written to the log.
+	diskFlushInterval = time.Second
+)
+
+// diskEntry is a decision held by the disk cache.
+type diskEntry struct {
+	value bool
+	// expiration time, in Unix nanoseconds. Zero means the entry never expires.
+	expiresAt int64
+}
+
+// diskDecisionCache implements Cache[bool] as an LRU cache persisted to an append-only log file, so that decisions
+// survive restarts. Every change is appended to the log, which is compacted to the live entries when the cache is
+// opened, and in the background when it holds twice as many records as the cache can hold entries. Records are
+// buffered, and written to the log every diskFlushInterval and when the cache is closed. Reads are not persisted: after a restart, entries are
+// evicted in the order they were last written.
+type diskDecisionCache struct {
+	mu      sync.Mutex
+	logger  *zap.Logger
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	entries *simplelru.LRU[uint64, diskEntry]
+	size    int
+	options options
+	// number of records in the log file, including the buffered ones.
+	records int
+	// compacting is true while the log is being compacted, the records appended meanwhile being kept in pending to be
+	// written to the compacted log.
+	compacting bool
+	pending    []byte
+
+	compactCh chan struct{}
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+var _ Cache[bool] = (*diskDecisionCache)(nil)
+
+// NewDiskDecisionCache returns a new Cache persisting decisions to the file at the given path, which is created if
+// missing. The size parameter indicates the amount of keys the cache will hold before it starts evicting the least
+// recently used key. The returned cache must be closed with io.Closer once no longer used.
+func NewDiskDecisionCache(logger *zap.Logger, path string, size int, opts ...Option) (Cache[bool], error) {
+	return newDiskDecisionCache(logger, path, size, opts...)
+}
+
+func newDiskDecisionCache(logger *zap.Logger, path string, size int, opts ...Option) (*diskDecisionCache, error) {
+	entries, err := simplelru.NewLRU[uint64, diskEntry](size, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &diskDecisionCache{
+		logger:    logger,
+		path:      path,
+		entries:   entries,
+		size:      size,
+		options:   newOptions(opts),
+		compactCh: make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	// Start with a log holding the live entries only.
+	if err := c.compact(); err != nil {
+		return nil, err
+	}
+	go c.flushPeriodically()
+	return c, nil
+}
+
+func (c *diskDecisionCache) Get(id pcommon.TraceID) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := rightHalfTraceID(id)
+	entry, ok := c.entries.Get(key)
+	if !ok {
+		return false, false
+	}
+	if c.options.expired(entry.expiresAt) {
+		c.entries.Remove(key)
+		c.options.expire()
+		return false, false
+	}
+	return entry.value, true
+}
+
+func (c *diskDecisionCache) Put(id pcommon.TraceID, v bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := diskEntry{value: v, expiresAt: c.options.expiresAt()}
+	key := rightHalfTraceID(id)
+	c.entries.Add(key, entry)
+	c.append(key, entry, false)
+}
+
+func (c *diskDecisionCache) Delete(id pcommon.TraceID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := rightHalfTraceID(id)
+	if c.entries.Remove(key) {
+		c.append(key, diskEntry{}, true)
+	}
+}
+
+// Close writes the buffered records to the log, flushes it to disk and closes it.
+func (c *diskDecisionCache) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := errors.Join(c.writer.Flush(), c.file.Sync(), c.file.Close())
+	c.file = nil
+	return err
+}
+
+// flushPeriodically writes the buffered records to the log every diskFlushInterval, and compacts the log when
+// requested by append, until the cache is closed.
+func (c *diskDecisionCache) flushPeriodically() {
+	defer close(c.done)
+	ticker := time.NewTicker(diskFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.flush()
+		case <-c.compactCh:
+			if err := c.compact(); err != nil {
+				c.logger.Warn("Failed to compact the decision cache", zap.String("path", c.path), zap.Error(err))
+			}
+		}
+	}
+}
+
+// flush writes the buffered records to the log.
+func (c *diskDecisionCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return
+	}
+	if err := c.writer.Flush(); err != nil {
+		c.logger.Warn("Failed to persist sampling decisions", zap.String("path", c.path), zap.Error(err))
+	}
+}
+
+// append buffers a record of the log, requesting the compaction of the log if needed.
+func (c *diskDecisionCache) append(key uint64, entry diskEntry, tombstone bool) {
+	if c.file == nil {
+		return
+	}
+
+	record := encodeDiskRecord(key, entry, tombstone)
+	if c.compacting {
+		c.pending = append(c.pending, record...)
+	}
+	if _, err := c.writer.Write(record); err != nil {
+		c.logger.Warn("Failed to persist sampling decision", zap.String("path", c.path), zap.Error(err))
+		return
+	}
+	c.records++
+
+	if c.records >= 2*c.size && !c.compacting {
+		select {
+		case c.compactCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// load replays the log into the cache, skipping expired entries without reporting them. A truncated record at the
+// end of the log, e.g.: left by a crash, is ignored.
+func (c *diskDecisionCache) load() error {
+	file, err := os.Open(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	record := make([]byte, diskRecordSize)
+	for {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+
+		key, entry, tombstone := decodeDiskRecord(record)
+		if tombstone || c.options.expired(entry.expiresAt) {
+			c.entries.Remove(key)
+			continue
+		}
+		c.entries.Add(key, entry)
+	}
+}
+
+// compact rewrites the log with the live entries only, from the least to the most recently used so that replaying it
+// preserves the eviction order, and replaces the current log with it. The live entries are written and flushed to disk
+// without holding the lock, the records appended meanwhile being added to the compacted log once it replaced the
+// current one.
+func (c *diskDecisionCache) compact() error {
+	records := c.startCompaction()
+	file, err := writeCompactedLog(c.path, records)
+	return c.finishCompaction(file, len(records)/diskRecordSize, err)
+}
+
+// startCompaction returns the records of the live entries, removing the expired ones, and starts keeping the records
+// appended until the compaction is finished.
+func (c *diskDecisionCache) startCompaction() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records := make([]byte, 0, c.entries.Len()*diskRecordSize)
+	for _, key := range c.entries.Keys() {
+		entry, _ := c.entries.Peek(key)
+		if c.options.expired(entry.expiresAt) {
+			c.entries.Remove(key)
+			c.options.expire()
+			continue
+		}
+		records = append(records, encodeDiskRecord(key, entry, false)...)
+	}
+	c.compacting = true
+	c.pending = nil
+	return records
+}
+
+// finishCompaction replaces the current log with the compacted one, unless the compaction failed, and adds the
+// records appended since the compaction started to it. The records still buffered for the current log are discarded,
+// the live entries having been written instead.
+func (c *diskDecisionCache) finishCompaction(file *os.File, records int, err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.pending
+	c.compacting = false
+	c.pending = nil
+	if err != nil {
+		return err
+	}
+
+	if c.file != nil {
+		_ = c.file.Close()
+	}
+	c.file = file
+	if c.writer == nil {
+		c.writer = bufio.NewWriter(file)
+	} else {
+		c.writer.Reset(file)
+	}
+	c.records = records
+	if _, err = c.writer.Write(pending); err != nil {
+		return err
+	}
+	c.records += len(pending) / diskRecordSize
+	return nil
+}
+
+// writeCompactedLog writes the given records to a temporary file, flushes it to disk, and renames it to the given path.
+// It returns the renamed file, open for appending.
+func writeCompactedLog(path string, records []byte) (*os.File, error) {
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tmp.Write(records)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err = errors.Join(err, tmp.Close()); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+}
+
+func encodeDiskRecord(key uint64, entry diskEntry, tombstone bool) []byte {
+	record := make([]byte, diskRecordSize)
+	binary.LittleEndian.PutUint64(record[0:8], key)
+	switch {
+	case tombstone:
+		record[8] = diskTombstone
+	case entry.value:
+		record[8] = diskValueTrue
+	default:
+		record[8] = diskValueFalse
+	}
+	binary.LittleEndian.PutUint64(record[9:17], uint64(entry.expiresAt))
+	return record
+}
+
+func decodeDiskRecord(record []byte) (uint64, diskEntry, bool) {
+	key := binary.LittleEndian.Uint64(record[0:8])
+	entry := diskEntry{
+		value:     record[8] == diskValueTrue,
+		expiresAt: int64(binary.LittleEndian.Uint64(record[9:17])),
+	}
+	return key, entry, record[8] == diskTombstone
+}
@@ -0,0 +1,195 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+func newTestDiskCache(t *testing.T, path string, size int, now *time.Time, opts ...Option) *diskDecisionCache {
+	opts = append(opts, withTimeProvider(func() time.Time { return *now }))
+	c, err := newDiskDecisionCache(zap.NewNop(), path, size, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, c.Close())
+	})
+	return c
+}
+
+func TestDiskCachePersistsDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	now := time.Unix(1000, 0)
+	id1, err := traceIDFromHex("12341234123412341234123412341231")
+	require.NoError(t, err)
+	id2, err := traceIDFromHex("12341234123412341234123412341232")
+	require.NoError(t, err)
+	id3, err := traceIDFromHex("12341234123412341234123412341233")
+	require.NoError(t, err)
+
+	c, err := NewDiskDecisionCache(zap.NewNop(), path, 10)
+	require.NoError(t, err)
+	c.Put(id1, true)
+	c.Put(id2, false)
+	c.Put(id3, true)
+	c.Delete(id3)
+	require.NoError(t, c.(*diskDecisionCache).Close())
+
+	reopened := newTestDiskCache(t, path, 10, &now)
+	v, ok := reopened.Get(id1)
+	assert.True(t, v)
+	assert.True(t, ok)
+	v, ok = reopened.Get(id2)
+	assert.False(t, v)
+	assert.True(t, ok)
+	_, ok = reopened.Get(id3)
+	assert.False(t, ok) // deleted
+}
+
+func TestDiskCacheExceedsSizeLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	now := time.Unix(1000, 0)
+	c := newTestDiskCache(t, path, 2, &now)
+
+	ids := make([]pcommon.TraceID, 3)
+	for i := range ids {
+		ids[i] = pcommon.TraceID([16]byte{15: byte(i + 1)})
+	}
+	c.Put(ids[0], true)
+	c.Put(ids[1], true)
+	_, ok := c.Get(ids[0]) // use ids[0]
+	assert.True(t, ok)
+	c.Put(ids[2], true)
+
+	_, ok = c.Get(ids[1])
+	assert.False(t, ok) // evicted
+	require.NoError(t, c.Close())
+
+	// Reads are not persisted: the entries last written survive a restart.
+	reopened := newTestDiskCache(t, path, 2, &now)
+	assert.Equal(t, 2, reopened.entries.Len())
+	_, ok = reopened.Get(ids[0])
+	assert.False(t, ok)
+	_, ok = reopened.Get(ids[2])
+	assert.True(t, ok)
+}
+
+func TestDiskCacheExpiresEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	now := time.Unix(1000, 0)
+	expirations := 0
+	c := newTestDiskCache(t, path, 10, &now, WithTTL(time.Minute), WithExpirationCallback(func() { expirations++ }))
+
+	id1 := pcommon.TraceID([16]byte{15: 1})
+	id2 := pcommon.TraceID([16]byte{15: 2})
+	c.Put(id1, true)
+	now = now.Add(30 * time.Second)
+	c.Put(id2, true)
+
+	now = now.Add(30 * time.Second)
+	_, ok := c.Get(id1)
+	assert.False(t, ok) // expired
+	_, ok = c.Get(id2)
+	assert.True(t, ok)
+	assert.Equal(t, 1, expirations)
+	require.NoError(t, c.Close())
+
+	// Entries that expired while the cache was closed are dropped when reopening, without being reported.
+	now = now.Add(30 * time.Second)
+	reopened := newTestDiskCache(t, path, 10, &now, WithTTL(time.Minute), WithExpirationCallback(func() { expirations++ }))
+	assert.Equal(t, 0, reopened.entries.Len())
+	assert.Equal(t, 1, expirations)
+}
+
+func TestDiskCacheCompactsLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	now := time.Unix(1000, 0)
+	c := newTestDiskCache(t, path, 4, &now)
+
+	for i := 0; i < 100; i++ {
+		c.Put(pcommon.TraceID([16]byte{15: byte(i)}), true)
+	}
+
+	// The log is compacted in the background.
+	assert.Eventually(t, func() bool {
+		c.flush()
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return info.Size() <= int64(2*4*diskRecordSize)
+	}, 5*time.Second, 10*time.Millisecond)
+	c.mu.Lock()
+	assert.Equal(t, 4, c.entries.Len())
+	c.mu.Unlock()
+}
+
+func TestDiskCacheKeepsRecordsAppendedWhileCompacting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	now := time.Unix(1000, 0)
+	c := newTestDiskCache(t, path, 10, &now)
+	c.Put(pcommon.TraceID([16]byte{15: 1}), true)
+	c.Put(pcommon.TraceID([16]byte{15: 2}), true)
+
+	records := c.startCompaction()
+	c.Put(pcommon.TraceID([16]byte{15: 3}), false)
+	c.Delete(pcommon.TraceID([16]byte{15: 1}))
+	file, err := writeCompactedLog(path, records)
+	require.NoError(t, c.finishCompaction(file, len(records)/diskRecordSize, err))
+	assert.Equal(t, 4, c.records)
+	require.NoError(t, c.Close())
+
+	reopened := newTestDiskCache(t, path, 10, &now)
+	_, ok := reopened.Get(pcommon.TraceID([16]byte{15: 1}))
+	assert.False(t, ok)
+	v, ok := reopened.Get(pcommon.TraceID([16]byte{15: 2}))
+	assert.True(t, ok)
+	assert.True(t, v)
+	v, ok = reopened.Get(pcommon.TraceID([16]byte{15: 3}))
+	assert.True(t, ok)
+	assert.False(t, v)
+}
+
+func TestDiskCacheBuffersRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	now := time.Unix(1000, 0)
+	c := newTestDiskCache(t, path, 10, &now)
+	logSize := func() int64 {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return info.Size()
+	}
+
+	c.Put(pcommon.TraceID([16]byte{15: 1}), true)
+	c.Delete(pcommon.TraceID([16]byte{15: 1}))
+	assert.Zero(t, logSize())
+	c.flush()
+	assert.Equal(t, int64(2*diskRecordSize), logSize())
+
+	// Compacting the log discards the buffered records, the live entries being written instead.
+	c.Put(pcommon.TraceID([16]byte{15: 2}), false)
+	require.NoError(t, c.compact())
+	assert.Equal(t, int64(diskRecordSize), logSize())
+	c.flush()
+	assert.Equal(t, int64(diskRecordSize), logSize())
+}
+
+func TestDiskCacheIgnoresTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	id := pcommon.TraceID([16]byte{15: 1})
+	record := encodeDiskRecord(rightHalfTraceID(id), diskEntry{value: true}, false)
+	require.NoError(t, os.WriteFile(path, append(record, record[:5]...), 0o600))
+
+	now := time.Unix(1000, 0)
+	c := newTestDiskCache(t, path, 10, &now)
+	v, ok := c.Get(id)
+	assert.True(t, v)
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.entries.Len())
+}
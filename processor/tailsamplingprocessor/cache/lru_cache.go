@@ -16,7 +16,15 @@ import (
 // a decision was made for an ID. You need separate DecisionCaches for caching
 // sampled and not sampled trace IDs.
 type lruDecisionCache[V any] struct {
-	cache *lru.Cache[uint64, V]
+	cache   *lru.Cache[uint64, lruEntry[V]]
+	options options
+}
+
+// lruEntry is a value held by the LRU cache.
+type lruEntry[V any] struct {
+	value V
+	// expiration time, in Unix nanoseconds. Zero means the entry never expires.
+	expiresAt int64
 }
 
 var _ Cache[any] = (*lruDecisionCache[any])(nil)
@@ -24,24 +32,39 @@ var _ Cache[any] = (*lruDecisionCache[any])(nil)
 // NewLRUDecisionCache returns a new lruDecisionCache.
 // The size parameter indicates the amount of keys the cache will hold before it
 // starts evicting the least recently used key.
-func NewLRUDecisionCache[V any](size int) (Cache[V], error) {
-	c, err := lru.New[uint64, V](size)
+func NewLRUDecisionCache[V any](size int, opts ...Option) (Cache[V], error) {
+	c, err := lru.New[uint64, lruEntry[V]](size)
 	if err != nil {
 		return nil, err
 	}
-	return &lruDecisionCache[V]{cache: c}, nil
+	return &lruDecisionCache[V]{cache: c, options: newOptions(opts)}, nil
 }
 
 func (c *lruDecisionCache[V]) Get(id pcommon.TraceID) (V, bool) {
-	return c.cache.Get(rightHalfTraceID(id))
+	var zero V
+	key := rightHalfTraceID(id)
+	entry, ok := c.cache.Get(key)
+	if !ok {
+		return zero, false
+	}
+	if c.options.expired(entry.expiresAt) {
+		// Only report the expiration if the entry wasn't removed concurrently.
+		if c.cache.Remove(key) {
+			c.options.expire()
+		}
+		return zero, false
+	}
+	return entry.value, true
 }
 
 func (c *lruDecisionCache[V]) Put(id pcommon.TraceID, v V) {
-	_ = c.cache.Add(rightHalfTraceID(id), v)
+	_ = c.cache.Add(rightHalfTraceID(id), lruEntry[V]{value: v, expiresAt: c.options.expiresAt()})
 }
 
-// Delete is no-op since LRU relies on least recently used key being evicting automatically
-func (c *lruDecisionCache[V]) Delete(_ pcommon.TraceID) {}
+// Delete removes the given trace ID from the cache, e.g.: when a trace not sampled is sampled after all.
+func (c *lruDecisionCache[V]) Delete(id pcommon.TraceID) {
+	c.cache.Remove(rightHalfTraceID(id))
+}
 
 func rightHalfTraceID(id pcommon.TraceID) uint64 {
 	return binary.LittleEndian.Uint64(id[8:])
@@ -6,6 +6,7 @@ package cache
 import (
 	"encoding/hex"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -23,6 +24,17 @@ func TestSinglePut(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestDelete(t *testing.T) {
+	c, err := NewLRUDecisionCache[int](2)
+	require.NoError(t, err)
+	id, err := traceIDFromHex("12341234123412341234123412341234")
+	require.NoError(t, err)
+	c.Put(id, 123)
+	c.Delete(id)
+	_, ok := c.Get(id)
+	assert.False(t, ok)
+}
+
 func TestExceedsSizeLimit(t *testing.T) {
 	c, err := NewLRUDecisionCache[bool](2)
 	require.NoError(t, err)
@@ -76,6 +88,45 @@ func TestLeastRecentlyUsedIsEvicted(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestExpiredEntriesAreRemoved(t *testing.T) {
+	now := time.Unix(1000, 0)
+	expirations := 0
+	c, err := NewLRUDecisionCache[bool](10,
+		WithTTL(time.Minute),
+		WithExpirationCallback(func() { expirations++ }),
+		withTimeProvider(func() time.Time { return now }),
+	)
+	require.NoError(t, err)
+	id1, err := traceIDFromHex("12341234123412341234123412341231")
+	require.NoError(t, err)
+	id2, err := traceIDFromHex("12341234123412341234123412341232")
+	require.NoError(t, err)
+
+	c.Put(id1, true)
+	now = now.Add(30 * time.Second)
+	c.Put(id2, true)
+	now = now.Add(30 * time.Second)
+
+	v, ok := c.Get(id1)
+	assert.False(t, v)  // expired, returns zero-value
+	assert.False(t, ok) // expired, not OK
+	v, ok = c.Get(id2)
+	assert.True(t, v)
+	assert.True(t, ok)
+	assert.Equal(t, 1, expirations)
+
+	// A removed entry is only reported once.
+	_, ok = c.Get(id1)
+	assert.False(t, ok)
+	assert.Equal(t, 1, expirations)
+
+	// Putting an entry again renews it.
+	c.Put(id2, true)
+	now = now.Add(59 * time.Second)
+	_, ok = c.Get(id2)
+	assert.True(t, ok)
+}
+
 func traceIDFromHex(idStr string) (pcommon.TraceID, error) {
 	id := pcommon.NewTraceIDEmpty()
 	_, err := hex.Decode(id[:], []byte(idStr))
@@ -22,3 +22,9 @@ func (n *nopDecisionCache[V]) Put(_ pcommon.TraceID, _ V) {
 }
 
 func (n *nopDecisionCache[V]) Delete(_ pcommon.TraceID) {}
+
+// IsNopDecisionCache returns whether the given cache is a no-op cache, which never holds any entry.
+func IsNopDecisionCache[V any](c Cache[V]) bool {
+	_, ok := c.(*nopDecisionCache[V])
+	return ok
+}
@@ -19,3 +19,10 @@ func TestNopCache(t *testing.T) {
 	assert.False(t, v)
 	assert.False(t, ok)
 }
+
+func TestIsNopDecisionCache(t *testing.T) {
+	assert.True(t, IsNopDecisionCache(NewNopDecisionCache[bool]()))
+	c, err := NewLRUDecisionCache[bool](2)
+	require.NoError(t, err)
+	assert.False(t, IsNopDecisionCache(c))
+}
@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cache // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/cache"
+
+import "time"
+
+// Option configures a decision cache.
+type Option func(*options)
+
+type options struct {
+	ttl      time.Duration
+	onExpire func()
+	now      func() time.Time
+}
+
+func newOptions(opts []Option) options {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTTL sets the time entries are kept for, regardless of the size of the cache. Expired entries are no longer
+// returned and are removed when they are next accessed. Zero, the default, means entries never expire.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithExpirationCallback sets a function called every time an expired entry is removed from the cache.
+func WithExpirationCallback(onExpire func()) Option {
+	return func(o *options) {
+		o.onExpire = onExpire
+	}
+}
+
+// withTimeProvider sets the function returning the current time, for tests.
+func withTimeProvider(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// expiresAt returns the expiration time of an entry added now, in Unix nanoseconds, or zero if entries never expire.
+func (o options) expiresAt() int64 {
+	if o.ttl <= 0 {
+		return 0
+	}
+	return o.now().Add(o.ttl).UnixNano()
+}
+
+// expired returns whether an entry with the given expiration time, as returned by expiresAt, has expired.
+func (o options) expired(expiresAt int64) bool {
+	return expiresAt != 0 && expiresAt <= o.now().UnixNano()
+}
+
+// expire reports the removal of an expired entry.
+func (o options) expire() {
+	if o.onExpire != nil {
+		o.onExpire()
+	}
+}
@@ -4,8 +4,12 @@
 package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
 
 import (
+	"fmt"
 	"time"
 
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
+
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
 )
 
@@ -47,6 +51,16 @@ const (
 	// OTTLCondition sample traces which match user provided OpenTelemetry Transformation Language
 	// conditions.
 	OTTLCondition PolicyType = "ottl_condition"
+	// ErrorOrigin sample traces whose error originates in a given service or operation.
+	ErrorOrigin PolicyType = "error_origin"
+	// CriticalPath sample traces where a given service or operation spends long on the critical path.
+	CriticalPath PolicyType = "critical_path"
+	// LatencyAnomaly sample traces that are slower than usual for their service and root span name.
+	LatencyAnomaly PolicyType = "latency_anomaly"
+	// Novelty sample the first traces with new values of given attributes.
+	Novelty PolicyType = "novelty"
+	// KeyedRateLimiting allows all traces until the limits of their key, e.g.: their service or tenant, are satisfied.
+	KeyedRateLimiting PolicyType = "keyed_rate_limiting"
 )
 
 // sharedPolicyCfg holds the common configuration to all policies that are used in derivative policy configurations
@@ -78,6 +92,16 @@ type sharedPolicyCfg struct {
 	BooleanAttributeCfg BooleanAttributeCfg `mapstructure:"boolean_attribute"`
 	// Configs for OTTL condition filter sampling policy evaluator
 	OTTLConditionCfg OTTLConditionCfg `mapstructure:"ottl_condition"`
+	// Configs for error origin filter sampling policy evaluator.
+	ErrorOriginCfg ErrorOriginCfg `mapstructure:"error_origin"`
+	// Configs for critical path sampling policy evaluator.
+	CriticalPathCfg CriticalPathCfg `mapstructure:"critical_path"`
+	// Configs for latency anomaly sampling policy evaluator.
+	LatencyAnomalyCfg LatencyAnomalyCfg `mapstructure:"latency_anomaly"`
+	// Configs for novelty sampling policy evaluator.
+	NoveltyCfg NoveltyCfg `mapstructure:"novelty"`
+	// Configs for keyed rate limiting sampling policy evaluator.
+	KeyedRateLimitingCfg KeyedRateLimitingCfg `mapstructure:"keyed_rate_limiting"`
 }
 
 // CompositeSubPolicyCfg holds the common configuration to all policies under composite policy.
@@ -130,6 +154,22 @@ type RateAllocationCfg struct {
 type PolicyCfg struct {
 	sharedPolicyCfg `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
 
+	// DecisionWait is the time since the first span of a trace after which this policy can sample it, when shorter
+	// than the DecisionWait of the processor. Traces not sampled nor dropped by then keep being evaluated by the
+	// policies with a longer DecisionWait.
+	DecisionWait time.Duration `mapstructure:"decision_wait"`
+	// Priority orders the evaluation of the policies in the priority decision mode, higher first. Policies with the same
+	// priority are evaluated in order.
+	Priority int `mapstructure:"priority"`
+	// AbstainOnNoMatch makes the policy abstain from the decision on the traces it doesn't sample, instead of not
+	// sampling them, so that it only decides on the traces it matches in the priority decision mode.
+	AbstainOnNoMatch bool `mapstructure:"abstain_on_no_match"`
+	// Shadow makes the policy evaluated without affecting the sampling decision, to measure what it would sample with
+	// its count_traces_sampled metric, reported with a shadow=true attribute.
+	Shadow bool `mapstructure:"shadow"`
+	// Trim removes the spans adding little to the large traces sampled by this policy.
+	Trim TrimCfg `mapstructure:"trim"`
+
 	// Configs for defining composite policy
 	CompositeCfg CompositeCfg `mapstructure:"composite"`
 	// Configs for defining and policy
@@ -169,19 +209,79 @@ type ProbabilisticCfg struct {
 	// have different sampling rates: if they use the same salt all passing one layer may pass the other even if they have
 	// different sampling rates, configuring different salts avoids that.
 	HashSalt string `mapstructure:"hash_salt"`
+	// Mode defines how traces are sampled: "hash_seed", the default, hashes the trace ID with HashSalt; "consistent"
+	// follows the OpenTelemetry consistent probability sampling specification, comparing the randomness of the trace with
+	// a threshold and recording the threshold in the tracestate of the spans of sampled traces.
+	Mode string `mapstructure:"mode"`
 	// SamplingPercentage is the percentage rate at which traces are going to be sampled. Defaults to zero, i.e.: no sample.
 	// Values greater or equal 100 are treated as "sample all traces".
 	SamplingPercentage float64 `mapstructure:"sampling_percentage"`
 }
 
+// StratifiedProbabilisticCfg holds the configurable settings to create a stratified probabilistic
+// sampling policy evaluator.
 type StratifiedProbabilisticCfg struct {
 	// HashSalt allows one to configure the hashing salts. This is important in scenarios where multiple layers of collectors
 	// have different sampling rates: if they use the same salt all passing one layer may pass the other even if they have
 	// different sampling rates, configuring different salts avoids that.
 	HashSalt string `mapstructure:"hash_salt"`
+	// Mode defines how traces are sampled: "hash_seed", the default, hashes the trace ID with HashSalt; "consistent"
+	// follows the OpenTelemetry consistent probability sampling specification, comparing the randomness of the trace with
+	// a threshold and recording the threshold in the tracestate of the spans of sampled traces.
+	Mode string `mapstructure:"mode"`
 	// SamplingPercentage is the percentage rate at which traces are going to be sampled. Defaults to zero, i.e.: no sample.
 	// Values greater or equal 100 are treated as "sample all traces".
 	SamplingPercentage float64 `mapstructure:"sampling_percentage"`
+	// Window is the period over which trajectory statistics are accumulated. Statistics of the previous window are kept
+	// as well, trajectories not seen during a whole window are forgotten. Defaults to 1m, the minimum is 1s.
+	Window time.Duration `mapstructure:"window"`
+	// MaxTrajectories is the maximum number of distinct trajectories tracked. When exceeded, the least recently seen
+	// trajectory is forgotten. Defaults to 10000.
+	MaxTrajectories int `mapstructure:"max_trajectories"`
+	// MinTracesPerTrajectory is the number of traces of each trajectory that are always sampled per window, regardless of
+	// SamplingPercentage. Defaults to 1 when unset, i.e.: the first trace of a trajectory in each window is sampled. 0
+	// doesn't keep any trace of rare trajectories.
+	MinTracesPerTrajectory *int64 `mapstructure:"min_traces_per_trajectory"`
+	// TargetTracesPerSecond is the number of sampled traces per second to aim for. When set, SamplingPercentage is
+	// ignored and the budget is spread across trajectories, so that rare trajectories are over-represented. Cannot be
+	// combined with TargetSpansPerSecond.
+	TargetTracesPerSecond float64 `mapstructure:"target_traces_per_second"`
+	// TargetSpansPerSecond is the number of spans of sampled traces per second to aim for. When set, SamplingPercentage
+	// is ignored and the budget is spread across trajectories, so that rare trajectories are over-represented. Cannot be
+	// combined with TargetTracesPerSecond.
+	TargetSpansPerSecond float64 `mapstructure:"target_spans_per_second"`
+	// Trajectory defines how the trajectory of a trace is built from its spans.
+	Trajectory TrajectoryCfg `mapstructure:"trajectory"`
+}
+
+// TrajectoryCfg holds the configurable settings defining how the stratified probabilistic sampling
+// policy evaluator builds the trajectory of a trace.
+type TrajectoryCfg struct {
+	// NodeIdentity defines which properties of a span identify it as a node of the trajectory. One of
+	// service_operation (service name and span name), service (service name only), service_kind (service name
+	// and span kind), attributes (service name and the values of AttributeKeys) or ottl (service name and the
+	// result of Expression). Defaults to service_operation.
+	NodeIdentity string `mapstructure:"node_identity"`
+	// AttributeKeys are the span attributes, or resource attributes if not found on the span, identifying a node
+	// when NodeIdentity is attributes.
+	AttributeKeys []string `mapstructure:"attribute_keys"`
+	// Expression is the OTTL value expression, in the span context, identifying a node when NodeIdentity is ottl.
+	Expression string `mapstructure:"expression"`
+	// MaxDepth is the maximum depth of the spans taken into account to build the trajectory, the root span being
+	// at depth 1. Defaults to zero, i.e.: no limit.
+	MaxDepth int `mapstructure:"max_depth"`
+}
+
+// TrimCfg holds the configurable settings to trim the large traces sampled by a policy, keeping their root path,
+// error spans, slow spans and representative spans.
+type TrimCfg struct {
+	// MinSpans is the number of spans above which a trace is trimmed. Zero disables trimming.
+	MinSpans int64 `mapstructure:"min_spans"`
+	// LatencyThreshold keeps the spans lasting at least this long. Zero keeps no span for its latency.
+	LatencyThreshold time.Duration `mapstructure:"latency_threshold"`
+	// SpansPerGroup is the number of representative spans kept for each service and span name, the first ones to
+	// start. Defaults to 1.
+	SpansPerGroup int `mapstructure:"spans_per_group"`
 }
 
 // StatusCodeCfg holds the configurable settings to create a status code filter sampling
@@ -217,6 +317,31 @@ type RateLimitingCfg struct {
 	SpansPerSecond int64 `mapstructure:"spans_per_second"`
 }
 
+// KeyedRateLimitingCfg holds the configurable settings to create a keyed rate limiting
+// sampling policy evaluator. The key of a trace is the key of its root span, or else of its first span with a key.
+type KeyedRateLimitingCfg struct {
+	// Key is the span or resource attribute whose value is the key of a span.
+	Key string `mapstructure:"key"`
+	// Expression is the OTTL value expression, in the span context, whose result is the key of a span. Cannot be
+	// combined with Key.
+	Expression string `mapstructure:"expression"`
+	// SpansPerSecond sets the limit on the number of spans sampled each second for each key.
+	SpansPerSecond int64 `mapstructure:"spans_per_second"`
+	// Overrides set the limits of specific keys.
+	Overrides []KeyedRateLimitOverrideCfg `mapstructure:"overrides"`
+	// MaxKeys is the maximum number of keys tracked. When reached, the least recently seen key is forgotten once its
+	// bucket is full again, and new keys share an overflow bucket until then. Defaults to 10000.
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+// KeyedRateLimitOverrideCfg holds the limit of a specific key of a keyed rate limiting policy.
+type KeyedRateLimitOverrideCfg struct {
+	// Key is the value of the key whose limit is overridden.
+	Key string `mapstructure:"key"`
+	// SpansPerSecond sets the limit on the number of spans sampled each second for the key.
+	SpansPerSecond int64 `mapstructure:"spans_per_second"`
+}
+
 // SpanCountCfg holds the configurable settings to create a Span Count filter sampling
 // policy evaluator
 type SpanCountCfg struct {
@@ -247,6 +372,66 @@ type OTTLConditionCfg struct {
 	SpanEventConditions []string       `mapstructure:"spanevent"`
 }
 
+// ErrorOriginCfg holds the configurable settings to create an error origin filter sampling
+// policy evaluator. The origin of the error of a trace is its deepest span with the error status code.
+type ErrorOriginCfg struct {
+	// Services are the values of the service.name resource attribute of the origins matched. Empty matches any service.
+	Services []string `mapstructure:"services"`
+	// Operations are the span names of the origins matched. Empty matches any operation.
+	Operations []string `mapstructure:"operations"`
+	// TracesPerSecond is the maximum number of traces sampled per second for each service and operation where an
+	// error originates. Zero doesn't limit them.
+	TracesPerSecond int64 `mapstructure:"traces_per_second"`
+}
+
+// CriticalPathCfg holds the configurable settings to create a critical path sampling
+// policy evaluator. The time of a service or operation on the critical path is the self time of its spans on the
+// critical path, i.e.: the time not spent waiting for a child span on the critical path.
+type CriticalPathCfg struct {
+	// Services are the values of the service.name resource attribute whose time on the critical path is measured.
+	Services []string `mapstructure:"services"`
+	// Operations are the span names whose time on the critical path is measured. Without services nor operations,
+	// the time of every service is measured.
+	Operations []string `mapstructure:"operations"`
+	// ThresholdMs samples traces where a measured service or operation spends at least this long on the critical path.
+	ThresholdMs int64 `mapstructure:"threshold_ms"`
+	// Percentage samples traces where a measured service or operation spends at least this percentage of the duration
+	// of the trace on the critical path.
+	Percentage float64 `mapstructure:"percentage"`
+}
+
+// LatencyAnomalyCfg holds the configurable settings to create a latency anomaly sampling
+// policy evaluator. The latencies of the traces are tracked per service and root span name, in streaming quantile
+// sketches.
+type LatencyAnomalyCfg struct {
+	// Percentile samples traces slower than this percentile of the latencies of their operation, e.g.: 99.
+	Percentile float64 `mapstructure:"percentile"`
+	// MedianMultiplier samples traces slower than this multiple of the median latency of their operation.
+	MedianMultiplier float64 `mapstructure:"median_multiplier"`
+	// Window is the period over which latencies are accumulated. Latencies of the previous window are kept along with
+	// the current one. Defaults to 10m.
+	Window time.Duration `mapstructure:"window"`
+	// WarmUpTraces is the number of traces of an operation seen before its traces are sampled. Defaults to 100.
+	WarmUpTraces int64 `mapstructure:"warm_up_traces"`
+	// MaxOperations is the maximum number of operations tracked. When exceeded, the least recently seen operation is
+	// forgotten. Defaults to 1000.
+	MaxOperations int `mapstructure:"max_operations"`
+}
+
+// NoveltyCfg holds the configurable settings to create a novelty sampling policy evaluator. A value of an attribute
+// is new when it wasn't seen before, or wasn't seen during a whole window.
+type NoveltyCfg struct {
+	// Keys are the resource or span attributes whose new values are sampled.
+	Keys []string `mapstructure:"keys"`
+	// FirstTraces is the number of traces sampled with each new value. Defaults to 1.
+	FirstTraces int64 `mapstructure:"first_traces"`
+	// Window is the period after which a value not seen is forgotten, and is new again. Defaults to 1h.
+	Window time.Duration `mapstructure:"window"`
+	// MaxValues is the maximum number of values tracked. When exceeded, the least recently seen value is forgotten.
+	// Defaults to 10000.
+	MaxValues int `mapstructure:"max_values"`
+}
+
 type DecisionCacheConfig struct {
 	// SampledCacheSize specifies the size of the cache that holds the sampled trace IDs.
 	// This value will be the maximum amount of trace IDs that the cache can hold before overwriting previous IDs.
@@ -258,6 +443,46 @@ type DecisionCacheConfig struct {
 	// For effective use, this value should be at least an order of magnitude greater than Config.NumTraces.
 	// If left as default 0, a no-op DecisionCache will be used.
 	NonSampledCacheSize int `mapstructure:"non_sampled_cache_size"`
+	// Type is the type of the caches: "lru", the default, keeps decisions in memory; "disk" persists them to
+	// Disk.Directory so that they survive restarts.
+	Type DecisionCacheType `mapstructure:"type"`
+	// TTL is the time decisions are kept for, regardless of the size of the caches. If left as default 0, decisions
+	// are only evicted when the caches are full.
+	TTL time.Duration `mapstructure:"ttl"`
+	// Disk holds configuration for the "disk" type.
+	Disk DiskDecisionCacheConfig `mapstructure:"disk"`
+}
+
+// DecisionMode is how the decisions of the policies are combined into the sampling decision of a trace. Policies
+// returning the Abstain decision are ignored in every mode.
+type DecisionMode string
+
+const (
+	// DecisionModeAny samples a trace if a policy samples it, unless a policy drops it.
+	DecisionModeAny DecisionMode = "any"
+	// DecisionModeAll samples a trace if all the policies sample it.
+	DecisionModeAll DecisionMode = "all"
+	// DecisionModeFirstMatch is like DecisionModeAny, but stops evaluating the policies once one samples the trace.
+	DecisionModeFirstMatch DecisionMode = "first_match"
+	// DecisionModePriority evaluates the policies by descending priority, the first one not abstaining making the
+	// decision.
+	DecisionModePriority DecisionMode = "priority"
+)
+
+// DecisionCacheType is the type of the decision caches.
+type DecisionCacheType string
+
+const (
+	// LRUDecisionCache keeps decisions in memory.
+	LRUDecisionCache DecisionCacheType = "lru"
+	// DiskDecisionCache persists decisions to disk.
+	DiskDecisionCache DecisionCacheType = "disk"
+)
+
+// DiskDecisionCacheConfig holds configuration for the decision caches persisted to disk.
+type DiskDecisionCacheConfig struct {
+	// Directory holds the files of the caches, in a subdirectory named after the processor ID. It must exist.
+	Directory string `mapstructure:"directory"`
 }
 
 // Config holds the configuration for tail-based sampling.
@@ -279,5 +504,208 @@ type Config struct {
 	// Options allows for additional configuration of the tail-based sampling processor in code.
 	Options []Option `mapstructure:"-"`
 	// Make decision as soon as a policy matches
+	//
+	// Deprecated: use DecisionMode first_match instead.
 	SampleOnFirstMatch bool `mapstructure:"sample_on_first_match"`
+	// DecisionMode is how the decisions of the policies are combined into the sampling decision of a trace. Defaults to
+	// any.
+	DecisionMode DecisionMode `mapstructure:"decision_mode"`
+	// Debug holds configuration for the debug pages of the processor.
+	Debug DebugCfg `mapstructure:"debug"`
+	// RecordDecision holds configuration for recording the sampling decision on the spans of sampled traces.
+	RecordDecision RecordDecisionCfg `mapstructure:"record_decision"`
+	// Spill holds configuration for spilling pending traces to disk when NumTraces is reached.
+	Spill SpillCfg `mapstructure:"spill"`
+	// Routing holds configuration for routing spans to the instance owning their trace among several instances.
+	Routing RoutingCfg `mapstructure:"routing"`
+	// EarlyDecision holds configuration for deciding on complete traces before DecisionWait.
+	EarlyDecision EarlyDecisionCfg `mapstructure:"early_decision"`
+	// LateSpanReevaluation holds configuration for sampling traces not sampled when their late spans are received.
+	LateSpanReevaluation LateSpanReevaluationCfg `mapstructure:"late_span_reevaluation"`
+	// PolicySource holds configuration for loading the policies from a file or an HTTP endpoint, replacing PolicyCfgs
+	// whenever it changes.
+	PolicySource PolicySourceCfg `mapstructure:"policy_source"`
+	// SpanMetrics holds configuration for the metrics of all the spans, sampled or not, emitted when the processor is
+	// part of a metrics pipeline.
+	SpanMetrics SpanMetricsCfg `mapstructure:"span_metrics"`
+}
+
+// Validate checks if the processor configuration is valid.
+func (cfg *Config) Validate() error {
+	switch cfg.RecordDecision.Probability {
+	case "", ProbabilityAttribute, ProbabilityTraceState:
+	default:
+		return fmt.Errorf("unknown sampling probability format %q", cfg.RecordDecision.Probability)
+	}
+	return validatePolicies(cfg.PolicyCfgs)
+}
+
+// validatePolicies checks the configurations of policies, along with the ones of their sub-policies.
+func validatePolicies(cfgs []PolicyCfg) error {
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		shared := []*sharedPolicyCfg{&cfg.sharedPolicyCfg}
+		for j := range cfg.AndCfg.SubPolicyCfg {
+			shared = append(shared, &cfg.AndCfg.SubPolicyCfg[j].sharedPolicyCfg)
+		}
+		for j := range cfg.DropCfg.SubPolicyCfg {
+			shared = append(shared, &cfg.DropCfg.SubPolicyCfg[j].sharedPolicyCfg)
+		}
+		for j := range cfg.CompositeCfg.SubPolicyCfg {
+			sub := &cfg.CompositeCfg.SubPolicyCfg[j]
+			shared = append(shared, &sub.sharedPolicyCfg)
+			for k := range sub.AndCfg.SubPolicyCfg {
+				shared = append(shared, &sub.AndCfg.SubPolicyCfg[k].sharedPolicyCfg)
+			}
+		}
+		for _, s := range shared {
+			if err := s.validate(); err != nil {
+				return fmt.Errorf("invalid policy %q: %w", cfg.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg *sharedPolicyCfg) validate() error {
+	overrides := make(map[string]struct{}, len(cfg.KeyedRateLimitingCfg.Overrides))
+	for _, o := range cfg.KeyedRateLimitingCfg.Overrides {
+		if _, ok := overrides[o.Key]; ok {
+			return fmt.Errorf("duplicate rate limit override for key %q", o.Key)
+		}
+		overrides[o.Key] = struct{}{}
+	}
+	return nil
+}
+
+// SpanMetricsCfg holds configuration for the request, error and duration metrics of the spans of all the traces,
+// computed before their decision. They are emitted as cumulative metrics by service, span name, span kind and status
+// code, with the spans of sampled traces as exemplars.
+type SpanMetricsCfg struct {
+	// Interval is the time between two emissions of the metrics. Defaults to 15s.
+	Interval time.Duration `mapstructure:"interval"`
+	// Buckets are the bounds of the buckets of the duration histogram. Defaults to the buckets of the spanmetrics
+	// connector.
+	Buckets []time.Duration `mapstructure:"buckets"`
+	// MaxSeries is the maximum number of series. Spans of further series are counted in a single series with the
+	// otel.metric.overflow attribute. Defaults to 1000.
+	MaxSeries int `mapstructure:"max_series"`
+}
+
+// PolicySourceCfg holds configuration for loading the policies from a local YAML file or an HTTP URL, polled every
+// Interval. The content of the source has the same policies as the configuration, along with an optional version:
+//
+//	version: 2
+//	policies:
+//	  - name: errors
+//	    type: status_code
+//	    status_code: {status_codes: [ERROR]}
+//
+// Invalid content is rejected, keeping the current policies.
+type PolicySourceCfg struct {
+	// File is the path of a local YAML file.
+	File string `mapstructure:"file"`
+	// URL is an HTTP URL serving YAML. The ETag of its responses is sent back when polling it.
+	URL string `mapstructure:"url"`
+	// Interval is the time between two polls of the source. Defaults to 30s.
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout is the time to wait for the source. Defaults to 10s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// LateSpanReevaluationCfg holds configuration for re-evaluating the traces not sampled when their late spans are
+// received. A summary of each trace not sampled is kept: its duration, span count and status codes. When late spans
+// are received, the summary is updated and evaluated by the latency and status_code policies. If one of them samples
+// it, the trace is sampled from then on, and the late spans are released.
+type LateSpanReevaluationCfg struct {
+	// Enabled enables the re-evaluation of late spans.
+	Enabled bool `mapstructure:"enabled"`
+	// CacheSize is the number of summaries kept, the oldest ones being evicted first. Defaults to the size of the
+	// non-sampled decision cache, or NumTraces if it is disabled.
+	CacheSize int `mapstructure:"cache_size"`
+}
+
+// EarlyDecisionCfg holds configuration for making the sampling decision on a trace as soon as it is complete, rather
+// than DecisionWait after its first span. A trace is complete once its root span and the parents of all its received
+// spans were received.
+type EarlyDecisionCfg struct {
+	// Enabled enables early decisions.
+	Enabled bool `mapstructure:"enabled"`
+	// GracePeriod is the time a trace must stay complete before it is decided, to receive the spans that no received
+	// span references, e.g.: the children of a span received before them. Defaults to 1s.
+	GracePeriod time.Duration `mapstructure:"grace_period"`
+}
+
+// RoutingCfg holds configuration for routing spans to the instance owning their trace among a set of peers, so that
+// all the spans of a trace are sampled by the same instance. Trace IDs are assigned to peers with a consistent hash
+// ring, and spans of traces owned by other peers are forwarded to them over OTLP/gRPC. Routing is disabled when no
+// peer is configured.
+type RoutingCfg struct {
+	// Self is the endpoint of this instance as listed among the peers, e.g.: "${env:MY_POD_IP}:4317".
+	Self string `mapstructure:"self"`
+	// Static holds configuration for a fixed list of peers.
+	Static StaticRoutingCfg `mapstructure:"static"`
+	// DNS holds configuration for peers resolved from a hostname.
+	DNS DNSRoutingCfg `mapstructure:"dns"`
+	// Timeout is the time to wait for a peer to accept forwarded spans, after which they are sampled by this
+	// instance. Defaults to 5s.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Client holds the gRPC client settings of the connections to the peers, e.g.: TLS. Its endpoint is ignored.
+	Client configgrpc.ClientConfig `mapstructure:"client"`
+}
+
+// StaticRoutingCfg holds configuration for a fixed list of peers.
+type StaticRoutingCfg struct {
+	// Endpoints are the OTLP/gRPC endpoints of all the peers, including this instance.
+	Endpoints []string `mapstructure:"endpoints"`
+}
+
+// DNSRoutingCfg holds configuration for peers resolved from a hostname, e.g.: a headless Kubernetes service.
+type DNSRoutingCfg struct {
+	// Hostname resolves to the IP addresses of all the peers, including this instance.
+	Hostname string `mapstructure:"hostname"`
+	// Port is the OTLP/gRPC port of the peers. Defaults to 4317.
+	Port string `mapstructure:"port"`
+	// Interval is the time between two resolutions of the peers. Defaults to 5s.
+	Interval time.Duration `mapstructure:"interval"`
+	// Timeout is the time to wait for a resolution of the peers. Defaults to 1s.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// SpillCfg holds configuration for spilling the spans of the oldest pending traces to disk when NumTraces is reached,
+// instead of dropping them. Spilled traces are reloaded when their sampling decision is made.
+type SpillCfg struct {
+	// Directory holds the spilled traces, in a subdirectory named after the processor ID. It must exist.
+	// Spilling is disabled when empty.
+	Directory string `mapstructure:"directory"`
+	// MaxSizeMiB is the maximum size of the spilled traces, in MiB. Traces are dropped once it is reached.
+	MaxSizeMiB int64 `mapstructure:"max_size_mib"`
+}
+
+// ProbabilityFormat defines how the sampling probability is recorded on spans.
+type ProbabilityFormat string
+
+const (
+	// ProbabilityAttribute records the sampling probability in the tailsampling.probability span attribute.
+	ProbabilityAttribute ProbabilityFormat = "attribute"
+	// ProbabilityTraceState records the sampling probability as the OpenTelemetry sampling threshold, th, in the
+	// tracestate of spans.
+	ProbabilityTraceState ProbabilityFormat = "tracestate"
+)
+
+// RecordDecisionCfg holds configuration for recording the sampling decision on the spans of sampled traces, so that
+// span counts can be extrapolated downstream.
+type RecordDecisionCfg struct {
+	// Policy records the name of the policy that sampled the trace in the tailsampling.policy span attribute.
+	Policy bool `mapstructure:"policy"`
+	// Probability records the probability with which the trace was sampled, when the policy that sampled it samples
+	// probabilistically, in the given format. Disabled when empty.
+	Probability ProbabilityFormat `mapstructure:"probability"`
+}
+
+// DebugCfg holds configuration for the debug pages of the processor.
+type DebugCfg struct {
+	// Extension is the ID of the tail_sampling_debug extension serving the debug pages, e.g.: "tail_sampling_debug".
+	// The pages are disabled when unset.
+	Extension *component.ID `mapstructure:"extension"`
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/confmap/confmaptest"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
@@ -30,12 +31,31 @@ func TestLoadConfig(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, sub.Unmarshal(cfg))
 
+	debugID := component.MustNewID("tail_sampling_debug")
+	minTraces, targetMinTraces := int64(2), int64(1)
+	routingClient := *configgrpc.NewDefaultClientConfig()
+	routingClient.Compression = "zstd"
+	routingClient.WaitForReady = true
 	assert.Equal(t,
 		&Config{
 			DecisionWait:            10 * time.Second,
 			NumTraces:               100,
 			ExpectedNewTracesPerSec: 10,
+			DecisionMode:            DecisionModeFirstMatch,
 			DecisionCache:           DecisionCacheConfig{SampledCacheSize: 1_000, NonSampledCacheSize: 10_000},
+			Debug:                   DebugCfg{Extension: &debugID},
+			RecordDecision:          RecordDecisionCfg{Policy: true, Probability: ProbabilityTraceState},
+			Spill:                   SpillCfg{MaxSizeMiB: 512},
+			Routing: RoutingCfg{
+				Self:    "collector-0.collector-headless:4317",
+				Static:  StaticRoutingCfg{Endpoints: []string{"collector-0.collector-headless:4317", "collector-1.collector-headless:4317"}},
+				Timeout: 2 * time.Second,
+				Client:  routingClient,
+			},
+			EarlyDecision:        EarlyDecisionCfg{Enabled: true, GracePeriod: 2 * time.Second},
+			LateSpanReevaluation: LateSpanReevaluationCfg{Enabled: true},
+			PolicySource:         PolicySourceCfg{File: "testdata/policy_source.yaml", Interval: time.Minute},
+			SpanMetrics:          SpanMetricsCfg{Interval: 30 * time.Second, Buckets: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second}, MaxSeries: 500},
 			PolicyCfgs: []PolicyCfg{
 				{
 					sharedPolicyCfg: sharedPolicyCfg{
@@ -49,6 +69,7 @@ func TestLoadConfig(t *testing.T) {
 						Type:       Latency,
 						LatencyCfg: LatencyCfg{ThresholdMs: 5000},
 					},
+					Trim: TrimCfg{MinSpans: 1000, LatencyThreshold: 500 * time.Millisecond, SpansPerGroup: 3},
 				},
 				{
 					sharedPolicyCfg: sharedPolicyCfg{
@@ -70,6 +91,7 @@ func TestLoadConfig(t *testing.T) {
 						Type:          StatusCode,
 						StatusCodeCfg: StatusCodeCfg{StatusCodes: []string{"ERROR", "UNSET"}},
 					},
+					DecisionWait: 2 * time.Second,
 				},
 				{
 					sharedPolicyCfg: sharedPolicyCfg{
@@ -105,6 +127,8 @@ func TestLoadConfig(t *testing.T) {
 						Type:                BooleanAttribute,
 						BooleanAttributeCfg: BooleanAttributeCfg{Key: "key4", Value: true},
 					},
+					Shadow:           true,
+					AbstainOnNoMatch: true,
 				},
 				{
 					sharedPolicyCfg: sharedPolicyCfg{
@@ -117,6 +141,85 @@ func TestLoadConfig(t *testing.T) {
 						},
 					},
 				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-12",
+						Type: StratifiedProbabilistic,
+						StratifiedProbabilisticCfg: StratifiedProbabilisticCfg{
+							SamplingPercentage:     5,
+							Window:                 5 * time.Minute,
+							MaxTrajectories:        1000,
+							MinTracesPerTrajectory: &minTraces,
+							Mode:                   "consistent",
+							Trajectory: TrajectoryCfg{
+								NodeIdentity:  "attributes",
+								AttributeKeys: []string{"http.route"},
+								MaxDepth:      4,
+							},
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-13",
+						Type: StratifiedProbabilistic,
+						StratifiedProbabilisticCfg: StratifiedProbabilisticCfg{
+							MinTracesPerTrajectory: &targetMinTraces,
+							TargetSpansPerSecond:   1000,
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:           "test-policy-14",
+						Type:           ErrorOrigin,
+						ErrorOriginCfg: ErrorOriginCfg{Services: []string{"payments"}, Operations: []string{"charge"}, TracesPerSecond: 5},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name:            "test-policy-15",
+						Type:            CriticalPath,
+						CriticalPathCfg: CriticalPathCfg{Services: []string{"inventory"}, ThresholdMs: 200, Percentage: 40},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-16",
+						Type: LatencyAnomaly,
+						LatencyAnomalyCfg: LatencyAnomalyCfg{
+							Percentile:       99,
+							MedianMultiplier: 3,
+							Window:           5 * time.Minute,
+							WarmUpTraces:     50,
+							MaxOperations:    200,
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-17",
+						Type: Novelty,
+						NoveltyCfg: NoveltyCfg{
+							Keys:        []string{"http.route", "deployment.version"},
+							FirstTraces: 3,
+							Window:      30 * time.Minute,
+							MaxValues:   5000,
+						},
+					},
+				},
+				{
+					sharedPolicyCfg: sharedPolicyCfg{
+						Name: "test-policy-18",
+						Type: KeyedRateLimiting,
+						KeyedRateLimitingCfg: KeyedRateLimitingCfg{
+							Key:            "tenant.id",
+							SpansPerSecond: 100,
+							Overrides:      []KeyedRateLimitOverrideCfg{{Key: "acme", SpansPerSecond: 1000}},
+							MaxKeys:        500,
+						},
+					},
+				},
 				{
 					sharedPolicyCfg: sharedPolicyCfg{
 						Name: "and-policy-1",
@@ -186,3 +289,24 @@ func TestLoadConfig(t *testing.T) {
 			},
 		}, cfg)
 }
+
+func TestValidateRateLimitOverrides(t *testing.T) {
+	tenants := sharedPolicyCfg{
+		Name: "tenants",
+		Type: KeyedRateLimiting,
+		KeyedRateLimitingCfg: KeyedRateLimitingCfg{
+			Key:            "tenant.id",
+			SpansPerSecond: 100,
+			Overrides:      []KeyedRateLimitOverrideCfg{{Key: "acme", SpansPerSecond: 10}, {Key: "acme", SpansPerSecond: 20}},
+		},
+	}
+	cfg := &Config{PolicyCfgs: []PolicyCfg{{sharedPolicyCfg: tenants}}}
+	assert.EqualError(t, cfg.Validate(), `invalid policy "tenants": duplicate rate limit override for key "acme"`)
+
+	// Sub-policies are validated as well.
+	cfg = &Config{PolicyCfgs: []PolicyCfg{{
+		sharedPolicyCfg: sharedPolicyCfg{Name: "errors-per-tenant", Type: And},
+		AndCfg:          AndCfg{SubPolicyCfg: []AndSubPolicyCfg{{sharedPolicyCfg: tenants}}},
+	}}}
+	assert.EqualError(t, cfg.Validate(), `invalid policy "errors-per-tenant": duplicate rate limit override for key "acme"`)
+}
@@ -0,0 +1,24 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package debugextension // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/debugextension"
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/collector/config/confignet"
+)
+
+// Config defines the configuration of the extension serving the debug pages of the tail sampling processors.
+type Config struct {
+	// TCPAddr is the address the debug pages are served on, e.g.: "localhost:55680".
+	TCPAddr confignet.TCPAddrConfig `mapstructure:",squash"`
+}
+
+// Validate checks if the extension configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.TCPAddr.Endpoint == "" {
+		return errors.New(`"endpoint" is required`)
+	}
+	return nil
+}
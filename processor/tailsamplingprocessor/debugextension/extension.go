@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package debugextension // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/debugextension"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+// Registry is implemented by the extension, letting components add their own pages to the ones it serves.
+type Registry interface {
+	// RegisterPage serves the given handler on the given path, replacing the handler already registered on it.
+	RegisterPage(path string, handler http.Handler)
+}
+
+type debugExtension struct {
+	cfg    *Config
+	logger *zap.Logger
+
+	pagesMux sync.RWMutex
+	pages    map[string]http.Handler
+
+	server  *http.Server
+	stopped chan struct{}
+}
+
+var _ Registry = (*debugExtension)(nil)
+
+func newDebugExtension(cfg *Config, logger *zap.Logger) *debugExtension {
+	return &debugExtension{
+		cfg:    cfg,
+		logger: logger,
+		pages:  map[string]http.Handler{},
+	}
+}
+
+func (e *debugExtension) RegisterPage(path string, handler http.Handler) {
+	e.pagesMux.Lock()
+	defer e.pagesMux.Unlock()
+	e.pages[path] = handler
+}
+
+// ServeHTTP serves the page registered on the path of the request.
+func (e *debugExtension) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.pagesMux.RLock()
+	handler, ok := e.pages[r.URL.Path]
+	e.pagesMux.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func (e *debugExtension) Start(ctx context.Context, _ component.Host) error {
+	listener, err := e.cfg.TCPAddr.Listen(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to bind to address %s: %w", e.cfg.TCPAddr.Endpoint, err)
+	}
+
+	e.server = &http.Server{
+		Handler:           e,
+		ReadHeaderTimeout: 20 * time.Second,
+	}
+	e.stopped = make(chan struct{})
+
+	e.logger.Info("Starting the tail sampling debug pages", zap.String("endpoint", listener.Addr().String()))
+	go func() {
+		defer close(e.stopped)
+		if err := e.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logger.Error("Failed to serve the tail sampling debug pages", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (e *debugExtension) Shutdown(ctx context.Context) error {
+	if e.server == nil {
+		return nil
+	}
+	err := e.server.Shutdown(ctx)
+	<-e.stopped
+	return err
+}
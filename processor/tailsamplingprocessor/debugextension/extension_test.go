@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package debugextension
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/extension"
+)
+
+// availableEndpoint returns a local endpoint nothing listens on.
+func availableEndpoint(t *testing.T) string {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	endpoint := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return endpoint
+}
+
+func TestCreateDefaultConfig(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig()
+	assert.Equal(t, defaultEndpoint, cfg.(*Config).TCPAddr.Endpoint)
+	assert.NoError(t, componenttest.CheckConfigStruct(cfg))
+	assert.NoError(t, cfg.(*Config).Validate())
+	assert.EqualError(t, (&Config{}).Validate(), `"endpoint" is required`)
+}
+
+func TestRegisterPage(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.TCPAddr.Endpoint = availableEndpoint(t)
+	ext, err := factory.Create(context.Background(), extension.Settings{
+		ID:                component.NewID(Type),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}, cfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, ext.Shutdown(context.Background()))
+	}()
+
+	registry, ok := ext.(Registry)
+	require.True(t, ok)
+	registry.RegisterPage("/debug/page", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("page"))
+	}))
+
+	resp, err := http.Get("http://" + cfg.TCPAddr.Endpoint + "/debug/page")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "page", string(body))
+
+	resp, err = http.Get("http://" + cfg.TCPAddr.Endpoint + "/debug/unknown")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStartFailsOnUsedEndpoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ext := newDebugExtension(&Config{}, componenttest.NewNopTelemetrySettings().Logger)
+	ext.cfg.TCPAddr.Endpoint = listener.Addr().String()
+	assert.ErrorContains(t, ext.Start(context.Background(), componenttest.NewNopHost()), "failed to bind to address")
+	assert.NoError(t, ext.Shutdown(context.Background()))
+}
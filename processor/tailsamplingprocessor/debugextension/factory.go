@@ -0,0 +1,34 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package debugextension provides an extension serving the debug pages of the tail sampling processors, e.g.: the
+// trajectories tracked by their stratified policies.
+package debugextension // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/debugextension"
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/confignet"
+	"go.opentelemetry.io/collector/extension"
+)
+
+const defaultEndpoint = "localhost:55680"
+
+// Type is the type of the extension, as referenced in the collector configuration.
+var Type = component.MustNewType("tail_sampling_debug")
+
+// NewFactory returns a new factory for the extension serving the debug pages of the tail sampling processors.
+func NewFactory() extension.Factory {
+	return extension.NewFactory(Type, createDefaultConfig, createExtension, component.StabilityLevelDevelopment)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		TCPAddr: confignet.TCPAddrConfig{Endpoint: defaultEndpoint},
+	}
+}
+
+func createExtension(_ context.Context, set extension.Settings, cfg component.Config) (extension.Extension, error) {
+	return newDebugExtension(cfg.(*Config), set.Logger), nil
+}
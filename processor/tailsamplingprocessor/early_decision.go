@@ -0,0 +1,82 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+const defaultEarlyDecisionGracePeriod = time.Second
+
+// trackCompleteness records the spans added to a trace, and schedules its early decision once it is complete. The
+// caller holds the lock of the trace.
+func (tsp *tailSamplingSpanProcessor) trackCompleteness(id pcommon.TraceID, trace *sampling.TraceData, spans []spanAndScope, now time.Time) {
+	for _, s := range spans {
+		trace.Completeness.Add(*s.span)
+	}
+	if !trace.Completeness.Complete() {
+		return
+	}
+
+	tsp.completeTracesMux.Lock()
+	defer tsp.completeTracesMux.Unlock()
+	if _, ok := tsp.completeTraces[id]; !ok {
+		tsp.completeTraces[id] = now
+	}
+}
+
+// decideCompleteTraces makes the sampling decision on the traces that stayed complete for the grace period. Their spans
+// are released right away, while the traces are kept like the ones decided after DecisionWait, so that their late spans
+// follow the decision.
+func (tsp *tailSamplingSpanProcessor) decideCompleteTraces(ctx context.Context, metrics *policyMetrics) {
+	var due []pcommon.TraceID
+	deadline := time.Now().Add(-tsp.earlyDecisionGrace)
+	tsp.completeTracesMux.Lock()
+	for id, completeTime := range tsp.completeTraces {
+		if !completeTime.After(deadline) {
+			due = append(due, id)
+			delete(tsp.completeTraces, id)
+		}
+	}
+	tsp.completeTracesMux.Unlock()
+
+	var decisions int64
+	for _, id := range due {
+		d, ok := tsp.idToTrace.Load(id)
+		if !ok {
+			continue
+		}
+		trace := d.(*sampling.TraceData)
+
+		trace.Lock()
+		// A span received during the grace period may have revealed missing spans, in which case the trace is scheduled
+		// again once complete, or decided after DecisionWait.
+		complete := trace.FinalDecision == sampling.Unspecified && trace.Completeness.Complete()
+		trace.Unlock()
+		if !complete {
+			continue
+		}
+
+		tsp.decideTrace(ctx, id, trace, tsp.remainingPolicies(trace), true, metrics)
+		decisions++
+
+		// Late spans follow the decision, their completeness doesn't matter anymore.
+		trace.Lock()
+		trace.Completeness = nil
+		trace.Unlock()
+	}
+	tsp.telemetry.ProcessorTailSamplingEarlyDecisions.Add(tsp.ctx, decisions)
+}
+
+// decided returns whether the sampling decision was made on a trace.
+func decided(trace *sampling.TraceData) bool {
+	trace.Lock()
+	defer trace.Unlock()
+	return trace.FinalDecision != sampling.Unspecified
+}
@@ -0,0 +1,213 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func TestEarlyDecision(t *testing.T) {
+	tests := []struct {
+		name          string
+		earlyDecision EarlyDecisionCfg
+		// parents of the spans of the trace, 0 being the root span.
+		parents       []uint64
+		expectedSpans int
+	}{
+		{
+			name:          "complete trace",
+			earlyDecision: EarlyDecisionCfg{Enabled: true, GracePeriod: time.Nanosecond},
+			parents:       []uint64{0, 1, 1},
+			expectedSpans: 3,
+		},
+		{
+			name:          "missing root span",
+			earlyDecision: EarlyDecisionCfg{Enabled: true, GracePeriod: time.Nanosecond},
+			parents:       []uint64{1, 1},
+		},
+		{
+			name:          "missing parent span",
+			earlyDecision: EarlyDecisionCfg{Enabled: true, GracePeriod: time.Nanosecond},
+			parents:       []uint64{0, 1, 5},
+		},
+		{
+			name:          "grace period not elapsed",
+			earlyDecision: EarlyDecisionCfg{Enabled: true, GracePeriod: time.Hour},
+			parents:       []uint64{0, 1},
+		},
+		{
+			name:    "disabled",
+			parents: []uint64{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupTestTelemetry()
+			sink := new(consumertest.TracesSink)
+			mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+			cfg := Config{
+				DecisionWait:  time.Hour,
+				NumTraces:     defaultNumTraces,
+				EarlyDecision: tt.earlyDecision,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{
+						{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), s.newSettings(), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			traceID := uInt64ToTraceID(1)
+			for i, parent := range tt.parents {
+				require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(traceID, uint64(i+1), parent)))
+			}
+
+			// The first tick always gets an empty batch, so only an early decision releases the trace.
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.policyTicker.OnTick()
+
+			assert.Equal(t, tt.expectedSpans, sink.SpanCount())
+			if tt.expectedSpans == 0 {
+				return
+			}
+			assert.Equal(t, 1, mpe.EvaluationCount)
+
+			m := metricdata.Metrics{
+				Name:        "otelcol_processor_tail_sampling_early_decisions",
+				Description: "Count of traces decided before decision_wait because they were complete.",
+				Unit:        "{traces}",
+				Data: metricdata.Sum[int64]{
+					IsMonotonic: true,
+					Temporality: metricdata.CumulativeTemporality,
+					DataPoints:  []metricdata.DataPoint[int64]{{Value: 1}},
+				},
+			}
+			md := metricdata.ResourceMetrics{}
+			require.NoError(t, s.reader.Collect(context.Background(), &md))
+			got := s.getMetric(m.Name, md)
+			metricdatatest.AssertEqual(t, m, got, metricdatatest.IgnoreTimestamp())
+		})
+	}
+}
+
+func TestEarlyDecisionNotRepeatedOnDecisionWait(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+	cfg := Config{
+		DecisionWait:  defaultTestDecisionWait,
+		NumTraces:     defaultNumTraces,
+		EarlyDecision: EarlyDecisionCfg{Enabled: true, GracePeriod: time.Nanosecond},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{
+				{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+			}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+	}()
+
+	require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(uInt64ToTraceID(1), 1, 0)))
+
+	// The trace is decided early on the first tick, releasing its spans, then found decided in the batch of the second
+	// tick.
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.policyTicker.OnTick()
+	d, ok := tsp.idToTrace.Load(uInt64ToTraceID(1))
+	require.True(t, ok)
+	trace := d.(*sampling.TraceData)
+	trace.Lock()
+	assert.Equal(t, 0, trace.ReceivedBatches.SpanCount())
+	assert.Nil(t, trace.Completeness)
+	trace.Unlock()
+	tsp.policyTicker.OnTick()
+
+	assert.Equal(t, 1, sink.SpanCount())
+	assert.Equal(t, 1, mpe.EvaluationCount)
+}
+
+func TestEarlyDecisionLateSpans(t *testing.T) {
+	for name, decision := range map[string]sampling.Decision{"sampled": sampling.Sampled, "not sampled": sampling.NotSampled} {
+		t.Run(name, func(t *testing.T) {
+			sink := new(consumertest.TracesSink)
+			mpe := &mockPolicyEvaluator{NextDecision: decision}
+			cfg := Config{
+				DecisionWait:  defaultTestDecisionWait,
+				NumTraces:     defaultNumTraces,
+				EarlyDecision: EarlyDecisionCfg{Enabled: true, GracePeriod: time.Nanosecond},
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{
+						{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			tsp := p.(*tailSamplingSpanProcessor)
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(uInt64ToTraceID(1), 1, 0)))
+			tsp.policyTicker.OnTick()
+
+			// Late spans, received before and after DecisionWait, follow the early decision without a new one.
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(uInt64ToTraceID(1), 2, 1)))
+			tsp.policyTicker.OnTick()
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(uInt64ToTraceID(1), 3, 1)))
+			tsp.policyTicker.OnTick()
+
+			assert.Equal(t, 1, mpe.EvaluationCount)
+			if decision == sampling.Sampled {
+				assert.Equal(t, 3, sink.SpanCount())
+			} else {
+				assert.Zero(t, sink.SpanCount())
+			}
+			assert.Equal(t, uint64(1), tsp.numTracesOnMap.Load())
+			tsp.completeTracesMux.Lock()
+			assert.Empty(t, tsp.completeTraces)
+			tsp.completeTracesMux.Unlock()
+		})
+	}
+}
+
+func tracesWithParent(traceID pcommon.TraceID, spanID, parentSpanID uint64) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(uInt64ToSpanID(spanID))
+	if parentSpanID != 0 {
+		span.SetParentSpanID(uInt64ToSpanID(parentSpanID))
+	}
+	return traces
+}
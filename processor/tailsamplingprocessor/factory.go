@@ -7,22 +7,43 @@ package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/processor"
 
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/sharedcomponent"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/telemetry"
 )
 
+// processors holds the processor of each configuration, shared by its traces, metrics and logs pipelines so that log
+// records follow the decision on their trace, and metrics are computed from the spans of all the traces. A
+// configuration used by several traces pipelines gets an independent processor per traces pipeline, which can't be
+// shared with other signals as their pipeline wouldn't tell which traces pipeline it follows.
+var processors = sharedcomponent.NewSharedComponents()
+
+var (
+	errSharedWithSeveralTracesPipelines     = errors.New("the processor can't be used by logs or metrics pipelines when used by several traces pipelines")
+	errDiskSharedWithSeveralTracesPipelines = errors.New("the processor can't be used by several traces pipelines when spilling traces or persisting decisions to disk")
+	errSeveralLogsPipelines                 = errors.New("the processor can't be used by several logs pipelines")
+	errSeveralMetricsPipelines              = errors.New("the processor can't be used by several metrics pipelines")
+)
+
 // NewFactory returns a new factory for the Tail Sampling processor.
 func NewFactory() processor.Factory {
 	return processor.NewFactory(
 		metadata.Type,
 		createDefaultConfig,
-		processor.WithTraces(createTracesProcessor, metadata.TracesStability))
+		processor.WithTraces(createTracesProcessor, metadata.TracesStability),
+		processor.WithMetrics(createMetricsProcessor, metadata.MetricsStability),
+		processor.WithLogs(createLogsProcessor, metadata.LogsStability))
 }
 
 func createDefaultConfig() component.Config {
@@ -30,6 +51,8 @@ func createDefaultConfig() component.Config {
 		DecisionWait:       30 * time.Second,
 		NumTraces:          50000,
 		SampleOnFirstMatch: false,
+		Spill:              SpillCfg{MaxSizeMiB: 1024},
+		Routing:            RoutingCfg{Client: *configgrpc.NewDefaultClientConfig()},
 	}
 }
 
@@ -40,9 +63,156 @@ func createTracesProcessor(
 	nextConsumer consumer.Traces,
 ) (processor.Traces, error) {
 	tCfg := cfg.(*Config)
+	c, sp := getOrCreateProcessor(ctx, params, tCfg)
+	if sp.err != nil {
+		return nil, sp.err
+	}
+	if sp.tracesPipelines > 0 {
+		// Another traces pipeline uses the configuration, it gets its own processor as it would without sharing.
+		if sp.logsPipelines > 0 || sp.metricsPipelines > 0 {
+			return nil, errSharedWithSeveralTracesPipelines
+		}
+		// The processors would share the files of the processor ID.
+		if usesDisk(tCfg) {
+			return nil, errDiskSharedWithSeveralTracesPipelines
+		}
+		sp.tracesPipelines++
+		return newTracesProcessor(ctx, params, nextConsumer, *tCfg)
+	}
+	sp.tracesPipelines++
+	sp.tsp.nextConsumer = nextConsumer
+	return &tracesProcessor{SharedComponent: c, tsp: sp.tsp}, nil
+}
 
-	if telemetry.IsRecordPolicyEnabled() {
-		tCfg.Options = append(tCfg.Options, withRecordPolicy())
+func createLogsProcessor(
+	ctx context.Context,
+	params processor.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (processor.Logs, error) {
+	lCfg := cfg.(*Config)
+	if len(lCfg.Routing.Static.Endpoints) > 0 || lCfg.Routing.DNS.Hostname != "" {
+		return nil, errors.New("log records can't be sampled when routing spans to peers")
+	}
+
+	c, sp := getOrCreateProcessor(ctx, params, lCfg)
+	if sp.err != nil {
+		return nil, sp.err
+	}
+	switch {
+	case sp.tracesPipelines > 1:
+		return nil, errSharedWithSeveralTracesPipelines
+	case sp.logsPipelines > 0:
+		return nil, errSeveralLogsPipelines
 	}
-	return newTracesProcessor(ctx, params, nextConsumer, *tCfg)
+	sp.logsPipelines++
+	sp.tsp.logsConsumer = nextConsumer
+	return &logsProcessor{SharedComponent: c, tsp: sp.tsp}, nil
+}
+
+func createMetricsProcessor(
+	ctx context.Context,
+	params processor.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (processor.Metrics, error) {
+	mCfg := cfg.(*Config)
+	c, sp := getOrCreateProcessor(ctx, params, mCfg)
+	if sp.err != nil {
+		return nil, sp.err
+	}
+	switch {
+	case sp.tracesPipelines > 1:
+		return nil, errSharedWithSeveralTracesPipelines
+	case sp.metricsPipelines > 0:
+		return nil, errSeveralMetricsPipelines
+	}
+	sp.metricsPipelines++
+	tsp := sp.tsp
+	tsp.metricsConsumer = nextConsumer
+	tsp.spanMetrics = newSpanMetrics(mCfg.SpanMetrics)
+	tsp.spanMetricsInterval = mCfg.SpanMetrics.Interval
+	if tsp.spanMetricsInterval <= 0 {
+		tsp.spanMetricsInterval = defaultSpanMetricsInterval
+	}
+	return &metricsProcessor{SharedComponent: c, tsp: tsp}, nil
+}
+
+// sharedProcessor is the processor of a configuration, or the error creating it, along with the number of pipelines
+// of each signal using the configuration.
+type sharedProcessor struct {
+	component.Component
+	tsp *tailSamplingSpanProcessor
+	err error
+
+	tracesPipelines  int
+	metricsPipelines int
+	logsPipelines    int
+}
+
+// getOrCreateProcessor returns the processor of the configuration, creating it for its first pipeline.
+func getOrCreateProcessor(ctx context.Context, params processor.Settings, cfg *Config) (*sharedcomponent.SharedComponent, *sharedProcessor) {
+	c := processors.GetOrAdd(cfg, func() component.Component {
+		if telemetry.IsRecordPolicyEnabled() {
+			cfg.Options = append(cfg.Options, withRecordPolicy())
+		}
+		sp := &sharedProcessor{}
+		tp, err := newTracesProcessor(ctx, params, nil, *cfg)
+		if err != nil {
+			sp.err = err
+			return sp
+		}
+		sp.tsp = tp.(*tailSamplingSpanProcessor)
+		sp.Component = sp.tsp
+		return sp
+	})
+	return c, c.Unwrap().(*sharedProcessor)
+}
+
+// tracesProcessor is the processor of a traces pipeline, sharing its state with the metrics and logs pipelines.
+type tracesProcessor struct {
+	*sharedcomponent.SharedComponent
+	tsp *tailSamplingSpanProcessor
+}
+
+func (p *tracesProcessor) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	return p.tsp.ConsumeTraces(ctx, td)
+}
+
+func (p *tracesProcessor) Capabilities() consumer.Capabilities {
+	return p.tsp.Capabilities()
+}
+
+// metricsProcessor is the processor of a metrics pipeline, sharing its state with the traces pipelines.
+type metricsProcessor struct {
+	*sharedcomponent.SharedComponent
+	tsp *tailSamplingSpanProcessor
+}
+
+func (p *metricsProcessor) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	return p.tsp.ConsumeMetrics(ctx, md)
+}
+
+func (p *metricsProcessor) Capabilities() consumer.Capabilities {
+	return p.tsp.Capabilities()
+}
+
+// logsProcessor is the processor of a logs pipeline, sharing its state with the traces pipelines.
+type logsProcessor struct {
+	*sharedcomponent.SharedComponent
+	tsp *tailSamplingSpanProcessor
+}
+
+func (p *logsProcessor) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	return p.tsp.ConsumeLogs(ctx, ld)
+}
+
+func (p *logsProcessor) Capabilities() consumer.Capabilities {
+	return p.tsp.Capabilities()
+}
+
+// usesDisk returns whether the processor keeps files in the directories of the configuration.
+func usesDisk(cfg *Config) bool {
+	diskCache := cfg.DecisionCache.SampledCacheSize > 0 || cfg.DecisionCache.NonSampledCacheSize > 0
+	return cfg.Spill.Directory != "" || (cfg.DecisionCache.Type == DiskDecisionCache && diskCache)
 }
@@ -35,6 +35,8 @@ func TestCreateProcessor(t *testing.T) {
 	sub, err := cm.Sub(component.NewIDWithName(metadata.Type, "").String())
 	require.NoError(t, err)
 	require.NoError(t, sub.Unmarshal(cfg))
+	// The nop host has no extension to add the debug pages to.
+	cfg.(*Config).Debug = DebugCfg{}
 
 	params := processortest.NewNopSettings(metadata.Type)
 	tp, err := factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
@@ -12,5 +12,7 @@ var (
 )
 
 const (
-	TracesStability = component.StabilityLevelBeta
+	TracesStability  = component.StabilityLevelBeta
+	MetricsStability = component.StabilityLevelAlpha
+	LogsStability    = component.StabilityLevelAlpha
 )
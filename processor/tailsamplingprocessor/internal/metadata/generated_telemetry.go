@@ -26,11 +26,21 @@ type TelemetryBuilder struct {
 	meter                                               metric.Meter
 	mu                                                  sync.Mutex
 	registrations                                       []metric.Registration
+	ProcessorTailSamplingCountLogRecordsSampled         metric.Int64Counter
 	ProcessorTailSamplingCountSpansSampled              metric.Int64Counter
 	ProcessorTailSamplingCountTracesSampled             metric.Int64Counter
+	ProcessorTailSamplingDecisionCacheExpirations       metric.Int64Counter
+	ProcessorTailSamplingDecisionCacheHits              metric.Int64Counter
+	ProcessorTailSamplingDecisionCacheMisses            metric.Int64Counter
+	ProcessorTailSamplingEarlyDecisions                 metric.Int64Counter
 	ProcessorTailSamplingEarlyReleasesFromCacheDecision metric.Int64Counter
 	ProcessorTailSamplingGlobalCountTracesSampled       metric.Int64Counter
+	ProcessorTailSamplingLateSpanUpgrades               metric.Int64Counter
 	ProcessorTailSamplingNewTraceIDReceived             metric.Int64Counter
+	ProcessorTailSamplingPolicySourceLoads              metric.Int64Counter
+	ProcessorTailSamplingPolicySourceVersion            metric.Int64Gauge
+	ProcessorTailSamplingRoutedSpans                    metric.Int64Counter
+	ProcessorTailSamplingRoutingPeers                   metric.Int64Gauge
 	ProcessorTailSamplingSamplingDecisionLatency        metric.Int64Histogram
 	ProcessorTailSamplingSamplingDecisionTimerLatency   metric.Int64Histogram
 	ProcessorTailSamplingSamplingLateSpanAge            metric.Int64Histogram
@@ -38,6 +48,13 @@ type TelemetryBuilder struct {
 	ProcessorTailSamplingSamplingTraceDroppedTooEarly   metric.Int64Counter
 	ProcessorTailSamplingSamplingTraceRemovalAge        metric.Int64Histogram
 	ProcessorTailSamplingSamplingTracesOnMemory         metric.Int64Gauge
+	ProcessorTailSamplingSpilledSpansDropped            metric.Int64Counter
+	ProcessorTailSamplingSpilledTraces                  metric.Int64Counter
+	ProcessorTailSamplingSpilledTracesSize              metric.Int64Gauge
+	ProcessorTailSamplingStratifiedCountTracesSampled   metric.Int64Counter
+	ProcessorTailSamplingStratifiedNewTrajectories      metric.Int64Counter
+	ProcessorTailSamplingStratifiedTrajectories         metric.Int64Gauge
+	ProcessorTailSamplingTrimmedSpans                   metric.Int64Counter
 }
 
 // TelemetryBuilderOption applies changes to default builder.
@@ -69,6 +86,12 @@ func NewTelemetryBuilder(settings component.TelemetrySettings, options ...Teleme
 	}
 	builder.meter = Meter(settings)
 	var err, errs error
+	builder.ProcessorTailSamplingCountLogRecordsSampled, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_count_log_records_sampled",
+		metric.WithDescription("Count of log records that were sampled or not along with their trace"),
+		metric.WithUnit("{records}"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ProcessorTailSamplingCountSpansSampled, err = builder.meter.Int64Counter(
 		"otelcol_processor_tail_sampling_count_spans_sampled",
 		metric.WithDescription("Count of spans that were sampled or not per sampling policy"),
@@ -81,6 +104,30 @@ func NewTelemetryBuilder(settings component.TelemetrySettings, options ...Teleme
 		metric.WithUnit("{traces}"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingDecisionCacheExpirations, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_decision_cache_expirations",
+		metric.WithDescription("Count of decisions removed from a decision cache because their TTL expired, per decision cache."),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingDecisionCacheHits, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_decision_cache_hits",
+		metric.WithDescription("Count of trace IDs found in a decision cache, per decision cache."),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingDecisionCacheMisses, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_decision_cache_misses",
+		metric.WithDescription("Count of trace IDs not found in a decision cache, per decision cache."),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingEarlyDecisions, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_early_decisions",
+		metric.WithDescription("Count of traces decided before decision_wait because they were complete."),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ProcessorTailSamplingEarlyReleasesFromCacheDecision, err = builder.meter.Int64Counter(
 		"otelcol_processor_tail_sampling_early_releases_from_cache_decision",
 		metric.WithDescription("Number of spans that were able to be immediately released due to a decision cache hit."),
@@ -93,12 +140,42 @@ func NewTelemetryBuilder(settings component.TelemetrySettings, options ...Teleme
 		metric.WithUnit("{traces}"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingLateSpanUpgrades, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_late_span_upgrades",
+		metric.WithDescription("Count of traces not sampled, then sampled when re-evaluated with their late spans."),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ProcessorTailSamplingNewTraceIDReceived, err = builder.meter.Int64Counter(
 		"otelcol_processor_tail_sampling_new_trace_id_received",
 		metric.WithDescription("Counts the arrival of new traces"),
 		metric.WithUnit("{traces}"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingPolicySourceLoads, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_policy_source_loads",
+		metric.WithDescription("Count of loads of the policies from the policy_source, by result."),
+		metric.WithUnit("{loads}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingPolicySourceVersion, err = builder.meter.Int64Gauge(
+		"otelcol_processor_tail_sampling_policy_source_version",
+		metric.WithDescription("Version of the last policies loaded from the policy_source."),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingRoutedSpans, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_routed_spans",
+		metric.WithDescription("Count of spans routed to the instance owning their trace, per route. Spans are sampled locally, forwarded to a peer, or sampled locally as a fallback when forwarding them failed."),
+		metric.WithUnit("{spans}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingRoutingPeers, err = builder.meter.Int64Gauge(
+		"otelcol_processor_tail_sampling_routing_peers",
+		metric.WithDescription("Number of peers traces are routed among, including this instance."),
+		metric.WithUnit("{peers}"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ProcessorTailSamplingSamplingDecisionLatency, err = builder.meter.Int64Histogram(
 		"otelcol_processor_tail_sampling_sampling_decision_latency",
 		metric.WithDescription("Latency (in microseconds) of a given sampling policy"),
@@ -143,5 +220,47 @@ func NewTelemetryBuilder(settings component.TelemetrySettings, options ...Teleme
 		metric.WithUnit("{traces}"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingSpilledSpansDropped, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_spilled_spans_dropped",
+		metric.WithDescription("Count of spans of spilled traces dropped because they couldn't be written to disk, such as when the spill directory is full."),
+		metric.WithUnit("{spans}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingSpilledTraces, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_spilled_traces",
+		metric.WithDescription("Count of pending traces spilled to disk because num_traces was reached."),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingSpilledTracesSize, err = builder.meter.Int64Gauge(
+		"otelcol_processor_tail_sampling_spilled_traces_size",
+		metric.WithDescription("Size of the pending traces spilled to disk."),
+		metric.WithUnit("By"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingStratifiedCountTracesSampled, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_stratified_count_traces_sampled",
+		metric.WithDescription("Count of traces that were sampled or not per trajectory by the stratified policy"),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingStratifiedNewTrajectories, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_stratified_new_trajectories",
+		metric.WithDescription("Count of trajectories seen for the first time by the stratified policy"),
+		metric.WithUnit("{trajectories}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingStratifiedTrajectories, err = builder.meter.Int64Gauge(
+		"otelcol_processor_tail_sampling_stratified_trajectories",
+		metric.WithDescription("Number of distinct trajectories seen by the stratified policy in the current window"),
+		metric.WithUnit("{trajectories}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorTailSamplingTrimmedSpans, err = builder.meter.Int64Counter(
+		"otelcol_processor_tail_sampling_trimmed_spans",
+		metric.WithDescription("Count of spans removed from the large traces sampled by a policy, when trimming them."),
+		metric.WithUnit("{spans}"),
+	)
+	errs = errors.Join(errs, err)
 	return &builder, errs
 }
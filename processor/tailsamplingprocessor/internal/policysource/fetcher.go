@@ -0,0 +1,89 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package policysource // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/policysource"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// maxContentSize bounds the size of the content fetched from a source.
+const maxContentSize = 16 << 20
+
+// Fetcher fetches the content of a source.
+type Fetcher interface {
+	// Fetch returns the content of the source, or false if the source tells it didn't change since the last fetch.
+	Fetch(ctx context.Context) ([]byte, bool, error)
+}
+
+// fileFetcher reads a local file.
+type fileFetcher struct {
+	path string
+}
+
+var _ Fetcher = (*fileFetcher)(nil)
+
+// NewFileFetcher returns a Fetcher of the content of the file with the given path.
+func NewFileFetcher(path string) Fetcher {
+	return &fileFetcher{path: path}
+}
+
+func (f *fileFetcher) Fetch(context.Context) ([]byte, bool, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// httpFetcher gets an HTTP URL, sending the ETag of the last response so that the server may tell it didn't change.
+type httpFetcher struct {
+	client *http.Client
+	url    string
+	etag   string
+}
+
+var _ Fetcher = (*httpFetcher)(nil)
+
+// NewHTTPFetcher returns a Fetcher of the content of the given HTTP URL, requested with the given client.
+func NewHTTPFetcher(url string, client *http.Client) Fetcher {
+	return &httpFetcher{client: client, url: url}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, http.NoBody)
+	if err != nil {
+		return nil, false, err
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected status %q from %s", resp.Status, f.url)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxContentSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(content) > maxContentSize {
+		return nil, false, fmt.Errorf("content of %s exceeds %d bytes", f.url, maxContentSize)
+	}
+	f.etag = resp.Header.Get("ETag")
+	return content, true, nil
+}
@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package policysource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileFetcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	f := NewFileFetcher(path)
+
+	_, _, err := f.Fetch(context.Background())
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("policies: []"), 0o600))
+	content, changed, err := f.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "policies: []", string(content))
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("policies: []"))
+	}))
+	defer server.Close()
+	f := NewHTTPFetcher(server.URL, server.Client())
+
+	content, changed, err := f.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "policies: []", string(content))
+
+	content, changed, err = f.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Nil(t, content)
+
+	assert.Equal(t, []string{"", `"v1"`}, requests)
+}
+
+func TestHTTPFetcherErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, _, err := NewHTTPFetcher(server.URL, server.Client()).Fetch(context.Background())
+	require.ErrorContains(t, err, "404 Not Found")
+
+	_, _, err = NewHTTPFetcher("http://invalid host", server.Client()).Fetch(context.Background())
+	require.Error(t, err)
+}
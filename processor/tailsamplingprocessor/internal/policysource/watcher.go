@@ -0,0 +1,93 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package policysource watches a source of sampling policies, e.g.: a local file
+// or an HTTP endpoint, notifying of the changes of its content.
+package policysource // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/policysource"
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Watcher periodically fetches the content of a source, and notifies of its changes.
+type Watcher struct {
+	fetcher  Fetcher
+	interval time.Duration
+	timeout  time.Duration
+	// onChange is called with the content of the source when it changed, or with the error fetching it.
+	onChange func(content []byte, err error)
+
+	// lastHash is the hash of the last content notified of, only accessed by the fetching goroutine.
+	lastHash [sha256.Size]byte
+	notified bool
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewWatcher returns a Watcher fetching the content of the given source every interval. A fetch taking longer than
+// the given timeout fails.
+func NewWatcher(fetcher Fetcher, interval, timeout time.Duration, onChange func(content []byte, err error)) *Watcher {
+	return &Watcher{
+		fetcher:  fetcher,
+		interval: interval,
+		timeout:  timeout,
+		onChange: onChange,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start fetches the content of the source, then keeps fetching it every interval until Shutdown is called.
+func (w *Watcher) Start(ctx context.Context) error {
+	w.fetch(ctx)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+				w.fetch(context.Background())
+			}
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops fetching the content of the source.
+func (w *Watcher) Shutdown(context.Context) error {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+	w.wg.Wait()
+	return nil
+}
+
+func (w *Watcher) fetch(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	content, changed, err := w.fetcher.Fetch(ctx)
+	if err != nil {
+		w.onChange(nil, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	// Sources may not tell whether their content changed, e.g.: files, or servers not sending ETags.
+	hash := sha256.Sum256(content)
+	if w.notified && hash == w.lastHash {
+		return
+	}
+	w.lastHash, w.notified = hash, true
+	w.onChange(content, nil)
+}
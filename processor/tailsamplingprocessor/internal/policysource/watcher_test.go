@@ -0,0 +1,70 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package policysource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFetcher struct {
+	content string
+	changed bool
+	err     error
+}
+
+func (f *fakeFetcher) Fetch(context.Context) ([]byte, bool, error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	return []byte(f.content), f.changed, nil
+}
+
+func TestWatcherNotifiesChanges(t *testing.T) {
+	fetcher := &fakeFetcher{content: "v1", changed: true}
+	var contents []string
+	var errs []error
+	w := NewWatcher(fetcher, time.Hour, time.Second, func(content []byte, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		contents = append(contents, string(content))
+	})
+	require.NoError(t, w.Start(context.Background()))
+	defer func() {
+		require.NoError(t, w.Shutdown(context.Background()))
+	}()
+
+	// The same content isn't notified again.
+	w.fetch(context.Background())
+	// The source tells the content didn't change.
+	fetcher.content, fetcher.changed = "v2", false
+	w.fetch(context.Background())
+	fetcher.changed = true
+	w.fetch(context.Background())
+	fetcher.err = errors.New("unavailable")
+	w.fetch(context.Background())
+
+	assert.Equal(t, []string{"v1", "v2"}, contents)
+	assert.Equal(t, []error{fetcher.err}, errs)
+}
+
+func TestWatcherPolls(t *testing.T) {
+	changes := make(chan string, 10)
+	fetcher := &fakeFetcher{content: "v1", changed: true}
+	w := NewWatcher(fetcher, time.Millisecond, time.Second, func(content []byte, _ error) {
+		changes <- string(content)
+	})
+	require.NoError(t, w.Start(context.Background()))
+	assert.Equal(t, "v1", <-changes)
+	require.NoError(t, w.Shutdown(context.Background()))
+	// Shutting down twice is fine.
+	require.NoError(t, w.Shutdown(context.Background()))
+}
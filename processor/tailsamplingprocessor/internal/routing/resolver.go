@@ -0,0 +1,126 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package routing // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/routing"
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Resolver resolves the endpoints of the peers, including this instance.
+type Resolver interface {
+	// Start resolves the endpoints, then keeps calling onChange with the current endpoints whenever they change.
+	Start(ctx context.Context, onChange func(endpoints []string)) error
+	// Shutdown stops resolving the endpoints.
+	Shutdown(ctx context.Context) error
+}
+
+// staticResolver resolves a fixed list of endpoints.
+type staticResolver struct {
+	endpoints []string
+}
+
+var _ Resolver = (*staticResolver)(nil)
+
+// NewStaticResolver returns a Resolver of the given endpoints.
+func NewStaticResolver(endpoints []string) Resolver {
+	return &staticResolver{endpoints: endpoints}
+}
+
+func (r *staticResolver) Start(_ context.Context, onChange func(endpoints []string)) error {
+	onChange(r.endpoints)
+	return nil
+}
+
+func (*staticResolver) Shutdown(context.Context) error {
+	return nil
+}
+
+// dnsResolver periodically resolves the IP addresses of a hostname, e.g.: a headless Kubernetes service.
+type dnsResolver struct {
+	logger   *zap.Logger
+	hostname string
+	port     string
+	interval time.Duration
+	timeout  time.Duration
+	lookup   func(ctx context.Context, host string) ([]string, error)
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	current  []string
+}
+
+var _ Resolver = (*dnsResolver)(nil)
+
+// NewDNSResolver returns a Resolver of the IP addresses of the given hostname, each with the given port, resolved
+// every interval. A lookup failing or taking longer than the given timeout keeps the previous endpoints.
+func NewDNSResolver(logger *zap.Logger, hostname, port string, interval, timeout time.Duration) Resolver {
+	return &dnsResolver{
+		logger:   logger,
+		hostname: hostname,
+		port:     port,
+		interval: interval,
+		timeout:  timeout,
+		lookup:   net.DefaultResolver.LookupHost,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (r *dnsResolver) Start(ctx context.Context, onChange func(endpoints []string)) error {
+	// The first resolution failing isn't fatal: the peers may not be ready yet.
+	r.resolve(ctx, onChange)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				r.resolve(context.Background(), onChange)
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *dnsResolver) Shutdown(context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	r.wg.Wait()
+	return nil
+}
+
+func (r *dnsResolver) resolve(ctx context.Context, onChange func(endpoints []string)) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	addrs, err := r.lookup(ctx, r.hostname)
+	if err != nil {
+		r.logger.Warn("Failed to resolve the tail sampling peers", zap.String("hostname", r.hostname), zap.Error(err))
+		return
+	}
+
+	endpoints := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, net.JoinHostPort(addr, r.port))
+	}
+	slices.Sort(endpoints)
+	endpoints = slices.Compact(endpoints)
+	if slices.Equal(endpoints, r.current) {
+		return
+	}
+	r.current = endpoints
+	onChange(endpoints)
+}
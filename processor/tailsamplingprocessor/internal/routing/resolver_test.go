@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package routing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStaticResolver(t *testing.T) {
+	var got []string
+	r := NewStaticResolver([]string{"a:4317", "b:4317"})
+	require.NoError(t, r.Start(context.Background(), func(endpoints []string) { got = endpoints }))
+	assert.Equal(t, []string{"a:4317", "b:4317"}, got)
+	require.NoError(t, r.Shutdown(context.Background()))
+}
+
+func TestDNSResolver(t *testing.T) {
+	var mu sync.Mutex
+	addrs := []string{"10.0.0.2", "10.0.0.1"}
+	var lookupErr error
+	var changes [][]string
+
+	r := NewDNSResolver(zap.NewNop(), "collector-headless", "4317", time.Millisecond, time.Second).(*dnsResolver)
+	r.lookup = func(_ context.Context, host string) ([]string, error) {
+		assert.Equal(t, "collector-headless", host)
+		mu.Lock()
+		defer mu.Unlock()
+		return addrs, lookupErr
+	}
+	onChange := func(endpoints []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, endpoints)
+	}
+	lastChange := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return changes[len(changes)-1]
+	}
+
+	require.NoError(t, r.Start(context.Background(), onChange))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+	// The first resolution happens on start.
+	assert.Equal(t, []string{"10.0.0.1:4317", "10.0.0.2:4317"}, lastChange())
+
+	// A failed lookup keeps the previous endpoints.
+	mu.Lock()
+	lookupErr = errors.New("no such host")
+	mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	lookupErr = nil
+	addrs = []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"10.0.0.1:4317", "10.0.0.2:4317", "10.0.0.3:4317"}, lastChange())
+	}, time.Second, time.Millisecond)
+
+	// Unchanged endpoints aren't reported again.
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	assert.Len(t, changes, 2)
+	mu.Unlock()
+}
@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package routing // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/routing"
+
+import (
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// virtualNodesPerEndpoint is the number of positions of each endpoint on the ring, spreading trace IDs evenly among
+// endpoints and moving as few of them as possible when endpoints are added or removed.
+const virtualNodesPerEndpoint = 100
+
+type ringPosition struct {
+	hash     uint32
+	endpoint string
+}
+
+// ring is a consistent hash ring assigning trace IDs to endpoints.
+type ring struct {
+	endpoints []string
+	positions []ringPosition
+}
+
+// newRing returns a ring of the given endpoints. The same endpoints, in any order, always give the same ring.
+func newRing(endpoints []string) *ring {
+	endpoints = slices.Clone(endpoints)
+	slices.Sort(endpoints)
+	endpoints = slices.Compact(endpoints)
+
+	positions := make([]ringPosition, 0, len(endpoints)*virtualNodesPerEndpoint)
+	for _, endpoint := range endpoints {
+		for i := 0; i < virtualNodesPerEndpoint; i++ {
+			positions = append(positions, ringPosition{
+				hash:     crc32.ChecksumIEEE([]byte(endpoint + "-" + strconv.Itoa(i))),
+				endpoint: endpoint,
+			})
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].hash != positions[j].hash {
+			return positions[i].hash < positions[j].hash
+		}
+		return positions[i].endpoint < positions[j].endpoint
+	})
+	return &ring{endpoints: endpoints, positions: positions}
+}
+
+// endpointFor returns the endpoint owning the given trace ID, or an empty string if the ring has no endpoint.
+func (r *ring) endpointFor(id pcommon.TraceID) string {
+	if len(r.positions) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE(id[:])
+	i := sort.Search(len(r.positions), func(i int) bool {
+		return r.positions[i].hash >= hash
+	})
+	if i == len(r.positions) {
+		i = 0
+	}
+	return r.positions[i].endpoint
+}
@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package routing
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func testTraceID(i int) pcommon.TraceID {
+	var id pcommon.TraceID
+	binary.BigEndian.PutUint64(id[:8], uint64(i)*0x9e3779b97f4a7c15)
+	binary.BigEndian.PutUint64(id[8:], uint64(i))
+	return id
+}
+
+func TestRingIsIndependentOfOrder(t *testing.T) {
+	r1 := newRing([]string{"a:4317", "b:4317", "c:4317"})
+	r2 := newRing([]string{"c:4317", "a:4317", "b:4317", "a:4317"})
+	assert.Equal(t, []string{"a:4317", "b:4317", "c:4317"}, r2.endpoints)
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, r1.endpointFor(testTraceID(i)), r2.endpointFor(testTraceID(i)))
+	}
+}
+
+func TestRingSpreadsTraces(t *testing.T) {
+	endpoints := []string{"10.0.0.1:4317", "10.0.0.2:4317", "10.0.0.3:4317", "10.0.0.4:4317"}
+	r := newRing(endpoints)
+	counts := map[string]int{}
+	const traces = 10_000
+	for i := 0; i < traces; i++ {
+		counts[r.endpointFor(testTraceID(i))]++
+	}
+	for _, endpoint := range endpoints {
+		assert.InDelta(t, traces/len(endpoints), counts[endpoint], float64(traces/len(endpoints)/2), endpoint)
+	}
+}
+
+func TestRingMovesFewTracesWhenAddingEndpoint(t *testing.T) {
+	r1 := newRing([]string{"10.0.0.1:4317", "10.0.0.2:4317", "10.0.0.3:4317"})
+	r2 := newRing([]string{"10.0.0.1:4317", "10.0.0.2:4317", "10.0.0.3:4317", "10.0.0.4:4317"})
+	moved := 0
+	const traces = 10_000
+	for i := 0; i < traces; i++ {
+		before, after := r1.endpointFor(testTraceID(i)), r2.endpointFor(testTraceID(i))
+		if before != after {
+			// Traces only move to the new endpoint.
+			assert.Equal(t, "10.0.0.4:4317", after)
+			moved++
+		}
+	}
+	assert.Less(t, moved, traces/2)
+}
+
+func TestEmptyRing(t *testing.T) {
+	assert.Empty(t, newRing(nil).endpointFor(testTraceID(1)))
+}
@@ -0,0 +1,222 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package routing routes spans to the instance owning their trace among a set
+// of peers, so that all the spans of a trace are sampled by the same instance.
+package routing // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/routing"
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ForwardedMetadataKey is the gRPC metadata key of the requests forwarded by a
+// peer. Their spans are sampled by the receiving instance, whatever its view of
+// the peers, so that spans can't be forwarded back and forth.
+const ForwardedMetadataKey = "x-tail-sampling-forwarded"
+
+// Dialer connects to the peer with the given endpoint.
+type Dialer func(ctx context.Context, endpoint string) (*grpc.ClientConn, error)
+
+// IsForwarded returns whether the spans of the request were forwarded by a
+// peer. The metadata of the request must be included in its client info, e.g.:
+// with the include_metadata option of the OTLP receiver.
+func IsForwarded(ctx context.Context) bool {
+	return len(client.FromContext(ctx).Metadata.Get(ForwardedMetadataKey)) > 0
+}
+
+// Router assigns trace IDs to peers with a consistent hash ring and forwards
+// spans to their peer over OTLP/gRPC. It is safe for concurrent use.
+type Router struct {
+	logger   *zap.Logger
+	self     string
+	timeout  time.Duration
+	resolver Resolver
+	dial     Dialer
+	ring     atomic.Pointer[ring]
+
+	clientsMux sync.Mutex
+	clients    map[string]*peerClient
+}
+
+type peerClient struct {
+	conn   *grpc.ClientConn
+	client ptraceotlp.GRPCClient
+}
+
+// NewRouter returns a Router of the peers given by the resolver. The self
+// endpoint identifies this instance among the peers. Forwarding spans to a
+// peer times out after the given timeout.
+func NewRouter(logger *zap.Logger, self string, resolver Resolver, timeout time.Duration) *Router {
+	r := &Router{
+		logger:   logger,
+		self:     self,
+		timeout:  timeout,
+		resolver: resolver,
+		clients:  map[string]*peerClient{},
+	}
+	r.ring.Store(newRing(nil))
+	return r
+}
+
+// Start starts resolving the peers, connecting to them with the given dialer.
+func (r *Router) Start(ctx context.Context, dial Dialer) error {
+	r.dial = dial
+	return r.resolver.Start(ctx, r.setPeers)
+}
+
+// Shutdown stops resolving the peers and closes the connections to them.
+func (r *Router) Shutdown(ctx context.Context) error {
+	err := r.resolver.Shutdown(ctx)
+
+	r.clientsMux.Lock()
+	defer r.clientsMux.Unlock()
+	for endpoint, c := range r.clients {
+		err = errors.Join(err, c.conn.Close())
+		delete(r.clients, endpoint)
+	}
+	return err
+}
+
+// Peers returns the number of peers, including this instance.
+func (r *Router) Peers() int {
+	return len(r.ring.Load().endpoints)
+}
+
+func (r *Router) setPeers(endpoints []string) {
+	next := newRing(endpoints)
+	if len(next.endpoints) > 0 && !slices.Contains(next.endpoints, r.self) {
+		r.logger.Warn("This instance isn't among the tail sampling peers, it won't sample any trace",
+			zap.String("self", r.self), zap.Strings("peers", next.endpoints))
+	}
+	r.ring.Store(next)
+	r.logger.Info("Tail sampling peers changed", zap.Strings("peers", next.endpoints))
+
+	// Close the connections to the peers that are gone.
+	r.clientsMux.Lock()
+	defer r.clientsMux.Unlock()
+	for endpoint, c := range r.clients {
+		if !slices.Contains(next.endpoints, endpoint) {
+			_ = c.conn.Close()
+			delete(r.clients, endpoint)
+		}
+	}
+}
+
+// Split splits the spans between the ones of traces owned by this instance and
+// the ones owned by each other peer, keyed by the endpoint of the peer. Spans
+// are kept when there is no peer.
+func (r *Router) Split(td ptrace.Traces) (ptrace.Traces, map[string]ptrace.Traces) {
+	ring := r.ring.Load()
+	if !r.hasRemoteSpans(ring, td) {
+		return td, nil
+	}
+
+	local := ptrace.NewTraces()
+	remote := map[string]ptrace.Traces{}
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		// The resource and scope copies of each destination, created on their first span.
+		resources := map[string]ptrace.ResourceSpans{}
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			ils := ilss.At(j)
+			scopes := map[string]ptrace.ScopeSpans{}
+			spans := ils.Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				endpoint := r.endpointFor(ring, span.TraceID())
+				scope, ok := scopes[endpoint]
+				if !ok {
+					resource, ok := resources[endpoint]
+					if !ok {
+						dest := local
+						if endpoint != r.self {
+							if dest, ok = remote[endpoint]; !ok {
+								dest = ptrace.NewTraces()
+								remote[endpoint] = dest
+							}
+						}
+						resource = dest.ResourceSpans().AppendEmpty()
+						rs.Resource().CopyTo(resource.Resource())
+						resource.SetSchemaUrl(rs.SchemaUrl())
+						resources[endpoint] = resource
+					}
+					scope = resource.ScopeSpans().AppendEmpty()
+					ils.Scope().CopyTo(scope.Scope())
+					scope.SetSchemaUrl(ils.SchemaUrl())
+					scopes[endpoint] = scope
+				}
+				span.CopyTo(scope.Spans().AppendEmpty())
+			}
+		}
+	}
+	return local, remote
+}
+
+// Forward sends spans to the peer with the given endpoint, marking the request
+// with ForwardedMetadataKey.
+func (r *Router) Forward(ctx context.Context, endpoint string, td ptrace.Traces) error {
+	c, err := r.client(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, ForwardedMetadataKey, "true")
+	_, err = c.client.Export(ctx, ptraceotlp.NewExportRequestFromTraces(td))
+	return err
+}
+
+func (r *Router) client(ctx context.Context, endpoint string) (*peerClient, error) {
+	r.clientsMux.Lock()
+	defer r.clientsMux.Unlock()
+	if c, ok := r.clients[endpoint]; ok {
+		return c, nil
+	}
+	conn, err := r.dial(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	c := &peerClient{conn: conn, client: ptraceotlp.NewGRPCClient(conn)}
+	r.clients[endpoint] = c
+	return c, nil
+}
+
+// endpointFor returns the endpoint of the peer owning the trace ID, which is
+// this instance when there is no peer.
+func (r *Router) endpointFor(ring *ring, id pcommon.TraceID) string {
+	if endpoint := ring.endpointFor(id); endpoint != "" {
+		return endpoint
+	}
+	return r.self
+}
+
+func (r *Router) hasRemoteSpans(ring *ring, td ptrace.Traces) bool {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if r.endpointFor(ring, spans.At(k).TraceID()) != r.self {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package routing
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// peerServer is an OTLP/gRPC server recording the spans it receives.
+type peerServer struct {
+	ptraceotlp.UnimplementedGRPCServer
+	mu        sync.Mutex
+	received  []ptrace.Traces
+	forwarded int
+}
+
+func (s *peerServer) Export(ctx context.Context, req ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = append(s.received, req.Traces())
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(ForwardedMetadataKey)) > 0 {
+		s.forwarded++
+	}
+	return ptraceotlp.NewExportResponse(), nil
+}
+
+func (s *peerServer) spanCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, td := range s.received {
+		count += td.SpanCount()
+	}
+	return count
+}
+
+// startPeerServer starts an OTLP/gRPC server and returns its endpoint.
+func startPeerServer(t *testing.T) (*peerServer, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	peer := &peerServer{}
+	srv := grpc.NewServer()
+	ptraceotlp.RegisterGRPCServer(srv, peer)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return peer, lis.Addr().String()
+}
+
+func insecureDialer(_ context.Context, endpoint string) (*grpc.ClientConn, error) {
+	return grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func newTestTraces(ids ...pcommon.TraceID) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("tracer")
+	for _, id := range ids {
+		ss.Spans().AppendEmpty().SetTraceID(id)
+	}
+	return td
+}
+
+func TestSplit(t *testing.T) {
+	r := NewRouter(zap.NewNop(), "self:4317", NewStaticResolver([]string{"self:4317", "peer:4317"}), time.Second)
+	require.NoError(t, r.Start(context.Background(), insecureDialer))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+	assert.Equal(t, 2, r.Peers())
+
+	var ids []pcommon.TraceID
+	for i := 0; i < 100; i++ {
+		ids = append(ids, testTraceID(i))
+	}
+	local, remote := r.Split(newTestTraces(ids...))
+
+	require.Len(t, remote, 1)
+	peerTd := remote["peer:4317"]
+	assert.Equal(t, 100, local.SpanCount()+peerTd.SpanCount())
+	assert.Positive(t, local.SpanCount())
+	assert.Positive(t, peerTd.SpanCount())
+
+	ring := r.ring.Load()
+	for _, td := range []ptrace.Traces{local, peerTd} {
+		require.Equal(t, 1, td.ResourceSpans().Len())
+		rs := td.ResourceSpans().At(0)
+		assert.Equal(t, map[string]any{"service.name": "checkout"}, rs.Resource().Attributes().AsRaw())
+		require.Equal(t, 1, rs.ScopeSpans().Len())
+		ss := rs.ScopeSpans().At(0)
+		assert.Equal(t, "tracer", ss.Scope().Name())
+		expected := "self:4317"
+		if td == peerTd {
+			expected = "peer:4317"
+		}
+		for i := 0; i < ss.Spans().Len(); i++ {
+			assert.Equal(t, expected, ring.endpointFor(ss.Spans().At(i).TraceID()))
+		}
+	}
+}
+
+func TestSplitWithoutPeers(t *testing.T) {
+	r := NewRouter(zap.NewNop(), "self:4317", NewStaticResolver(nil), time.Second)
+	require.NoError(t, r.Start(context.Background(), insecureDialer))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	td := newTestTraces(testTraceID(1), testTraceID(2))
+	local, remote := r.Split(td)
+	assert.Empty(t, remote)
+	assert.Equal(t, td, local)
+}
+
+func TestForward(t *testing.T) {
+	peer, endpoint := startPeerServer(t)
+	r := NewRouter(zap.NewNop(), "self:4317", NewStaticResolver([]string{"self:4317", endpoint}), time.Second)
+	require.NoError(t, r.Start(context.Background(), insecureDialer))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	require.NoError(t, r.Forward(context.Background(), endpoint, newTestTraces(testTraceID(1), testTraceID(2))))
+	assert.Equal(t, 2, peer.spanCount())
+	peer.mu.Lock()
+	assert.Equal(t, 1, peer.forwarded)
+	peer.mu.Unlock()
+}
+
+func TestIsForwarded(t *testing.T) {
+	assert.False(t, IsForwarded(context.Background()))
+	ctx := client.NewContext(context.Background(), client.Info{
+		Metadata: client.NewMetadata(map[string][]string{ForwardedMetadataKey: {"true"}}),
+	})
+	assert.True(t, IsForwarded(ctx))
+}
+
+func TestForwardToRemovedPeer(t *testing.T) {
+	_, endpoint := startPeerServer(t)
+	var onChange func([]string)
+	r := NewRouter(zap.NewNop(), "self:4317", &funcResolver{start: func(f func([]string)) {
+		onChange = f
+		f([]string{"self:4317", endpoint})
+	}}, time.Second)
+	require.NoError(t, r.Start(context.Background(), insecureDialer))
+	defer func() {
+		require.NoError(t, r.Shutdown(context.Background()))
+	}()
+
+	require.NoError(t, r.Forward(context.Background(), endpoint, newTestTraces(testTraceID(1))))
+	r.clientsMux.Lock()
+	assert.Len(t, r.clients, 1)
+	r.clientsMux.Unlock()
+
+	// The connection to a removed peer is closed.
+	onChange([]string{"self:4317"})
+	assert.Equal(t, 1, r.Peers())
+	r.clientsMux.Lock()
+	assert.Empty(t, r.clients)
+	r.clientsMux.Unlock()
+}
+
+type funcResolver struct {
+	start func(onChange func([]string))
+}
+
+func (r *funcResolver) Start(_ context.Context, onChange func([]string)) error {
+	r.start(onChange)
+	return nil
+}
+
+func (*funcResolver) Shutdown(context.Context) error {
+	return nil
+}
@@ -29,7 +29,9 @@ func NewAnd(
 // Evaluate looks at the trace data and returns a corresponding SamplingDecision.
 func (c *And) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace *TraceData) (Decision, error) {
 	// The policy iterates over all sub-policies and returns Sampled if all sub-policies returned a Sampled Decision.
-	// If any subpolicy returns NotSampled or InvertNotSampled, it returns NotSampled Decision.
+	// If any subpolicy returns NotSampled or InvertNotSampled, it returns NotSampled Decision. Sub-policies returning
+	// Abstain are ignored, and the policy abstains if all of them do.
+	abstained := 0
 	for _, sub := range c.subpolicies {
 		decision, err := sub.Evaluate(ctx, traceID, trace)
 		if err != nil {
@@ -38,6 +40,12 @@ func (c *And) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace *Trac
 		if decision == NotSampled || decision == InvertNotSampled {
 			return NotSampled, nil
 		}
+		if decision == Abstain {
+			abstained++
+		}
+	}
+	if abstained > 0 && abstained == len(c.subpolicies) {
+		return Abstain, nil
 	}
 	return Sampled, nil
 }
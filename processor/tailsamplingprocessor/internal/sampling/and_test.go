@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
 )
@@ -112,3 +113,22 @@ func TestAndEvaluatorStringInvertNotSampled(t *testing.T) {
 	require.NoError(t, err, "Failed to evaluate and policy: %v", err)
 	assert.Equal(t, NotSampled, decision)
 }
+
+// abstainEvaluator has no opinion on any trace.
+type abstainEvaluator struct{}
+
+func (abstainEvaluator) Evaluate(context.Context, pcommon.TraceID, *TraceData) (Decision, error) {
+	return Abstain, nil
+}
+
+func TestAndEvaluatorAbstain(t *testing.T) {
+	always := NewAlwaysSample(componenttest.NewNopTelemetrySettings())
+
+	decision, err := NewAnd(zap.NewNop(), []PolicyEvaluator{abstainEvaluator{}, always}).Evaluate(context.Background(), traceID, createTrace())
+	require.NoError(t, err)
+	assert.Equal(t, Sampled, decision, "an abstaining sub-policy is ignored")
+
+	decision, err = NewAnd(zap.NewNop(), []PolicyEvaluator{abstainEvaluator{}, abstainEvaluator{}}).Evaluate(context.Background(), traceID, createTrace())
+	require.NoError(t, err)
+	assert.Equal(t, Abstain, decision)
+}
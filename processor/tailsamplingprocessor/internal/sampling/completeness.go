@@ -0,0 +1,49 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TraceCompleteness tracks whether all the spans of a trace seem to have been received: the root span was received,
+// and the parent of every received span was received. Spans that nothing received references yet can't be accounted
+// for, which callers make up for by waiting for a grace period once a trace is complete. It isn't safe for concurrent
+// use, callers hold the lock of the TraceData.
+type TraceCompleteness struct {
+	rootReceived bool
+	spanIDs      map[pcommon.SpanID]struct{}
+	// parents referenced by received spans that weren't received yet.
+	missingParents map[pcommon.SpanID]struct{}
+}
+
+// NewTraceCompleteness returns a TraceCompleteness of a trace with no span received yet.
+func NewTraceCompleteness() *TraceCompleteness {
+	return &TraceCompleteness{
+		spanIDs:        map[pcommon.SpanID]struct{}{},
+		missingParents: map[pcommon.SpanID]struct{}{},
+	}
+}
+
+// Add records a received span.
+func (c *TraceCompleteness) Add(span ptrace.Span) {
+	spanID := span.SpanID()
+	c.spanIDs[spanID] = struct{}{}
+	delete(c.missingParents, spanID)
+
+	parentID := span.ParentSpanID()
+	if parentID.IsEmpty() {
+		c.rootReceived = true
+		return
+	}
+	if _, ok := c.spanIDs[parentID]; !ok {
+		c.missingParents[parentID] = struct{}{}
+	}
+}
+
+// Complete returns whether the root span and the parents of all the received spans were received.
+func (c *TraceCompleteness) Complete() bool {
+	return c.rootReceived && len(c.missingParents) == 0
+}
@@ -0,0 +1,63 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newCompletenessSpan(spanID, parentID byte) ptrace.Span {
+	span := ptrace.NewSpan()
+	span.SetSpanID(pcommon.SpanID([8]byte{spanID}))
+	if parentID != 0 {
+		span.SetParentSpanID(pcommon.SpanID([8]byte{parentID}))
+	}
+	return span
+}
+
+func TestTraceCompleteness(t *testing.T) {
+	tests := []struct {
+		name     string
+		spans    [][2]byte // span ID, parent ID
+		complete bool
+	}{
+		{
+			name:     "no span",
+			complete: false,
+		},
+		{
+			name:     "root only",
+			spans:    [][2]byte{{1, 0}},
+			complete: true,
+		},
+		{
+			name:     "children before root",
+			spans:    [][2]byte{{3, 2}, {2, 1}, {1, 0}},
+			complete: true,
+		},
+		{
+			name:     "missing root",
+			spans:    [][2]byte{{2, 1}, {3, 1}},
+			complete: false,
+		},
+		{
+			name:     "missing intermediate span",
+			spans:    [][2]byte{{1, 0}, {3, 2}},
+			complete: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewTraceCompleteness()
+			for _, s := range tt.spans {
+				c.Add(newCompletenessSpan(s[0], s[1]))
+			}
+			assert.Equal(t, tt.complete, c.Complete())
+		})
+	}
+}
@@ -88,6 +88,8 @@ func (c *Composite) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace
 	// once the limit is exceeded the traces are no longer sampled. The counter
 	// restarts at the beginning of each second.
 	// Current counters and rate limits are kept separately for each subpolicy.
+	// Subpolicies returning Abstain are skipped like the ones not sampling the
+	// trace, and the policy abstains if all of them do.
 
 	currSecond := c.timeProvider.getCurSecond()
 	if c.currentSecond != currSecond {
@@ -99,11 +101,16 @@ func (c *Composite) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace
 		}
 	}
 
+	abstained := 0
 	for _, sub := range c.subpolicies {
 		decision, err := sub.evaluator.Evaluate(ctx, traceID, trace)
 		if err != nil {
 			return Unspecified, err
 		}
+		if decision == Abstain {
+			abstained++
+			continue
+		}
 
 		if decision == Sampled || decision == InvertSampled {
 			// The subpolicy made a decision to Sample. Now we need to make our decision.
@@ -130,5 +137,8 @@ func (c *Composite) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace
 		}
 	}
 
+	if abstained > 0 && abstained == len(c.subpolicies) {
+		return Abstain, nil
+	}
 	return NotSampled, nil
 }
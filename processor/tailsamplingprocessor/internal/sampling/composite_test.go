@@ -314,3 +314,17 @@ func TestCompositeEvaluator2SubpolicyThrottling(t *testing.T) {
 		assert.Equal(t, expected, decision)
 	}
 }
+
+func TestCompositeEvaluatorAbstain(t *testing.T) {
+	always := NewAlwaysSample(componenttest.NewNopTelemetrySettings())
+
+	c := NewComposite(zap.NewNop(), 1000, []SubPolicyEvalParams{{abstainEvaluator{}, 100, "eval-1"}, {always, 100, "eval-2"}}, FakeTimeProvider{}, false)
+	decision, err := c.Evaluate(context.Background(), traceID, createTrace())
+	require.NoError(t, err)
+	assert.Equal(t, Sampled, decision, "an abstaining subpolicy is skipped")
+
+	c = NewComposite(zap.NewNop(), 1000, []SubPolicyEvalParams{{abstainEvaluator{}, 100, "eval-1"}}, FakeTimeProvider{}, false)
+	decision, err = c.Evaluate(context.Background(), traceID, createTrace())
+	require.NoError(t, err)
+	assert.Equal(t, Abstain, decision)
+}
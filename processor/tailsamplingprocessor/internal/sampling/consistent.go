@@ -0,0 +1,135 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+)
+
+// SamplingMode defines how a probabilistic policy decides whether a trace falls within its sampling probability.
+type SamplingMode string
+
+const (
+	// HashSeedMode hashes the trace ID with a salt. This is the default.
+	HashSeedMode SamplingMode = "hash_seed"
+	// ConsistentMode compares the randomness of the trace with a 56-bit threshold, as per the OpenTelemetry consistent
+	// probability sampling specification, so that decisions compose with head samplers and other collector tiers. The
+	// randomness is read from the rv key of the OpenTelemetry tracestate entry if set, from the trace ID otherwise.
+	ConsistentMode SamplingMode = "consistent"
+)
+
+func (m SamplingMode) validate() error {
+	switch m {
+	case "", HashSeedMode, ConsistentMode:
+		return nil
+	default:
+		return fmt.Errorf("unknown sampling mode %q", m)
+	}
+}
+
+// traceRandomness returns the 56-bit randomness value of a trace, along with the threshold it was already sampled
+// with, e.g.: by a head sampler, if known. Both are read from the OpenTelemetry tracestate entry of the root span, or
+// of the first span having one if the root span is missing. Without an explicit randomness value, the 7 least
+// significant bytes of the trace ID are used.
+func traceRandomness(logger *zap.Logger, traceID pcommon.TraceID, trace *TraceData) (randomness, upstream uint64, hasUpstream bool) {
+	randomness = binary.BigEndian.Uint64(traceID[8:]) & (maxThreshold - 1)
+
+	raw, found := "", false
+	trace.Lock()
+	rss := trace.ReceivedBatches.ResourceSpans()
+search:
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				ts := span.TraceState().AsRaw()
+				if !strings.Contains(ts, otelTraceStateKey+"=") {
+					continue
+				}
+				root := span.ParentSpanID().IsEmpty()
+				if root || !found {
+					raw, found = ts, true
+				}
+				if root {
+					break search
+				}
+			}
+		}
+	}
+	trace.Unlock()
+
+	if !found {
+		return randomness, 0, false
+	}
+
+	ts := parseOTelTraceState(raw)
+	if value, ok := ts.get(randomnessKey); ok {
+		if rv, err := decodeRandomness(value); err == nil {
+			randomness = rv
+		} else {
+			logger.Debug("Ignoring invalid randomness value", zap.Error(err))
+		}
+	}
+	if value, ok := ts.get(thresholdKey); ok {
+		if th, err := decodeThreshold(value); err == nil {
+			upstream, hasUpstream = th, true
+		} else {
+			logger.Debug("Ignoring invalid sampling threshold", zap.Error(err))
+		}
+	}
+	return randomness, upstream, hasUpstream
+}
+
+// consistentDecision samples a trace with the given probability, consistently with other samplers of the trace. When
+// sampled, the sampling probability and the resulting threshold are set on the trace. As the trace already passed the
+// threshold it was sampled with upstream, the resulting threshold is the highest of both.
+func consistentDecision(logger *zap.Logger, traceID pcommon.TraceID, trace *TraceData, probability float64) Decision {
+	if probability <= 0 {
+		return NotSampled
+	}
+
+	randomness, upstream, hasUpstream := traceRandomness(logger, traceID, trace)
+	threshold := probabilityToThreshold(probability)
+	if randomness < threshold {
+		return NotSampled
+	}
+
+	if hasUpstream {
+		threshold = max(threshold, upstream)
+	}
+	trace.SetSampling(probability, encodeThreshold(threshold))
+	return Sampled
+}
+
+type consistentProbabilisticSampler struct {
+	logger      *zap.Logger
+	probability float64
+}
+
+var _ PolicyEvaluator = (*consistentProbabilisticSampler)(nil)
+
+// NewConsistentProbabilisticSampler creates a policy evaluator that samples a percentage of traces, as per the
+// OpenTelemetry consistent probability sampling specification.
+func NewConsistentProbabilisticSampler(settings component.TelemetrySettings, samplingPercentage float64) PolicyEvaluator {
+	return &consistentProbabilisticSampler{
+		logger:      settings.Logger,
+		probability: min(1, samplingPercentage/100),
+	}
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (s *consistentProbabilisticSampler) Evaluate(_ context.Context, traceID pcommon.TraceID, trace *TraceData) (Decision, error) {
+	s.logger.Debug("Evaluating spans in consistent probabilistic filter")
+
+	return consistentDecision(s.logger, traceID, trace, s.probability), nil
+}
@@ -0,0 +1,178 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func newTraceWithTraceStates(traceStates ...string) *TraceData {
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i, ts := range traceStates {
+		span := spans.AppendEmpty()
+		span.SetSpanID([8]byte{byte(i + 1)})
+		if i > 0 {
+			// The first span is the root span.
+			span.SetParentSpanID([8]byte{1})
+		}
+		span.TraceState().FromRaw(ts)
+	}
+	return &TraceData{ReceivedBatches: traces}
+}
+
+func TestTraceRandomness(t *testing.T) {
+	traceID := pcommon.TraceID{0, 1, 2, 3, 4, 5, 6, 7, 0xff, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde}
+
+	tests := []struct {
+		name               string
+		trace              *TraceData
+		expectedRandomness uint64
+		expectedUpstream   uint64
+		expectedHasUp      bool
+	}{
+		{
+			name:               "from trace ID",
+			trace:              newTraceWithTraceStates("", "vendor=value"),
+			expectedRandomness: 0x123456789abcde,
+		},
+		{
+			name:               "from root span",
+			trace:              newTraceWithTraceStates("ot=rv:00000000000001;th:8", "ot=rv:00000000000002"),
+			expectedRandomness: 1,
+			expectedUpstream:   0x80000000000000,
+			expectedHasUp:      true,
+		},
+		{
+			name:               "from first span without root",
+			trace:              newTraceWithTraceStates("", "ot=rv:00000000000002", "ot=rv:00000000000003"),
+			expectedRandomness: 2,
+		},
+		{
+			name:               "invalid values",
+			trace:              newTraceWithTraceStates("ot=rv:1;th:zz"),
+			expectedRandomness: 0x123456789abcde,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			randomness, upstream, hasUpstream := traceRandomness(zap.NewNop(), traceID, tt.trace)
+			assert.Equal(t, tt.expectedRandomness, randomness)
+			assert.Equal(t, tt.expectedUpstream, upstream)
+			assert.Equal(t, tt.expectedHasUp, hasUpstream)
+		})
+	}
+}
+
+func TestConsistentProbabilisticSampling(t *testing.T) {
+	tests := []struct {
+		samplingPercentage float64
+		expectedThreshold  string
+	}{
+		{samplingPercentage: 0},
+		{samplingPercentage: 25, expectedThreshold: "c"},
+		{samplingPercentage: 50, expectedThreshold: "8"},
+		{samplingPercentage: 100, expectedThreshold: "0"},
+		{samplingPercentage: 150, expectedThreshold: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expectedThreshold, func(t *testing.T) {
+			sampler := NewConsistentProbabilisticSampler(componenttest.NewNopTelemetrySettings(), tt.samplingPercentage)
+
+			traceCount := 100_000
+			sampled := 0
+			for _, traceID := range genRandomTraceIDs(traceCount) {
+				trace := newTraceWithTraceStates("")
+				decision, err := sampler.Evaluate(context.Background(), traceID, trace)
+				require.NoError(t, err)
+				if decision == Sampled {
+					sampled++
+					assert.Equal(t, tt.expectedThreshold, trace.SamplingThreshold)
+					assert.Equal(t, min(1, tt.samplingPercentage/100), trace.SamplingProbability)
+				}
+			}
+			assert.InDelta(t, min(100, tt.samplingPercentage), float64(sampled)/float64(traceCount)*100, 0.5)
+		})
+	}
+}
+
+func TestConsistentProbabilisticSamplingComposes(t *testing.T) {
+	first := NewConsistentProbabilisticSampler(componenttest.NewNopTelemetrySettings(), 50)
+	second := NewConsistentProbabilisticSampler(componenttest.NewNopTelemetrySettings(), 25)
+
+	for _, traceID := range genRandomTraceIDs(10_000) {
+		trace := newTraceWithTraceStates("")
+		firstDecision, err := first.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+
+		// The second tier sees the threshold recorded by the first one.
+		trace = newTraceWithTraceStates(SetTraceStateThreshold("", trace.SamplingThreshold))
+		secondDecision, err := second.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+
+		// Traces sampled at a lower probability are a subset of the ones sampled at a higher probability.
+		if secondDecision == Sampled {
+			assert.Equal(t, Sampled, firstDecision)
+			assert.Equal(t, "c", trace.SamplingThreshold)
+		}
+	}
+}
+
+func TestConsistentProbabilisticSamplingKeepsUpstreamThreshold(t *testing.T) {
+	sampler := NewConsistentProbabilisticSampler(componenttest.NewNopTelemetrySettings(), 50)
+
+	// Sampled upstream at 25%, so the trace passes the 50% threshold as well.
+	trace := newTraceWithTraceStates("ot=rv:f0000000000000;th:c")
+	decision, err := sampler.Evaluate(context.Background(), pcommon.TraceID{}, trace)
+	require.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+	assert.Equal(t, "c", trace.SamplingThreshold)
+	assert.Equal(t, 0.5, trace.SamplingProbability)
+
+	// Explicit randomness takes precedence over the trace ID.
+	trace = newTraceWithTraceStates("ot=rv:70000000000000")
+	decision, err = sampler.Evaluate(context.Background(), pcommon.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, trace)
+	require.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+	assert.Empty(t, trace.SamplingThreshold)
+}
+
+func TestStratifiedProbabilisticSamplingConsistent(t *testing.T) {
+	sampler, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", ConsistentMode, 25, 0, 0, minTraces(1), ThroughputTarget{}, TrajectoryShape{}, &FakeTimeProvider{})
+	require.NoError(t, err)
+
+	thresholds := map[string]int{}
+	for _, traceID := range genStratifiedRandomTraceIDs(100) {
+		trace := newTraceWithTraceStates("")
+		decision, err := sampler.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+		if decision == Sampled {
+			thresholds[trace.SamplingThreshold]++
+			randomness, _, _ := traceRandomness(zap.NewNop(), traceID, trace)
+			if trace.SamplingThreshold == "c" {
+				assert.GreaterOrEqual(t, randomness, uint64(0xc0000000000000))
+			}
+		}
+	}
+
+	// The minimum per trajectory is sampled with a probability of 1, the other traces with the sampling percentage.
+	assert.Equal(t, 1, thresholds["0"])
+	assert.Positive(t, thresholds["c"])
+	assert.Len(t, thresholds, 2)
+}
+
+func TestStratifiedProbabilisticSamplingInvalidMode(t *testing.T) {
+	_, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "unknown", 25, 0, 0, minTraces(1), ThroughputTarget{}, TrajectoryShape{}, &FakeTimeProvider{})
+	assert.ErrorContains(t, err, `unknown sampling mode "unknown"`)
+}
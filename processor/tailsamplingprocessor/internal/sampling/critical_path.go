@@ -0,0 +1,193 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+type criticalPath struct {
+	logger      *zap.Logger
+	services    []string
+	operations  []string
+	thresholdMs int64
+	percentage  float64
+}
+
+var _ PolicyEvaluator = (*criticalPath)(nil)
+
+// NewCriticalPath creates a policy evaluator sampling traces where the given
+// services or operations, span names, spend at least thresholdMs or percentage
+// of the duration of the trace on its critical path. Without services nor
+// operations, traces where any service does are sampled.
+func NewCriticalPath(settings component.TelemetrySettings, services, operations []string, thresholdMs int64, percentage float64) (PolicyEvaluator, error) {
+	if thresholdMs <= 0 && percentage <= 0 {
+		return nil, errors.New("expected a threshold or a percentage of the critical path")
+	}
+	if percentage > 100 {
+		return nil, errors.New("the percentage of the critical path can't be above 100")
+	}
+	return &criticalPath{
+		logger:      settings.Logger,
+		services:    services,
+		operations:  operations,
+		thresholdMs: thresholdMs,
+		percentage:  percentage,
+	}, nil
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (c *criticalPath) Evaluate(_ context.Context, _ pcommon.TraceID, trace *TraceData) (Decision, error) {
+	c.logger.Debug("Evaluating spans in critical path filter")
+
+	trace.Lock()
+	path := newCriticalPathTimes(trace.ReceivedBatches)
+	trace.Unlock()
+
+	if len(c.services) == 0 && len(c.operations) == 0 {
+		for _, d := range path.services {
+			if c.exceeds(d, path.total) {
+				return Sampled, nil
+			}
+		}
+		return NotSampled, nil
+	}
+	for _, service := range c.services {
+		if c.exceeds(path.services[service], path.total) {
+			return Sampled, nil
+		}
+	}
+	for _, operation := range c.operations {
+		if c.exceeds(path.operations[operation], path.total) {
+			return Sampled, nil
+		}
+	}
+	return NotSampled, nil
+}
+
+func (c *criticalPath) exceeds(d, total time.Duration) bool {
+	if d <= 0 {
+		return false
+	}
+	if c.thresholdMs > 0 && d.Milliseconds() >= c.thresholdMs {
+		return true
+	}
+	return c.percentage > 0 && total > 0 && float64(d)*100/float64(total) >= c.percentage
+}
+
+// criticalPathTimes are the times spent by each service and operation on the
+// critical path of a trace.
+type criticalPathTimes struct {
+	// total is the duration of the root span of the critical path.
+	total      time.Duration
+	services   map[string]time.Duration
+	operations map[string]time.Duration
+}
+
+type pathSpan struct {
+	service  string
+	name     string
+	start    pcommon.Timestamp
+	end      pcommon.Timestamp
+	children []*pathSpan
+}
+
+// newCriticalPathTimes returns the times spent on the critical path of a
+// trace, starting from its longest root span. Spans whose parent isn't in the
+// trace are root spans. The critical path goes backwards from the end of a
+// span through the child ending last, then through the child ending last
+// before that child starts, and so on. The time of the span not covered by
+// these children is its self time on the critical path. Children are clipped
+// to the part of their parent they overlap with.
+func newCriticalPathTimes(td ptrace.Traces) criticalPathTimes {
+	times := criticalPathTimes{
+		services:   map[string]time.Duration{},
+		operations: map[string]time.Duration{},
+	}
+
+	spans := map[pcommon.SpanID]*pathSpan{}
+	parents := map[*pathSpan]pcommon.SpanID{}
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		var serviceName string
+		if svcAttr, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			serviceName = svcAttr.AsString()
+		}
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			ss := ilss.At(j).Spans()
+			for k := 0; k < ss.Len(); k++ {
+				span := ss.At(k)
+				s := &pathSpan{service: serviceName, name: span.Name(), start: span.StartTimestamp(), end: span.EndTimestamp()}
+				spans[span.SpanID()] = s
+				parents[s] = span.ParentSpanID()
+			}
+		}
+	}
+
+	var root *pathSpan
+	for s, parentID := range parents {
+		if parent, ok := spans[parentID]; ok && !parentID.IsEmpty() {
+			parent.children = append(parent.children, s)
+			continue
+		}
+		if root == nil || s.end-s.start > root.end-root.start ||
+			(s.end-s.start == root.end-root.start && s.start < root.start) {
+			root = s
+		}
+	}
+	if root == nil || root.end <= root.start {
+		return times
+	}
+	for _, s := range spans {
+		slices.SortFunc(s.children, func(a, b *pathSpan) int {
+			return cmp.Compare(b.end, a.end)
+		})
+	}
+
+	times.total = time.Duration(root.end - root.start)
+	times.walk(root, root.start, root.end)
+	return times
+}
+
+// walk adds the self time of a span on the critical path, within the given
+// window, and walks its children on the critical path.
+func (t *criticalPathTimes) walk(s *pathSpan, lo, hi pcommon.Timestamp) {
+	start, end := max(s.start, lo), min(s.end, hi)
+	if end <= start {
+		return
+	}
+	cursor := end
+	for _, child := range s.children {
+		if cursor <= start {
+			break
+		}
+		childStart, childEnd := max(child.start, start), min(child.end, cursor)
+		if childEnd <= childStart {
+			continue
+		}
+		t.add(s, cursor-childEnd)
+		t.walk(child, childStart, childEnd)
+		cursor = childStart
+	}
+	t.add(s, cursor-start)
+}
+
+func (t *criticalPathTimes) add(s *pathSpan, d pcommon.Timestamp) {
+	if d == 0 {
+		return
+	}
+	t.services[s.service] += time.Duration(d)
+	t.operations[s.name] += time.Duration(d)
+}
@@ -0,0 +1,132 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// checkoutTrace is a checkout whose payment is slow, while reserving the stock and an audit run before it, the audit
+// in parallel with the reservation.
+func checkoutTrace() ptrace.Traces {
+	return newTestTraces(
+		testSpan{service: "frontend", name: "GET /checkout", id: 1, end: time.Second},
+		testSpan{service: "checkout", name: "PlaceOrder", id: 2, parent: 1, start: 100 * time.Millisecond, end: 900 * time.Millisecond},
+		testSpan{service: "inventory", name: "reserve", id: 3, parent: 2, start: 150 * time.Millisecond, end: 300 * time.Millisecond},
+		testSpan{service: "audit", name: "record", id: 4, parent: 2, start: 160 * time.Millisecond, end: 200 * time.Millisecond},
+		testSpan{service: "payments", name: "charge", id: 5, parent: 2, start: 300 * time.Millisecond, end: 850 * time.Millisecond},
+	)
+}
+
+func TestCriticalPathTimes(t *testing.T) {
+	times := newCriticalPathTimes(checkoutTrace())
+	assert.Equal(t, time.Second, times.total)
+	assert.Equal(t, map[string]time.Duration{
+		"frontend":  200 * time.Millisecond,
+		"checkout":  100 * time.Millisecond,
+		"inventory": 150 * time.Millisecond,
+		"payments":  550 * time.Millisecond,
+	}, times.services)
+	assert.Equal(t, 550*time.Millisecond, times.operations["charge"])
+	assert.NotContains(t, times.operations, "record")
+}
+
+func TestCriticalPathTimesClipsChildren(t *testing.T) {
+	// The child outlives its parent, and the longest root span is the one whose parent is missing.
+	times := newCriticalPathTimes(newTestTraces(
+		testSpan{service: "frontend", name: "GET /", id: 1, end: 100 * time.Millisecond},
+		testSpan{service: "worker", name: "process", id: 2, parent: 9, end: 500 * time.Millisecond},
+		testSpan{service: "db", name: "query", id: 3, parent: 2, start: 400 * time.Millisecond, end: 700 * time.Millisecond},
+	))
+	assert.Equal(t, 500*time.Millisecond, times.total)
+	assert.Equal(t, map[string]time.Duration{
+		"worker": 400 * time.Millisecond,
+		"db":     100 * time.Millisecond,
+	}, times.services)
+}
+
+func TestCriticalPathTimesEmptyTrace(t *testing.T) {
+	times := newCriticalPathTimes(ptrace.NewTraces())
+	assert.Zero(t, times.total)
+	assert.Empty(t, times.services)
+}
+
+func TestNewCriticalPathErrors(t *testing.T) {
+	_, err := NewCriticalPath(componenttest.NewNopTelemetrySettings(), nil, nil, 0, 0)
+	assert.EqualError(t, err, "expected a threshold or a percentage of the critical path")
+
+	_, err = NewCriticalPath(componenttest.NewNopTelemetrySettings(), nil, nil, 0, 120)
+	assert.EqualError(t, err, "the percentage of the critical path can't be above 100")
+}
+
+func TestCriticalPathSampling(t *testing.T) {
+	cases := []struct {
+		desc        string
+		services    []string
+		operations  []string
+		thresholdMs int64
+		percentage  float64
+		decision    Decision
+	}{
+		{
+			desc:        "service above threshold",
+			services:    []string{"payments"},
+			thresholdMs: 500,
+			decision:    Sampled,
+		},
+		{
+			desc:        "service below threshold",
+			services:    []string{"inventory"},
+			thresholdMs: 200,
+			decision:    NotSampled,
+		},
+		{
+			desc:       "service above percentage",
+			services:   []string{"inventory"},
+			percentage: 15,
+			decision:   Sampled,
+		},
+		{
+			desc:       "operation below percentage",
+			operations: []string{"reserve"},
+			percentage: 20,
+			decision:   NotSampled,
+		},
+		{
+			desc:        "operation off the critical path",
+			operations:  []string{"record"},
+			thresholdMs: 1,
+			decision:    NotSampled,
+		},
+		{
+			desc:        "any service above threshold",
+			thresholdMs: 500,
+			decision:    Sampled,
+		},
+		{
+			desc:       "no service above percentage",
+			percentage: 60,
+			decision:   NotSampled,
+		},
+	}
+
+	trace := &TraceData{ReceivedBatches: checkoutTrace()}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			filter, err := NewCriticalPath(componenttest.NewNopTelemetrySettings(), c.services, c.operations, c.thresholdMs, c.percentage)
+			require.NoError(t, err)
+			decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, trace)
+			require.NoError(t, err)
+			assert.Equal(t, c.decision, decision)
+		})
+	}
+}
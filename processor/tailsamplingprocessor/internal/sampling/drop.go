@@ -30,7 +30,9 @@ func NewDrop(
 func (c *Drop) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace *TraceData) (Decision, error) {
 	// The policy iterates over all sub-policies and returns Dropped if all
 	// sub-policies returned a Sampled Decision. If any subpolicy returns
-	// NotSampled, it returns NotSampled Decision.
+	// NotSampled, it returns NotSampled Decision. Sub-policies returning
+	// Abstain are ignored, and the policy abstains if all of them do.
+	abstained := 0
 	for _, sub := range c.subpolicies {
 		decision, err := sub.Evaluate(ctx, traceID, trace)
 		if err != nil {
@@ -39,6 +41,12 @@ func (c *Drop) Evaluate(ctx context.Context, traceID pcommon.TraceID, trace *Tra
 		if decision == NotSampled || decision == InvertNotSampled {
 			return NotSampled, nil
 		}
+		if decision == Abstain {
+			abstained++
+		}
+	}
+	if abstained > 0 && abstained == len(c.subpolicies) {
+		return Abstain, nil
 	}
 	return Dropped, nil
 }
@@ -0,0 +1,138 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// spanOrigin is the service and operation of the span where the error of a trace
+// originates.
+type spanOrigin struct {
+	service   string
+	operation string
+}
+
+type errorOrigin struct {
+	logger          *zap.Logger
+	services        []string
+	operations      []string
+	tracesPerSecond int64
+	timeProvider    TimeProvider
+
+	mu                    sync.Mutex
+	currentSecond         int64
+	tracesInCurrentSecond map[spanOrigin]int64
+}
+
+var _ PolicyEvaluator = (*errorOrigin)(nil)
+
+// NewErrorOrigin creates a policy evaluator that samples the traces whose
+// error originates in one of the given services and operations, an empty list
+// matching any of them. The origin of the error is the deepest span with the
+// error status code. When tracesPerSecond isn't zero, at most that many traces
+// are sampled per second for each origin.
+func NewErrorOrigin(settings component.TelemetrySettings, services, operations []string, tracesPerSecond int64, timeProvider TimeProvider) PolicyEvaluator {
+	return &errorOrigin{
+		logger:                settings.Logger,
+		services:              services,
+		operations:            operations,
+		tracesPerSecond:       tracesPerSecond,
+		timeProvider:          timeProvider,
+		tracesInCurrentSecond: map[spanOrigin]int64{},
+	}
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (e *errorOrigin) Evaluate(_ context.Context, _ pcommon.TraceID, trace *TraceData) (Decision, error) {
+	e.logger.Debug("Evaluating spans in error origin filter")
+
+	trace.Lock()
+	errOrigin, ok := findErrorOrigin(trace.ReceivedBatches)
+	trace.Unlock()
+	if !ok {
+		return NotSampled, nil
+	}
+	if len(e.services) > 0 && !slices.Contains(e.services, errOrigin.service) {
+		return NotSampled, nil
+	}
+	if len(e.operations) > 0 && !slices.Contains(e.operations, errOrigin.operation) {
+		return NotSampled, nil
+	}
+	if e.tracesPerSecond <= 0 {
+		return Sampled, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if currSecond := e.timeProvider.getCurSecond(); e.currentSecond != currSecond {
+		e.currentSecond = currSecond
+		clear(e.tracesInCurrentSecond)
+	}
+	if e.tracesInCurrentSecond[errOrigin] >= e.tracesPerSecond {
+		return NotSampled, nil
+	}
+	e.tracesInCurrentSecond[errOrigin]++
+	return Sampled, nil
+}
+
+type errorSpan struct {
+	origin spanOrigin
+	start  pcommon.Timestamp
+	spanID string
+}
+
+// findErrorOrigin returns the origin of the error of a trace, the deepest span
+// with the error status code along the parent/child graph of its spans. Spans
+// whose parent isn't in the trace are at the root. Among the deepest error
+// spans, the first one to start is the origin. It returns false if no span has
+// the error status code.
+func findErrorOrigin(td ptrace.Traces) (spanOrigin, bool) {
+	spanIDToParentID := map[string]string{}
+	var errorSpans []errorSpan
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		var serviceName string
+		if svcAttr, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			serviceName = svcAttr.AsString()
+		}
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				spanID := span.SpanID().String()
+				spanIDToParentID[spanID] = span.ParentSpanID().String()
+				if span.Status().Code() == ptrace.StatusCodeError {
+					errorSpans = append(errorSpans, errorSpan{
+						origin: spanOrigin{service: serviceName, operation: span.Name()},
+						start:  span.StartTimestamp(),
+						spanID: spanID,
+					})
+				}
+			}
+		}
+	}
+	if len(errorSpans) == 0 {
+		return spanOrigin{}, false
+	}
+
+	depths := spanDepths(spanIDToParentID)
+	deepest := errorSpans[0]
+	for _, s := range errorSpans[1:] {
+		depth, deepestDepth := depths[s.spanID], depths[deepest.spanID]
+		if depth > deepestDepth || (depth == deepestDepth && s.start < deepest.start) {
+			deepest = s
+		}
+	}
+	return deepest.origin, true
+}
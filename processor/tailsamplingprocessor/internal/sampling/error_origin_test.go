@@ -0,0 +1,141 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestFindErrorOrigin(t *testing.T) {
+	cases := []struct {
+		desc     string
+		spans    []testSpan
+		expected spanOrigin
+		found    bool
+	}{
+		{
+			desc: "no error",
+			spans: []testSpan{
+				{service: "frontend", name: "GET /checkout", id: 1},
+				{service: "payments", name: "charge", id: 2, parent: 1},
+			},
+		},
+		{
+			desc: "error propagated to the root",
+			spans: []testSpan{
+				{service: "frontend", name: "GET /checkout", id: 1, err: true},
+				{service: "checkout", name: "PlaceOrder", id: 2, parent: 1, err: true},
+				{service: "payments", name: "charge", id: 3, parent: 2, err: true},
+				{service: "checkout", name: "ReserveStock", id: 4, parent: 1},
+			},
+			expected: spanOrigin{service: "payments", operation: "charge"},
+			found:    true,
+		},
+		{
+			desc: "retries at the same depth",
+			spans: []testSpan{
+				{service: "frontend", name: "GET /checkout", id: 1, err: true},
+				{service: "payments", name: "charge", id: 3, parent: 1, start: time.Second, err: true},
+				{service: "payments", name: "authorize", id: 2, parent: 1, err: true},
+			},
+			expected: spanOrigin{service: "payments", operation: "authorize"},
+			found:    true,
+		},
+		{
+			desc: "parent missing from the trace",
+			spans: []testSpan{
+				{service: "checkout", name: "PlaceOrder", id: 2, parent: 1, err: true},
+				{service: "payments", name: "charge", id: 3, parent: 2},
+			},
+			expected: spanOrigin{service: "checkout", operation: "PlaceOrder"},
+			found:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			origin, found := findErrorOrigin(newTestTrace(c.spans...).ReceivedBatches)
+			assert.Equal(t, c.found, found)
+			assert.Equal(t, c.expected, origin)
+		})
+	}
+}
+
+func TestErrorOriginMatches(t *testing.T) {
+	trace := newTestTrace(
+		testSpan{service: "frontend", name: "GET /checkout", id: 1, err: true},
+		testSpan{service: "payments", name: "charge", id: 2, parent: 1, err: true},
+	)
+
+	cases := []struct {
+		desc       string
+		services   []string
+		operations []string
+		decision   Decision
+	}{
+		{
+			desc:     "any origin",
+			decision: Sampled,
+		},
+		{
+			desc:     "origin service",
+			services: []string{"inventory", "payments"},
+			decision: Sampled,
+		},
+		{
+			desc:     "service the error propagated to",
+			services: []string{"frontend"},
+			decision: NotSampled,
+		},
+		{
+			desc:       "origin service and operation",
+			services:   []string{"payments"},
+			operations: []string{"charge"},
+			decision:   Sampled,
+		},
+		{
+			desc:       "other operation",
+			operations: []string{"refund"},
+			decision:   NotSampled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			filter := NewErrorOrigin(componenttest.NewNopTelemetrySettings(), c.services, c.operations, 0, MonotonicClock{})
+			decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, trace)
+			require.NoError(t, err)
+			assert.Equal(t, c.decision, decision)
+		})
+	}
+}
+
+func TestErrorOriginRateLimit(t *testing.T) {
+	clock := &FakeTimeProvider{second: 1}
+	filter := NewErrorOrigin(componenttest.NewNopTelemetrySettings(), nil, nil, 2, clock)
+	payments := newTestTrace(testSpan{service: "payments", name: "charge", id: 1, err: true})
+	inventory := newTestTrace(testSpan{service: "inventory", name: "reserve", id: 1, err: true})
+
+	evaluate := func(trace *TraceData) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, trace)
+		require.NoError(t, err)
+		return decision
+	}
+
+	assert.Equal(t, Sampled, evaluate(payments))
+	assert.Equal(t, Sampled, evaluate(payments))
+	assert.Equal(t, NotSampled, evaluate(payments))
+	// Each origin has its own limit.
+	assert.Equal(t, Sampled, evaluate(inventory))
+
+	clock.second = 2
+	assert.Equal(t, Sampled, evaluate(payments))
+}
@@ -0,0 +1,230 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/filter/filterottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+)
+
+const keyedRateLimitingDefaultMaxKeys = 10000
+
+// RateLimitKey defines how the key of a trace is found, from an attribute or
+// an OTTL expression. Exactly one of them must be set.
+type RateLimitKey struct {
+	// Attribute is the span or resource attribute whose value is the key.
+	Attribute string
+	// Expression is the OTTL value expression, in the span context, whose
+	// result is the key.
+	Expression string
+}
+
+// keyFunc returns the key of a span, and whether the span has one.
+type keyFunc func(ctx context.Context, span ptrace.Span, ss ptrace.ScopeSpans, rs ptrace.ResourceSpans) (string, bool, error)
+
+// tokenBucket holds the tokens left for a key, one token per span.
+type tokenBucket struct {
+	tokens int64
+	// last is the second the bucket was last refilled, in Unix seconds.
+	last int64
+}
+
+type keyedRateLimiting struct {
+	logger         *zap.Logger
+	keyFor         keyFunc
+	spansPerSecond int64
+	overrides      map[string]int64
+	timeProvider   TimeProvider
+
+	mu      sync.Mutex
+	maxKeys int
+	buckets *simplelru.LRU[string, *tokenBucket]
+	// overflow is the bucket shared by the keys seen while maxKeys keys are
+	// tracked.
+	overflow *tokenBucket
+}
+
+var _ PolicyEvaluator = (*keyedRateLimiting)(nil)
+
+// NewKeyedRateLimiting creates a policy evaluator limiting the rate of the
+// spans sampled for each key of the traces, with a token bucket per key.
+// Buckets hold up to a second of spans, and are refilled at spansPerSecond, or
+// at the override of their key. Traces without a key share the bucket of the
+// empty key. At most maxKeys keys are tracked: the least recently seen key is
+// only forgotten once its bucket is full again, as forgetting it earlier would
+// let its traces bypass the limit. Meanwhile, the other keys share an overflow
+// bucket, refilled at spansPerSecond.
+func NewKeyedRateLimiting(
+	settings component.TelemetrySettings,
+	key RateLimitKey,
+	spansPerSecond int64,
+	overrides map[string]int64,
+	maxKeys int,
+	timeProvider TimeProvider,
+) (PolicyEvaluator, error) {
+	if spansPerSecond <= 0 {
+		return nil, errors.New("expected a positive number of spans per second")
+	}
+	for value, limit := range overrides {
+		if limit <= 0 {
+			return nil, fmt.Errorf("expected a positive number of spans per second for key %q", value)
+		}
+	}
+	keyFor, err := newKeyFunc(settings, key)
+	if err != nil {
+		return nil, err
+	}
+	if maxKeys <= 0 {
+		maxKeys = keyedRateLimitingDefaultMaxKeys
+	}
+	buckets, err := simplelru.NewLRU[string, *tokenBucket](maxKeys, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &keyedRateLimiting{
+		logger:         settings.Logger,
+		keyFor:         keyFor,
+		spansPerSecond: spansPerSecond,
+		overrides:      overrides,
+		timeProvider:   timeProvider,
+		maxKeys:        maxKeys,
+		buckets:        buckets,
+		overflow:       &tokenBucket{tokens: spansPerSecond, last: timeProvider.getCurSecond()},
+	}, nil
+}
+
+func newKeyFunc(settings component.TelemetrySettings, key RateLimitKey) (keyFunc, error) {
+	switch {
+	case key.Attribute != "" && key.Expression != "":
+		return nil, errors.New("expected either an attribute or an OTTL expression as the key, not both")
+	case key.Attribute != "":
+		return func(_ context.Context, span ptrace.Span, _ ptrace.ScopeSpans, rs ptrace.ResourceSpans) (string, bool, error) {
+			v, ok := span.Attributes().Get(key.Attribute)
+			if !ok {
+				v, ok = rs.Resource().Attributes().Get(key.Attribute)
+			}
+			if !ok {
+				return "", false, nil
+			}
+			return v.AsString(), true, nil
+		}, nil
+	case key.Expression != "":
+		parser, err := ottlspan.NewParser(filterottl.StandardSpanFuncs(), settings)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := parser.ParseValueExpression(key.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, span ptrace.Span, ss ptrace.ScopeSpans, rs ptrace.ResourceSpans) (string, bool, error) {
+			v, err := expr.Eval(ctx, ottlspan.NewTransformContext(span, ss.Scope(), rs.Resource(), ss, rs))
+			if err != nil {
+				return "", false, err
+			}
+			value := valueToString(v)
+			return value, value != "", nil
+		}, nil
+	default:
+		return nil, errors.New("expected an attribute or an OTTL expression as the key")
+	}
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (k *keyedRateLimiting) Evaluate(ctx context.Context, _ pcommon.TraceID, trace *TraceData) (Decision, error) {
+	k.logger.Debug("Evaluating spans in keyed rate-limiting filter")
+
+	trace.Lock()
+	key, err := k.traceKey(ctx, trace.ReceivedBatches)
+	trace.Unlock()
+	if err != nil {
+		return Error, err
+	}
+	spans := trace.SpanCount.Load()
+
+	limit, ok := k.overrides[key]
+	if !ok {
+		limit = k.spansPerSecond
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.timeProvider.getCurSecond()
+	bucket, ok := k.buckets.Get(key)
+	switch {
+	case ok:
+	case k.buckets.Len() < k.maxKeys || k.forgetIdleKey(now):
+		bucket = &tokenBucket{tokens: limit, last: now}
+		k.buckets.Add(key, bucket)
+	default:
+		bucket, limit = k.overflow, k.spansPerSecond
+	}
+	if elapsed := now - bucket.last; elapsed > 0 {
+		bucket.tokens = min(limit, bucket.tokens+elapsed*limit)
+		bucket.last = now
+	}
+	if spans > bucket.tokens {
+		return NotSampled, nil
+	}
+	bucket.tokens -= spans
+	return Sampled, nil
+}
+
+// forgetIdleKey forgets the least recently seen key if its bucket is full
+// again, i.e. it wasn't used for a second, the same as a new bucket. It returns
+// false if the key can't be forgotten yet.
+func (k *keyedRateLimiting) forgetIdleKey(now int64) bool {
+	_, bucket, ok := k.buckets.GetOldest()
+	if !ok || now-bucket.last < 1 {
+		return false
+	}
+	k.buckets.RemoveOldest()
+	return true
+}
+
+// traceKey returns the key of a trace, the key of its root span, or else of
+// its first span with a key.
+func (k *keyedRateLimiting) traceKey(ctx context.Context, td ptrace.Traces) (string, error) {
+	var key string
+	var found bool
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			ss := ilss.At(j)
+			spans := ss.Spans()
+			for l := 0; l < spans.Len(); l++ {
+				span := spans.At(l)
+				isRoot := span.ParentSpanID().IsEmpty()
+				if found && !isRoot {
+					continue
+				}
+				spanKey, ok, err := k.keyFor(ctx, span, ss, rs)
+				if err != nil {
+					return "", err
+				}
+				if !ok {
+					continue
+				}
+				if isRoot {
+					return spanKey, nil
+				}
+				key, found = spanKey, true
+			}
+		}
+	}
+	return key, nil
+}
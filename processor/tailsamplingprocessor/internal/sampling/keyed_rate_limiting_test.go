@@ -0,0 +1,155 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newTenantTrace returns a trace with a root span of a resource of the given tenant, counting the given number of
+// spans: the policy limits the spans received for the trace, as counted by the processor.
+func newTenantTrace(tenant string, spanCount int64) *TraceData {
+	root := testSpan{name: "root"}
+	if tenant != "" {
+		root.resourceAttrs = map[string]any{"tenant.id": tenant}
+	}
+	trace := newTestTrace(root)
+	trace.SpanCount = &atomic.Int64{}
+	trace.SpanCount.Store(spanCount)
+	return trace
+}
+
+func TestNewKeyedRateLimitingErrors(t *testing.T) {
+	set := componenttest.NewNopTelemetrySettings()
+	tenant := RateLimitKey{Attribute: "tenant.id"}
+
+	_, err := NewKeyedRateLimiting(set, tenant, -10, nil, 0, MonotonicClock{})
+	assert.EqualError(t, err, "expected a positive number of spans per second")
+	// The traces without a key can have an override as well, which must be positive like the others.
+	_, err = NewKeyedRateLimiting(set, tenant, 10, map[string]int64{"acme": 20, "": -1}, 0, MonotonicClock{})
+	assert.EqualError(t, err, `expected a positive number of spans per second for key ""`)
+	_, err = NewKeyedRateLimiting(set, RateLimitKey{Attribute: "tenant.id", Expression: `attributes["tenant.id"]`}, 10, nil, 0, MonotonicClock{})
+	assert.EqualError(t, err, "expected either an attribute or an OTTL expression as the key, not both")
+	_, err = NewKeyedRateLimiting(set, RateLimitKey{}, 10, nil, 0, MonotonicClock{})
+	assert.EqualError(t, err, "expected an attribute or an OTTL expression as the key")
+	// The expression must be a value, not a condition.
+	_, err = NewKeyedRateLimiting(set, RateLimitKey{Expression: `attributes["tenant.id"] == "acme"`}, 10, nil, 0, MonotonicClock{})
+	assert.Error(t, err)
+
+	filter, err := NewKeyedRateLimiting(set, tenant, 10, nil, 0, MonotonicClock{})
+	require.NoError(t, err)
+	assert.Equal(t, keyedRateLimitingDefaultMaxKeys, filter.(*keyedRateLimiting).maxKeys)
+}
+
+func TestKeyedRateLimiting(t *testing.T) {
+	for _, key := range []RateLimitKey{
+		{Attribute: "tenant.id"},
+		{Expression: `resource.attributes["tenant.id"]`},
+	} {
+		t.Run(key.Attribute+key.Expression, func(t *testing.T) {
+			clock := &FakeTimeProvider{second: 1}
+			filter, err := NewKeyedRateLimiting(componenttest.NewNopTelemetrySettings(), key, 10, map[string]int64{"acme": 20}, 0, clock)
+			require.NoError(t, err)
+			evaluate := func(trace *TraceData) Decision {
+				decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, trace)
+				require.NoError(t, err)
+				return decision
+			}
+
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("globex", 6)))
+			assert.Equal(t, NotSampled, evaluate(newTenantTrace("globex", 6)))
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("globex", 4)))
+			// A noisy tenant doesn't use up the limits of the others.
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("initech", 10)))
+			// Overrides set the limit of a tenant.
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("acme", 15)))
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("acme", 5)))
+			assert.Equal(t, NotSampled, evaluate(newTenantTrace("acme", 1)))
+			// Traces without a tenant share a limit.
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("", 10)))
+			assert.Equal(t, NotSampled, evaluate(newTenantTrace("", 1)))
+
+			// Buckets are refilled every second, up to a second of spans.
+			clock.second = 10
+			assert.Equal(t, Sampled, evaluate(newTenantTrace("globex", 10)))
+			assert.Equal(t, NotSampled, evaluate(newTenantTrace("globex", 1)))
+		})
+	}
+}
+
+func TestKeyedRateLimitingKeyOfTrace(t *testing.T) {
+	filter, err := NewKeyedRateLimiting(componenttest.NewNopTelemetrySettings(), RateLimitKey{Attribute: "tenant.id"}, 10, nil, 0, &FakeTimeProvider{second: 1})
+	require.NoError(t, err)
+	k := filter.(*keyedRateLimiting)
+
+	// The key of the root span wins over the keys of the other spans.
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("tenant.id", "resource")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	child := spans.AppendEmpty()
+	child.SetParentSpanID(pcommon.SpanID{1})
+	child.Attributes().PutStr("tenant.id", "child")
+	spans.AppendEmpty().Attributes().PutStr("tenant.id", "root")
+	key, err := k.traceKey(context.Background(), traces)
+	require.NoError(t, err)
+	assert.Equal(t, "root", key)
+
+	// The first span with a key is used without a root span, the span attributes winning over the resource ones.
+	spans.RemoveIf(func(span ptrace.Span) bool { return span.ParentSpanID().IsEmpty() })
+	key, err = k.traceKey(context.Background(), traces)
+	require.NoError(t, err)
+	assert.Equal(t, "child", key)
+}
+
+func TestKeyedRateLimitingOverflow(t *testing.T) {
+	filter, err := NewKeyedRateLimiting(componenttest.NewNopTelemetrySettings(), RateLimitKey{Attribute: "tenant.id"}, 10, map[string]int64{"initech": 100}, 2, &FakeTimeProvider{second: 1})
+	require.NoError(t, err)
+	evaluate := func(tenant string, spans int64) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, newTenantTrace(tenant, spans))
+		require.NoError(t, err)
+		return decision
+	}
+
+	assert.Equal(t, Sampled, evaluate("acme", 10))
+	assert.Equal(t, Sampled, evaluate("globex", 10))
+	// Forgetting acme now would give it a full bucket, so the new keys share the overflow bucket, at the default limit
+	// even when they have an override.
+	assert.Equal(t, Sampled, evaluate("initech", 6))
+	assert.Equal(t, NotSampled, evaluate("hooli", 6))
+	assert.Equal(t, Sampled, evaluate("hooli", 4))
+	assert.Equal(t, NotSampled, evaluate("acme", 1))
+	assert.Equal(t, []string{"globex", "acme"}, filter.(*keyedRateLimiting).buckets.Keys())
+}
+
+func TestKeyedRateLimitingForgetsIdleKey(t *testing.T) {
+	clock := &FakeTimeProvider{second: 1}
+	filter, err := NewKeyedRateLimiting(componenttest.NewNopTelemetrySettings(), RateLimitKey{Attribute: "tenant.id"}, 10, nil, 2, clock)
+	require.NoError(t, err)
+	evaluate := func(tenant string, spans int64) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, newTenantTrace(tenant, spans))
+		require.NoError(t, err)
+		return decision
+	}
+
+	assert.Equal(t, Sampled, evaluate("acme", 10))
+	assert.Equal(t, Sampled, evaluate("globex", 1))
+	clock.second = 2
+	assert.Equal(t, Sampled, evaluate("globex", 1))
+	// acme wasn't seen for a second, its bucket would be full again: it's forgotten for initech.
+	assert.Equal(t, Sampled, evaluate("initech", 10))
+	assert.Equal(t, []string{"globex", "initech"}, filter.(*keyedRateLimiting).buckets.Keys())
+	// globex was seen this second, so acme gets the overflow bucket instead.
+	assert.Equal(t, Sampled, evaluate("acme", 10))
+	assert.Equal(t, NotSampled, evaluate("hooli", 1))
+	assert.Equal(t, []string{"globex", "initech"}, filter.(*keyedRateLimiting).buckets.Keys())
+}
@@ -18,7 +18,10 @@ type latency struct {
 	upperThresholdMs int64
 }
 
-var _ PolicyEvaluator = (*latency)(nil)
+var (
+	_ PolicyEvaluator  = (*latency)(nil)
+	_ SummaryEvaluator = (*latency)(nil)
+)
 
 // NewLatency creates a policy evaluator sampling traces with a duration greater than a configured threshold
 func NewLatency(settings component.TelemetrySettings, thresholdMs int64, upperThresholdMs int64) PolicyEvaluator {
@@ -55,3 +58,18 @@ func (l *latency) Evaluate(_ context.Context, _ pcommon.TraceID, traceData *Trac
 		return (l.thresholdMs < duration.Milliseconds() && duration.Milliseconds() <= l.upperThresholdMs)
 	}), nil
 }
+
+// EvaluateSummary looks at the duration of the trace and returns a corresponding SamplingDecision.
+func (l *latency) EvaluateSummary(summary *TraceSummary) Decision {
+	duration := summary.Duration()
+	if l.upperThresholdMs == 0 {
+		if duration.Milliseconds() >= l.thresholdMs {
+			return Sampled
+		}
+		return NotSampled
+	}
+	if l.thresholdMs < duration.Milliseconds() && duration.Milliseconds() <= l.upperThresholdMs {
+		return Sampled
+	}
+	return NotSampled
+}
@@ -0,0 +1,179 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	latencyAnomalyDefaultWindow        = 10 * time.Minute
+	latencyAnomalyDefaultWarmUpTraces  = 100
+	latencyAnomalyDefaultMaxOperations = 1000
+)
+
+// operation identifies the traces whose latencies are compared, by the service
+// and the name of their root span.
+type operation struct {
+	service  string
+	rootName string
+}
+
+// operationLatencies holds the sketches of the latencies of the traces of an
+// operation in the current and in the previous window.
+type operationLatencies struct {
+	current  *quantileSketch
+	previous *quantileSketch
+}
+
+type latencyAnomaly struct {
+	logger           *zap.Logger
+	percentile       float64
+	medianMultiplier float64
+	warmUpTraces     uint64
+
+	mu         sync.Mutex
+	window     window
+	operations *simplelru.LRU[operation, *operationLatencies]
+}
+
+var _ PolicyEvaluator = (*latencyAnomaly)(nil)
+
+// NewLatencyAnomaly creates a policy evaluator sampling traces slower than the
+// given percentile, or than the given multiple of the median, of the latencies
+// of the traces of the same service and root span name over the current and
+// the previous window. Traces aren't sampled until warmUpTraces traces of
+// their operation were seen. When more than maxOperations operations are
+// tracked, the least recently seen one is forgotten.
+func NewLatencyAnomaly(
+	settings component.TelemetrySettings,
+	percentile float64,
+	medianMultiplier float64,
+	window time.Duration,
+	warmUpTraces int64,
+	maxOperations int,
+	timeProvider TimeProvider,
+) (PolicyEvaluator, error) {
+	if percentile <= 0 && medianMultiplier <= 0 {
+		return nil, errors.New("expected a percentile or a median multiplier")
+	}
+	if percentile >= 100 {
+		return nil, errors.New("the percentile must be below 100")
+	}
+	if window <= 0 {
+		window = latencyAnomalyDefaultWindow
+	}
+	if warmUpTraces <= 0 {
+		warmUpTraces = latencyAnomalyDefaultWarmUpTraces
+	}
+	if maxOperations <= 0 {
+		maxOperations = latencyAnomalyDefaultMaxOperations
+	}
+	operations, err := simplelru.NewLRU[operation, *operationLatencies](maxOperations, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &latencyAnomaly{
+		logger:           settings.Logger,
+		percentile:       percentile,
+		medianMultiplier: medianMultiplier,
+		warmUpTraces:     uint64(warmUpTraces),
+		window:           newWindow(window, timeProvider),
+		operations:       operations,
+	}, nil
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (l *latencyAnomaly) Evaluate(_ context.Context, _ pcommon.TraceID, trace *TraceData) (Decision, error) {
+	l.logger.Debug("Evaluating spans in latency anomaly filter")
+
+	trace.Lock()
+	op, duration, ok := traceOperation(trace.ReceivedBatches)
+	trace.Unlock()
+	if !ok {
+		return NotSampled, nil
+	}
+	latency := float64(duration) / float64(time.Millisecond)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rotateWindow(&l.window, l.operations, func(latencies *operationLatencies) bool {
+		if latencies.current.count == 0 {
+			return false
+		}
+		latencies.previous, latencies.current = latencies.current, newQuantileSketch()
+		return true
+	})
+	latencies, ok := l.operations.Get(op)
+	if !ok {
+		latencies = &operationLatencies{current: newQuantileSketch(), previous: newQuantileSketch()}
+		l.operations.Add(op, latencies)
+	}
+
+	decision := NotSampled
+	if latencies.current.count+latencies.previous.count >= l.warmUpTraces {
+		if l.percentile > 0 && latency > quantile(l.percentile/100, latencies.current, latencies.previous) {
+			decision = Sampled
+		}
+		if l.medianMultiplier > 0 && latency > l.medianMultiplier*quantile(0.5, latencies.current, latencies.previous) {
+			decision = Sampled
+		}
+	}
+	latencies.current.add(latency)
+	return decision, nil
+}
+
+// traceOperation returns the operation of a trace, given by its root span, and
+// the duration of the trace, from the earliest start to the latest end of its
+// spans. The root span is the first span to start among the ones without a
+// parent, or among all the spans if none is without a parent. It returns false
+// if the trace has no span.
+func traceOperation(td ptrace.Traces) (operation, time.Duration, bool) {
+	var op operation
+	var root ptrace.Span
+	var hasRoot, found bool
+	var minTime, maxTime pcommon.Timestamp
+
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		var serviceName string
+		if svcAttr, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			serviceName = svcAttr.AsString()
+		}
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				if !found || span.StartTimestamp() < minTime {
+					minTime = span.StartTimestamp()
+				}
+				if !found || span.EndTimestamp() > maxTime {
+					maxTime = span.EndTimestamp()
+				}
+
+				isRoot := span.ParentSpanID().IsEmpty()
+				if !found || (isRoot && !hasRoot) || (isRoot == hasRoot && span.StartTimestamp() < root.StartTimestamp()) {
+					root, hasRoot = span, isRoot
+					op = operation{service: serviceName, rootName: span.Name()}
+				}
+				found = true
+			}
+		}
+	}
+	if !found || maxTime < minTime {
+		return op, 0, found
+	}
+	return op, time.Duration(maxTime - minTime), true
+}
@@ -0,0 +1,205 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// operationTrace returns a trace of a single root span of the given service and name.
+func operationTrace(service, name string, duration time.Duration) *TraceData {
+	return newTestTrace(testSpan{service: service, name: name, end: duration})
+}
+
+func TestLatencyAnomalyThresholdErrors(t *testing.T) {
+	_, err := NewLatencyAnomaly(componenttest.NewNopTelemetrySettings(), 0, 0, 0, 0, 0, MonotonicClock{})
+	assert.EqualError(t, err, "expected a percentile or a median multiplier")
+
+	_, err = NewLatencyAnomaly(componenttest.NewNopTelemetrySettings(), 100, 0, 0, 0, 0, MonotonicClock{})
+	assert.EqualError(t, err, "the percentile must be below 100")
+}
+
+func TestTraceOperation(t *testing.T) {
+	tests := []struct {
+		name             string
+		spans            []testSpan
+		expected         operation
+		expectedDuration time.Duration
+	}{
+		{
+			name: "the duration covers a child outliving the root",
+			spans: []testSpan{
+				{service: "db", name: "query", parent: 1, end: time.Second},
+				{service: "checkout", name: "GET /cart", end: time.Second / 2},
+			},
+			expected:         operation{service: "checkout", rootName: "GET /cart"},
+			expectedDuration: time.Second,
+		},
+		{
+			name: "the first root to start is the root of the trace",
+			spans: []testSpan{
+				{service: "worker", name: "consume", start: time.Second, end: 2 * time.Second},
+				{service: "checkout", name: "POST /order", end: time.Second},
+			},
+			expected:         operation{service: "checkout", rootName: "POST /order"},
+			expectedDuration: 2 * time.Second,
+		},
+		{
+			name: "the first span to start is the root of a trace missing its root",
+			spans: []testSpan{
+				{service: "db", name: "query", parent: 2, start: time.Second, end: 2 * time.Second},
+				{service: "checkout", name: "charge", parent: 1, end: time.Second},
+			},
+			expected:         operation{service: "checkout", rootName: "charge"},
+			expectedDuration: 2 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, duration, ok := traceOperation(newTestTraces(tt.spans...))
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, op)
+			assert.Equal(t, tt.expectedDuration, duration)
+		})
+	}
+
+	_, _, ok := traceOperation(ptrace.NewTraces())
+	assert.False(t, ok)
+}
+
+func TestLatencyAnomaly(t *testing.T) {
+	cases := []struct {
+		desc             string
+		percentile       float64
+		medianMultiplier float64
+		latency          time.Duration
+		decision         Decision
+	}{
+		{
+			desc:       "above percentile",
+			percentile: 90,
+			latency:    97 * time.Millisecond,
+			decision:   Sampled,
+		},
+		{
+			desc:       "below percentile",
+			percentile: 90,
+			latency:    85 * time.Millisecond,
+			decision:   NotSampled,
+		},
+		{
+			desc:             "above median multiple",
+			medianMultiplier: 3,
+			latency:          160 * time.Millisecond,
+			decision:         Sampled,
+		},
+		{
+			desc:             "below median multiple",
+			medianMultiplier: 3,
+			latency:          140 * time.Millisecond,
+			decision:         NotSampled,
+		},
+		{
+			desc:             "above percentile only",
+			percentile:       90,
+			medianMultiplier: 3,
+			latency:          97 * time.Millisecond,
+			decision:         Sampled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			filter, err := NewLatencyAnomaly(componenttest.NewNopTelemetrySettings(), c.percentile, c.medianMultiplier, time.Minute, 100, 10, &FakeTimeProvider{second: 1})
+			require.NoError(t, err)
+
+			// Warm up with latencies from 1ms to 100ms.
+			for i := 1; i <= 100; i++ {
+				decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, operationTrace("checkout", "GET /cart", time.Duration(i)*time.Millisecond))
+				require.NoError(t, err)
+				assert.Equal(t, NotSampled, decision)
+			}
+
+			decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, operationTrace("checkout", "GET /cart", c.latency))
+			require.NoError(t, err)
+			assert.Equal(t, c.decision, decision)
+		})
+	}
+}
+
+func TestLatencyAnomalyOperations(t *testing.T) {
+	filter, err := NewLatencyAnomaly(componenttest.NewNopTelemetrySettings(), 50, 0, time.Minute, 5, 10, &FakeTimeProvider{second: 1})
+	require.NoError(t, err)
+	evaluate := func(service, name string, latency time.Duration) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, operationTrace(service, name, latency))
+		require.NoError(t, err)
+		return decision
+	}
+
+	for i := 0; i < 5; i++ {
+		evaluate("checkout", "GET /cart", 10*time.Millisecond)
+	}
+	assert.Equal(t, Sampled, evaluate("checkout", "GET /cart", time.Second))
+	// The same root span name in another service is another operation, still warming up.
+	assert.Equal(t, NotSampled, evaluate("cart", "GET /cart", time.Hour))
+	// Traces without spans have no operation.
+	decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, &TraceData{ReceivedBatches: ptrace.NewTraces()})
+	require.NoError(t, err)
+	assert.Equal(t, NotSampled, decision)
+}
+
+func TestLatencyAnomalyWarmUpSpansWindows(t *testing.T) {
+	clock := &FakeTimeProvider{second: 0}
+	filter, err := NewLatencyAnomaly(componenttest.NewNopTelemetrySettings(), 50, 0, 10*time.Second, 10, 10, clock)
+	require.NoError(t, err)
+	evaluate := func(latency time.Duration) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, operationTrace("checkout", "GET /cart", latency))
+		require.NoError(t, err)
+		return decision
+	}
+
+	for i := 0; i < 6; i++ {
+		evaluate(100 * time.Millisecond)
+	}
+	// The traces of the previous window count towards the warm-up, and their latencies towards the percentile: a
+	// trace faster than all the ones of the current window is still slower than the median.
+	clock.second = 10
+	for i := 0; i < 4; i++ {
+		evaluate(time.Second)
+	}
+	assert.Equal(t, Sampled, evaluate(500*time.Millisecond))
+	assert.Equal(t, NotSampled, evaluate(50*time.Millisecond))
+
+	// The traces of the window before the previous one are forgotten, so the operation warms up again.
+	clock.second = 20
+	for i := 0; i < 3; i++ {
+		evaluate(time.Millisecond)
+	}
+	assert.Equal(t, NotSampled, evaluate(time.Hour))
+}
+
+func TestLatencyAnomalyEvictedOperationWarmsUp(t *testing.T) {
+	filter, err := NewLatencyAnomaly(componenttest.NewNopTelemetrySettings(), 50, 0, time.Minute, 2, 1, &FakeTimeProvider{second: 1})
+	require.NoError(t, err)
+	evaluate := func(name string, latency time.Duration) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, operationTrace("checkout", name, latency))
+		require.NoError(t, err)
+		return decision
+	}
+
+	evaluate("GET /a", time.Millisecond)
+	evaluate("GET /a", time.Millisecond)
+	assert.Equal(t, Sampled, evaluate("GET /a", time.Second))
+	// Only one operation is kept, /b evicts /a, which warms up again when seen next.
+	evaluate("GET /b", time.Millisecond)
+	assert.Equal(t, NotSampled, evaluate("GET /a", time.Hour))
+}
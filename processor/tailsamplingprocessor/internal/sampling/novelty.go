@@ -0,0 +1,141 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+const (
+	noveltyDefaultFirstTraces = 1
+	noveltyDefaultWindow      = time.Hour
+	noveltyDefaultMaxValues   = 10000
+)
+
+// attributeValue is a value of one of the attributes watched for new values.
+type attributeValue struct {
+	key   string
+	value string
+}
+
+// valueStats holds the number of traces seen with a value since it is new.
+type valueStats struct {
+	traces int64
+	// seen is true if the value was seen during the current window.
+	seen bool
+}
+
+type novelty struct {
+	logger      *zap.Logger
+	keys        []string
+	firstTraces int64
+
+	mu     sync.Mutex
+	window window
+	values *simplelru.LRU[attributeValue, *valueStats]
+}
+
+var _ PolicyEvaluator = (*novelty)(nil)
+
+// NewNovelty creates a policy evaluator sampling the first firstTraces traces
+// with each new value of the given resource or span attributes. A value is new
+// when it wasn't seen before, or wasn't seen during a whole window. When more
+// than maxValues values are tracked, the least recently seen one is forgotten.
+func NewNovelty(settings component.TelemetrySettings, keys []string, firstTraces int64, window time.Duration, maxValues int, timeProvider TimeProvider) (PolicyEvaluator, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("expected at least one attribute key")
+	}
+	if firstTraces <= 0 {
+		firstTraces = noveltyDefaultFirstTraces
+	}
+	if window <= 0 {
+		window = noveltyDefaultWindow
+	}
+	if maxValues <= 0 {
+		maxValues = noveltyDefaultMaxValues
+	}
+	values, err := simplelru.NewLRU[attributeValue, *valueStats](maxValues, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &novelty{
+		logger:      settings.Logger,
+		keys:        keys,
+		firstTraces: firstTraces,
+		window:      newWindow(window, timeProvider),
+		values:      values,
+	}, nil
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (n *novelty) Evaluate(_ context.Context, _ pcommon.TraceID, trace *TraceData) (Decision, error) {
+	n.logger.Debug("Evaluating spans in novelty filter")
+
+	trace.Lock()
+	values := n.traceValues(trace.ReceivedBatches)
+	trace.Unlock()
+	if len(values) == 0 {
+		return NotSampled, nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	rotateWindow(&n.window, n.values, func(stats *valueStats) bool {
+		if !stats.seen {
+			return false
+		}
+		stats.seen = false
+		return true
+	})
+	decision := NotSampled
+	for value := range values {
+		stats, ok := n.values.Get(value)
+		if !ok {
+			stats = &valueStats{}
+			n.values.Add(value, stats)
+		}
+		stats.seen = true
+		stats.traces++
+		if stats.traces <= n.firstTraces {
+			decision = Sampled
+		}
+	}
+	return decision, nil
+}
+
+// traceValues returns the distinct values of the watched attributes in the
+// resources and spans of a trace.
+func (n *novelty) traceValues(td ptrace.Traces) map[attributeValue]struct{} {
+	values := map[attributeValue]struct{}{}
+	collect := func(attrs pcommon.Map) {
+		for _, key := range n.keys {
+			if v, ok := attrs.Get(key); ok {
+				values[attributeValue{key: key, value: v.AsString()}] = struct{}{}
+			}
+		}
+	}
+
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		collect(rs.Resource().Attributes())
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				collect(spans.At(k).Attributes())
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,113 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// routeTrace returns a trace with a span per route.
+func routeTrace(routes ...string) *TraceData {
+	spans := make([]testSpan, 0, len(routes))
+	for _, route := range routes {
+		spans = append(spans, testSpan{attrs: map[string]any{"http.route": route}})
+	}
+	return newTestTrace(spans...)
+}
+
+func TestNoveltyRequiresKeys(t *testing.T) {
+	_, err := NewNovelty(componenttest.NewNopTelemetrySettings(), nil, 0, 0, 0, MonotonicClock{})
+	assert.EqualError(t, err, "expected at least one attribute key")
+}
+
+func TestNoveltyTraceValues(t *testing.T) {
+	filter, err := NewNovelty(componenttest.NewNopTelemetrySettings(), []string{"http.route", "deployment.version", "http.status_code"}, 1, 0, 0, &FakeTimeProvider{})
+	require.NoError(t, err)
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("deployment.version", "1.0")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, route := range []string{"/cart", "/cart", "1.0"} {
+		span := spans.AppendEmpty()
+		span.Attributes().PutStr("http.route", route)
+		span.Attributes().PutInt("http.status_code", 200)
+	}
+	spans.AppendEmpty().Attributes().PutStr("deployment.version", "1.0")
+
+	// Values are distinct per key, found on resources and spans, and compared as strings.
+	assert.Equal(t, map[attributeValue]struct{}{
+		{key: "deployment.version", value: "1.0"}: {},
+		{key: "http.route", value: "/cart"}:       {},
+		{key: "http.route", value: "1.0"}:         {},
+		{key: "http.status_code", value: "200"}:   {},
+	}, filter.(*novelty).traceValues(traces))
+}
+
+func TestNoveltyFirstTraces(t *testing.T) {
+	filter, err := NewNovelty(componenttest.NewNopTelemetrySettings(), []string{"http.route"}, 2, 0, 0, &FakeTimeProvider{})
+	require.NoError(t, err)
+	evaluate := func(routes ...string) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, routeTrace(routes...))
+		require.NoError(t, err)
+		return decision
+	}
+
+	// A value repeated in a trace counts as a single trace.
+	assert.Equal(t, Sampled, evaluate("/cart", "/cart"))
+	assert.Equal(t, Sampled, evaluate("/cart"))
+	assert.Equal(t, NotSampled, evaluate("/cart"))
+	// A single new value among known ones samples the trace, and all the values are counted.
+	assert.Equal(t, Sampled, evaluate("/cart", "/checkout"))
+	assert.Equal(t, Sampled, evaluate("/checkout"))
+	assert.Equal(t, NotSampled, evaluate("/checkout"))
+	assert.Equal(t, NotSampled, evaluate())
+}
+
+func TestNoveltyValueSeenEveryWindow(t *testing.T) {
+	clock := &FakeTimeProvider{}
+	filter, err := NewNovelty(componenttest.NewNopTelemetrySettings(), []string{"http.route"}, 2, 10*time.Second, 0, clock)
+	require.NoError(t, err)
+	evaluate := func(route string) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, routeTrace(route))
+		require.NoError(t, err)
+		return decision
+	}
+
+	assert.Equal(t, Sampled, evaluate("/cart"))
+	// The count of a value seen in the previous window isn't reset, so it's not sampled beyond the first traces.
+	clock.second = 15
+	assert.Equal(t, Sampled, evaluate("/cart"))
+	clock.second = 25
+	assert.Equal(t, NotSampled, evaluate("/cart"))
+	// Once a whole window went by without it, the value is new again.
+	clock.second = 45
+	assert.Equal(t, Sampled, evaluate("/cart"))
+}
+
+func TestNoveltyEvictedValueIsNew(t *testing.T) {
+	filter, err := NewNovelty(componenttest.NewNopTelemetrySettings(), []string{"http.route"}, 1, 0, 2, &FakeTimeProvider{})
+	require.NoError(t, err)
+	evaluate := func(route string) Decision {
+		decision, err := filter.Evaluate(context.Background(), pcommon.TraceID{}, routeTrace(route))
+		require.NoError(t, err)
+		return decision
+	}
+
+	assert.Equal(t, Sampled, evaluate("/a"))
+	assert.Equal(t, Sampled, evaluate("/b"))
+	// Seeing /a again keeps it, /b being the least recently seen value evicted by /c.
+	assert.Equal(t, NotSampled, evaluate("/a"))
+	assert.Equal(t, Sampled, evaluate("/c"))
+	assert.Equal(t, NotSampled, evaluate("/a"))
+	assert.Equal(t, Sampled, evaluate("/b"))
+}
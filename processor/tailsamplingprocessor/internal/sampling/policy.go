@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
@@ -24,8 +25,63 @@ type TraceData struct {
 	SpanCount *atomic.Int64
 	// ReceivedBatches stores all the batches received for the trace.
 	ReceivedBatches ptrace.Traces
+	// ReceivedLogs stores the log records of the trace received while the decision is pending, when the processor is part
+	// of a logs pipeline. It is empty until a log record of the trace is received.
+	ReceivedLogs plog.Logs
 	// FinalDecision.
 	FinalDecision Decision
+	// SamplingProbability is the probability with which the last policy evaluating the trace sampled it. Policy
+	// evaluators sampling traces probabilistically set it with SetSampling when sampling a trace, it is zero otherwise.
+	// Like the other fields describing the decision, it is read when spans of the trace are received, so it's only
+	// accessed under the lock of the trace.
+	SamplingProbability float64
+	// SamplingThreshold is the OpenTelemetry consistent sampling threshold, encoded as the th key of the tracestate,
+	// with which the last policy evaluating the trace sampled it. Policy evaluators sampling traces consistently set it
+	// along with SamplingProbability, it is empty otherwise.
+	SamplingThreshold string
+	// SampledBy is the name of the policy that sampled the trace, once the final decision was made.
+	SampledBy string
+	// DroppedBy is the name of the policy that dropped the trace, once the final decision was made, be it a drop policy
+	// or an inverted match.
+	DroppedBy string
+	// Completeness tracks whether all the spans of the trace were received, when early decisions are enabled. It is
+	// nil otherwise.
+	Completeness *TraceCompleteness
+	// Stages tracks the decision stages that evaluated the trace without deciding on it.
+	Stages EvaluatedStages
+}
+
+// SetSampling sets the sampling probability and threshold the trace was sampled with, under the lock of the trace.
+func (td *TraceData) SetSampling(probability float64, threshold string) {
+	td.Lock()
+	defer td.Unlock()
+	td.SamplingProbability, td.SamplingThreshold = probability, threshold
+}
+
+// Sampling returns the sampling probability and threshold the trace was sampled with, under the lock of the trace.
+func (td *TraceData) Sampling() (float64, string) {
+	td.Lock()
+	defer td.Unlock()
+	return td.SamplingProbability, td.SamplingThreshold
+}
+
+// EvaluatedStages tracks the decision stages, evaluating the policies with a decision_wait shorter than the one of the
+// processor, that evaluated a trace without deciding on it. Their policies are not evaluated again on the trace.
+type EvaluatedStages struct {
+	// Count is the number of stages, in order of decision_wait, that evaluated the trace.
+	Count int
+	// Decisions holds the decisions of the policies of these stages that are combined with the decisions of the
+	// policies evaluated later.
+	Decisions []PolicyDecision
+}
+
+// PolicyDecision is the decision of a policy on a trace, along with the sampling probability and threshold the policy
+// reported.
+type PolicyDecision struct {
+	Policy      string
+	Decision    Decision
+	Probability float64
+	Threshold   string
 }
 
 // Decision gives the status of sampling decision.
@@ -53,6 +109,9 @@ const (
 	// InvertNotSampled is used on the invert match flow and indicates to not
 	// sample the data.
 	InvertNotSampled
+	// Abstain indicates that the policy has no opinion on the trace, and must be
+	// ignored when combining the decisions of the policies.
+	Abstain
 )
 
 // PolicyEvaluator implements a tail-based sampling policy evaluator,
@@ -19,9 +19,10 @@ const (
 )
 
 type probabilisticSampler struct {
-	logger    *zap.Logger
-	threshold uint64
-	hashSalt  string
+	logger      *zap.Logger
+	threshold   uint64
+	hashSalt    string
+	probability float64
 }
 
 var _ PolicyEvaluator = (*probabilisticSampler)(nil)
@@ -36,16 +37,20 @@ func NewProbabilisticSampler(settings component.TelemetrySettings, hashSalt stri
 	return &probabilisticSampler{
 		logger: settings.Logger,
 		// calculate threshold once
-		threshold: calculateThreshold(samplingPercentage / 100),
-		hashSalt:  hashSalt,
+		threshold:   calculateThreshold(samplingPercentage / 100),
+		hashSalt:    hashSalt,
+		probability: min(1, samplingPercentage/100),
 	}
 }
 
 // Evaluate looks at the trace data and returns a corresponding SamplingDecision.
-func (s *probabilisticSampler) Evaluate(_ context.Context, traceID pcommon.TraceID, _ *TraceData) (Decision, error) {
+func (s *probabilisticSampler) Evaluate(_ context.Context, traceID pcommon.TraceID, trace *TraceData) (Decision, error) {
 	s.logger.Debug("Evaluating spans in probabilistic filter")
 
 	if hashTraceID(s.hashSalt, traceID[:]) <= s.threshold {
+		if trace != nil {
+			trace.SetSampling(s.probability, "")
+		}
 		return Sampled, nil
 	}
 
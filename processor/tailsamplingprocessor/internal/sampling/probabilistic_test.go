@@ -79,6 +79,7 @@ func TestProbabilisticSampling(t *testing.T) {
 
 				if decision == Sampled {
 					sampled++
+					assert.Equal(t, min(1, tt.samplingPercentage/100), trace.SamplingProbability)
 				}
 			}
 
@@ -0,0 +1,109 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import "math"
+
+// sketchRelativeAccuracy is the relative accuracy of the quantiles returned by
+// a quantileSketch.
+const sketchRelativeAccuracy = 0.01
+
+// quantileSketch is a streaming sketch of the quantiles of positive values,
+// after DDSketch: values are counted in buckets whose bounds grow
+// exponentially, so that any quantile is returned within the relative accuracy
+// of the sketch, with a number of buckets logarithmic in the range of values.
+// The buckets are kept in a slice, so that quantiles are computed without
+// sorting them. quantileSketch is not safe for concurrent use.
+type quantileSketch struct {
+	// counts holds the number of values of each bucket, from the bucket of
+	// index offset.
+	counts []uint64
+	offset int
+	// zeros is the number of values too small to be put in a bucket.
+	zeros uint64
+	count uint64
+}
+
+var (
+	sketchGamma    = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+	// sketchMinValue is the smallest value put in a bucket.
+	sketchMinValue = math.Pow(sketchGamma, math.MinInt16)
+)
+
+func newQuantileSketch() *quantileSketch {
+	return &quantileSketch{}
+}
+
+// add counts a value.
+func (s *quantileSketch) add(v float64) {
+	s.count++
+	if v < sketchMinValue {
+		s.zeros++
+		return
+	}
+	index := int(math.Ceil(math.Log(v) / sketchLogGamma))
+	switch {
+	case len(s.counts) == 0:
+		s.offset = index
+		s.counts = append(s.counts, 0)
+	case index < s.offset:
+		s.counts = append(make([]uint64, s.offset-index), s.counts...)
+		s.offset = index
+	case index >= s.end():
+		s.counts = append(s.counts, make([]uint64, index-s.end()+1)...)
+	}
+	s.counts[index-s.offset]++
+}
+
+// end returns the index following the last bucket of the sketch.
+func (s *quantileSketch) end() int {
+	return s.offset + len(s.counts)
+}
+
+// bucket returns the number of values of the bucket of the given index.
+func (s *quantileSketch) bucket(index int) uint64 {
+	if index < s.offset || index >= s.end() {
+		return 0
+	}
+	return s.counts[index-s.offset]
+}
+
+// quantile returns the q quantile, between 0 and 1, of the values counted by
+// the given sketches together, or 0 if they didn't count any.
+func quantile(q float64, sketches ...*quantileSketch) float64 {
+	var count, zeros uint64
+	start, end := math.MaxInt, math.MinInt
+	for _, s := range sketches {
+		count += s.count
+		zeros += s.zeros
+		if len(s.counts) > 0 {
+			start, end = min(start, s.offset), max(end, s.end())
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(count-1))
+	if rank < zeros {
+		return 0
+	}
+	seen := zeros
+	for i := start; i < end; i++ {
+		for _, s := range sketches {
+			seen += s.bucket(i)
+		}
+		if seen > rank {
+			return bucketValue(i)
+		}
+	}
+	return bucketValue(end - 1)
+}
+
+// bucketValue returns the value representing the bucket of the given index,
+// within the relative accuracy of the sketch from all the values of the bucket.
+func bucketValue(index int) float64 {
+	return 2 * math.Pow(sketchGamma, float64(index)) / (sketchGamma + 1)
+}
@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuantileSketch(t *testing.T) {
+	s := newQuantileSketch()
+	for v := 1; v <= 1000; v++ {
+		s.add(float64(v))
+	}
+	assert.Equal(t, uint64(1000), s.count)
+
+	for _, tc := range []struct {
+		q        float64
+		expected float64
+	}{
+		{q: 0, expected: 1},
+		{q: 0.5, expected: 500},
+		{q: 0.99, expected: 990},
+		{q: 1, expected: 1000},
+	} {
+		assert.InEpsilon(t, tc.expected, quantile(tc.q, s), sketchRelativeAccuracy)
+	}
+}
+
+func TestQuantileSketchMerge(t *testing.T) {
+	low, high := newQuantileSketch(), newQuantileSketch()
+	for v := 1; v <= 100; v++ {
+		low.add(float64(v))
+		high.add(float64(v + 100))
+	}
+	assert.InEpsilon(t, 100, quantile(0.5, low, high), sketchRelativeAccuracy)
+	assert.InEpsilon(t, 198, quantile(0.99, low, high), sketchRelativeAccuracy)
+}
+
+func TestQuantileSketchZeros(t *testing.T) {
+	s := newQuantileSketch()
+	assert.Zero(t, quantile(0.5, s))
+
+	s.add(0)
+	s.add(0)
+	s.add(10)
+	assert.Zero(t, quantile(0.5, s))
+	assert.InEpsilon(t, 10, quantile(1, s), sketchRelativeAccuracy)
+}
+
+func TestQuantileSketchBucketsGrowBothWays(t *testing.T) {
+	s := newQuantileSketch()
+	for _, v := range []float64{100, 1, 10000, 50} {
+		s.add(v)
+	}
+	assert.InEpsilon(t, 1, quantile(0, s), sketchRelativeAccuracy)
+	assert.InEpsilon(t, 50, quantile(0.34, s), sketchRelativeAccuracy)
+	assert.InEpsilon(t, 10000, quantile(1, s), sketchRelativeAccuracy)
+}
+
+func TestQuantileDoesNotAllocate(t *testing.T) {
+	current, previous := newQuantileSketch(), newQuantileSketch()
+	for v := 1; v <= 1000; v++ {
+		current.add(float64(v))
+		previous.add(float64(2 * v))
+	}
+	assert.Zero(t, testing.AllocsPerRun(10, func() {
+		quantile(0.99, current, previous)
+	}))
+}
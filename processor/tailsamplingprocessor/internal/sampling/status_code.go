@@ -19,7 +19,10 @@ type statusCodeFilter struct {
 	statusCodes []ptrace.StatusCode
 }
 
-var _ PolicyEvaluator = (*statusCodeFilter)(nil)
+var (
+	_ PolicyEvaluator  = (*statusCodeFilter)(nil)
+	_ SummaryEvaluator = (*statusCodeFilter)(nil)
+)
 
 // NewStatusCodeFilter creates a policy evaluator that samples all traces with
 // a given status code.
@@ -66,3 +69,13 @@ func (r *statusCodeFilter) Evaluate(_ context.Context, _ pcommon.TraceID, trace
 		return false
 	}), nil
 }
+
+// EvaluateSummary looks at the status codes of the spans of the trace and returns a corresponding SamplingDecision.
+func (r *statusCodeFilter) EvaluateSummary(summary *TraceSummary) Decision {
+	for _, statusCode := range r.statusCodes {
+		if summary.HasStatusCode(statusCode) {
+			return Sampled
+		}
+	}
+	return NotSampled
+}
@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// ThroughputTarget is the sampled throughput the stratified sampler aims for, instead of a fixed sampling percentage.
+// At most one of its fields can be set. A zero ThroughputTarget disables adaptive sampling.
+type ThroughputTarget struct {
+	// TracesPerSecond is the number of sampled traces per second to aim for.
+	TracesPerSecond float64
+	// SpansPerSecond is the number of spans of sampled traces per second to aim for.
+	SpansPerSecond float64
+}
+
+func (t ThroughputTarget) validate() error {
+	if t.TracesPerSecond < 0 || t.SpansPerSecond < 0 {
+		return errors.New("target throughput cannot be negative")
+	}
+	if t.TracesPerSecond > 0 && t.SpansPerSecond > 0 {
+		return errors.New("only one of traces or spans per second can be targeted")
+	}
+	return nil
+}
+
+func (t ThroughputTarget) enabled() bool {
+	return t.TracesPerSecond > 0 || t.SpansPerSecond > 0
+}
+
+// perSecond returns the targeted number of traces or spans per second.
+func (t ThroughputTarget) perSecond() float64 {
+	if t.SpansPerSecond > 0 {
+		return t.SpansPerSecond
+	}
+	return t.TracesPerSecond
+}
+
+// adaptiveThreshold returns the threshold the hash of a trace ID of the given trajectory is compared to, when
+// targeting a throughput, along with the matching sampling probability. The budget is spread across trajectories by inverse-frequency weighting: each trajectory
+// is allowed the same share of the budget, trajectories needing less than their share leaving the remainder to the
+// others. The sampling probability of a trajectory is then its share divided by its own rate, so rare trajectories
+// are kept entirely while frequent ones are sampled down.
+func (s *StratifiedProbabilisticSampler) adaptiveThreshold(stats *trajectoryStats) (uint64, float64) {
+	if now := s.trajectories.window.timeProvider.getCurSecond(); now != s.allocatedAt {
+		s.allocate()
+		s.allocatedAt = now
+	}
+
+	rate := s.trajectoryRate(stats)
+	if rate <= s.allocation {
+		return math.MaxUint64, 1
+	}
+	probability := s.allocation / rate
+	return stratifiedCalculateThreshold(probability), probability
+}
+
+// allocate computes the share of the target throughput allocated to each trajectory. The traces kept because of the
+// minimum per trajectory are taken from the budget first.
+func (s *StratifiedProbabilisticSampler) allocate() {
+	windowSecs := float64(s.trajectories.window.secs)
+	minRate := float64(s.minTracesPerTrajectory) / windowSecs
+
+	rates := make([]float64, 0, s.trajectories.len())
+	guaranteed := 0.0
+	s.trajectories.forEach(func(_ string, stats *trajectoryStats) {
+		rate := s.trajectoryRate(stats)
+		tracesRate := s.rate(stats.seen, stats.prevSeen)
+		if rate == 0 || tracesRate == 0 {
+			return
+		}
+		rates = append(rates, rate)
+		// The traces kept for the minimum, converted to the targeted unit.
+		guaranteed += min(minRate, tracesRate) * rate / tracesRate
+	})
+
+	s.allocation = waterFill(rates, max(0, s.target.perSecond()-guaranteed))
+}
+
+// trajectoryRate estimates the number of traces per second of a trajectory or, when targeting spans, the number of
+// spans per second.
+func (s *StratifiedProbabilisticSampler) trajectoryRate(stats *trajectoryStats) float64 {
+	if s.target.SpansPerSecond > 0 {
+		return s.rate(stats.spans, stats.prevSpans)
+	}
+	return s.rate(stats.seen, stats.prevSeen)
+}
+
+// rate estimates a rate per second from the counts of the current and of the previous window. The rate of the current
+// window is used when it is higher than the rate of the previous window, so that surges are accounted for before the
+// window ends.
+func (s *StratifiedProbabilisticSampler) rate(current, previous int64) float64 {
+	return max(
+		float64(current)/float64(s.trajectories.elapsedSecs()),
+		float64(previous)/float64(s.trajectories.window.secs),
+	)
+}
+
+// waterFill returns the largest share such that the sum of min(rate, share) over all rates does not exceed the
+// budget. It returns +Inf if the budget covers all rates.
+func waterFill(rates []float64, budget float64) float64 {
+	slices.Sort(rates)
+	remaining := budget
+	for i, rate := range rates {
+		share := remaining / float64(len(rates)-i)
+		if rate > share {
+			return share
+		}
+		remaining -= rate
+	}
+	return math.Inf(1)
+}
@@ -0,0 +1,184 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestWaterFill(t *testing.T) {
+	tests := []struct {
+		name     string
+		rates    []float64
+		budget   float64
+		expected float64
+	}{
+		{
+			name:     "no trajectories",
+			rates:    nil,
+			budget:   10,
+			expected: math.Inf(1),
+		},
+		{
+			name:     "budget covers all",
+			rates:    []float64{1, 2, 3},
+			budget:   10,
+			expected: math.Inf(1),
+		},
+		{
+			name:     "equal shares",
+			rates:    []float64{100, 100},
+			budget:   10,
+			expected: 5,
+		},
+		{
+			name:     "rare trajectories leave their remainder",
+			rates:    []float64{1000, 2, 100},
+			budget:   10,
+			expected: 4,
+		},
+		{
+			name:     "no budget",
+			rates:    []float64{1, 2},
+			budget:   0,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, waterFill(tt.rates, tt.budget))
+		})
+	}
+}
+
+func TestStratifiedProbabilisticSamplerInvalidTarget(t *testing.T) {
+	for _, target := range []ThroughputTarget{
+		{TracesPerSecond: -1},
+		{SpansPerSecond: -1},
+		{TracesPerSecond: 1, SpansPerSecond: 1},
+	} {
+		_, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, nil, target, TrajectoryShape{}, MonotonicClock{})
+		assert.Error(t, err, "target %v", target)
+	}
+}
+
+func TestStratifiedProbabilisticSamplingTargetThroughput(t *testing.T) {
+	tests := []struct {
+		name   string
+		target ThroughputTarget
+		// sampled traces or spans per second expected in the steady state.
+		expected float64
+	}{
+		{
+			name:     "traces",
+			target:   ThroughputTarget{TracesPerSecond: 10},
+			expected: 10,
+		},
+		{
+			name:     "spans",
+			target:   ThroughputTarget{SpansPerSecond: 100},
+			expected: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeProvider := &FakeTimeProvider{second: 0}
+			s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 50, 10*time.Second, 0, minTraces(1), tt.target, TrajectoryShape{}, timeProvider)
+			require.NoError(t, err)
+
+			frequent := newTestTrace([]testSpan{
+				{service: "frontend", name: "GET", kind: ptrace.SpanKindServer, id: 1},
+			}...)
+			frequent.SpanCount = &atomic.Int64{}
+			frequent.SpanCount.Store(10)
+			rare := newTestTrace([]testSpan{
+				{service: "frontend", name: "POST", kind: ptrace.SpanKindServer, id: 1},
+			}...)
+			rare.SpanCount = &atomic.Int64{}
+			rare.SpanCount.Store(10)
+
+			const (
+				seconds          = 30
+				frequentPerSec   = 1000
+				rarePerSec       = 2
+				steadyStateStart = 10
+			)
+			traceIDs := genStratifiedRandomTraceIDs(seconds * (frequentPerSec + rarePerSec))
+
+			var sampledFrequent, sampledRare, seenRare int64
+			for second := int64(0); second < seconds; second++ {
+				timeProvider.second = second
+				ids := traceIDs[second*(frequentPerSec+rarePerSec) : (second+1)*(frequentPerSec+rarePerSec)]
+				for i, traceID := range ids {
+					trace := frequent
+					if i%(frequentPerSec/rarePerSec) == 0 && i/(frequentPerSec/rarePerSec) < rarePerSec {
+						trace = rare
+					}
+					decision, err := s.Evaluate(context.Background(), traceID, trace)
+					require.NoError(t, err)
+
+					if second < steadyStateStart {
+						continue
+					}
+					if trace == rare {
+						seenRare++
+					}
+					if decision == Sampled {
+						if trace == rare {
+							sampledRare++
+						} else {
+							sampledFrequent++
+						}
+					}
+				}
+			}
+
+			steadyStateSecs := float64(seconds - steadyStateStart)
+			sampled := float64(sampledFrequent + sampledRare)
+			if tt.target.SpansPerSecond > 0 {
+				sampled *= 10
+			}
+			// The sampling percentage is ignored.
+			assert.InDelta(t, tt.expected, sampled/steadyStateSecs, tt.expected*0.2)
+			// Rare trajectories are kept entirely.
+			assert.Equal(t, seenRare, sampledRare)
+		})
+	}
+}
+
+func TestStratifiedProbabilisticSamplingTargetOnlyMinimumAtStart(t *testing.T) {
+	timeProvider := &FakeTimeProvider{second: 0}
+	s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 10*time.Second, 0, minTraces(3), ThroughputTarget{TracesPerSecond: 100}, TrajectoryShape{}, timeProvider)
+	require.NoError(t, err)
+
+	trace := newTestTrace([]testSpan{{service: "frontend", name: "GET", id: 1}}...)
+	sampled := 0
+	for _, traceID := range genStratifiedRandomTraceIDs(50) {
+		decision, err := s.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+		if decision == Sampled {
+			sampled++
+		}
+	}
+	assert.Equal(t, 3, sampled)
+
+	// Once rates are known, the budget covers the whole trajectory.
+	timeProvider.second = 1
+	for _, traceID := range genStratifiedRandomTraceIDs(50) {
+		decision, err := s.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+		assert.Equal(t, Sampled, decision)
+	}
+}
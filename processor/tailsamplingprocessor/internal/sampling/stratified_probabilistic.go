@@ -7,30 +7,84 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"math"
 	"math/big"
 	"sort"
 	"strconv"
-	"sync"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/golang-lru/v2/simplelru"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
 )
 
 const (
 	stratifiedDefaultHashSalt = "default-hash-seed"
+	// stratifiedDefaultWindow is the window used to keep trajectory statistics when none is configured.
+	stratifiedDefaultWindow = time.Minute
+	// stratifiedDefaultMaxTrajectories is the number of trajectories tracked when no limit is configured.
+	stratifiedDefaultMaxTrajectories = 10000
+	// stratifiedDefaultMinTracesPerTrajectory is the number of traces kept per trajectory and window when
+	// no minimum is configured, i.e.: the first occurrence of a trajectory in each window is always sampled.
+	stratifiedDefaultMinTracesPerTrajectory = 1
+	// stratifiedTrajectoryAttrLength is the number of hex characters of the trajectory hash used as metric attribute.
+	stratifiedTrajectoryAttrLength = 8
+	// stratifiedMaxTrajectoryAttrs bounds the number of distinct trajectory attribute values reported. A trajectory
+	// only takes over the value of another one once that one wasn't seen for a whole window, trajectories beyond that
+	// are reported as stratifiedOtherTrajectoryAttr.
+	stratifiedMaxTrajectoryAttrs  = 256
+	stratifiedOtherTrajectoryAttr = "other"
 )
 
+// trajectoryAttrs holds the pre-built measurement options used to report a
+// trajectory's sampling decisions.
+type trajectoryAttrs struct {
+	sampled    metric.MeasurementOption
+	notSampled metric.MeasurementOption
+	// window is the last window the trajectory was seen in.
+	window uint64
+}
+
+func newTrajectoryAttrs(trajectory string) *trajectoryAttrs {
+	return &trajectoryAttrs{
+		sampled:    metric.WithAttributes(attribute.String("trajectory", trajectory), attribute.String("sampled", "true")),
+		notSampled: metric.WithAttributes(attribute.String("trajectory", trajectory), attribute.String("sampled", "false")),
+	}
+}
+
 type StratifiedProbabilisticSampler struct {
-	logger               *zap.Logger
-	threshold            uint64
-	hashSalt             string
-	mu                   sync.Mutex
-	traceTrajectoryCount map[string]int
+	logger                 *zap.Logger
+	threshold              uint64
+	probability            float64
+	hashSalt               string
+	minTracesPerTrajectory int64
+	mu                     sync.Mutex
+	trajectories           *trajectoryTable
+	telemetry              *metadata.TelemetryBuilder
+	policyName             string
+	policyAttr             metric.MeasurementOption
+	trajectoryAttrs        *simplelru.LRU[string, *trajectoryAttrs]
+	otherTrajectoryAttrs   *trajectoryAttrs
+	nodeFor                nodeFunc
+	maxDepth               int
+	target                 ThroughputTarget
+	mode                   SamplingMode
+	// window counts the windows of the trajectories, to tell which trajectory attribute values are still in use.
+	window uint64
+	// allocation is the share of the target throughput allocated to each trajectory, see adaptiveThreshold.
+	allocation float64
+	// allocatedAt is the second the allocation was computed at.
+	allocatedAt int64
 }
 
 type Node struct {
@@ -45,64 +99,207 @@ type Edge struct {
 
 var _ PolicyEvaluator = (*StratifiedProbabilisticSampler)(nil)
 
-// NewStratifiedProbabilisticSampler creates a policy evaluator that samples a percentage of traces.
-func NewStratifiedProbabilisticSampler(settings component.TelemetrySettings, hashSalt string, samplingPercentage float64) PolicyEvaluator {
+// NewStratifiedProbabilisticSampler creates a policy evaluator that samples a percentage of traces, while
+// guaranteeing that at least minTracesPerTrajectory traces of each trajectory are sampled per window, 1 when nil.
+// Trajectory statistics are kept for up to maxTrajectories trajectories and survive across windows, so
+// that the sampling rate of a trajectory does not depend on how often decisions are evaluated.
+// The shape defines how the trajectory of a trace is built from its spans. When a target throughput is given, the
+// sampling percentage is ignored and the sampling probability of each trajectory is adapted to reach the target.
+// The mode defines whether the trace ID is hashed with the salt or traces are sampled consistently. The metrics of the
+// sampler are reported with the name of its policy.
+func NewStratifiedProbabilisticSampler(
+	settings component.TelemetrySettings,
+	policyName string,
+	hashSalt string,
+	mode SamplingMode,
+	samplingPercentage float64,
+	window time.Duration,
+	maxTrajectories int,
+	minTracesPerTrajectory *int64,
+	target ThroughputTarget,
+	shape TrajectoryShape,
+	timeProvider TimeProvider,
+) (PolicyEvaluator, error) {
 	if hashSalt == "" {
 		hashSalt = stratifiedDefaultHashSalt
 	}
+	if window <= 0 {
+		window = stratifiedDefaultWindow
+	}
+	if maxTrajectories <= 0 {
+		maxTrajectories = stratifiedDefaultMaxTrajectories
+	}
+	minTraces := int64(stratifiedDefaultMinTracesPerTrajectory)
+	if minTracesPerTrajectory != nil {
+		minTraces = *minTracesPerTrajectory
+	}
+	if minTraces < 0 {
+		return nil, errors.New("minimum number of traces per trajectory cannot be negative")
+	}
+
+	if err := mode.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := target.validate(); err != nil {
+		return nil, err
+	}
+
+	if shape.MaxDepth < 0 {
+		return nil, errors.New("max depth of trajectories cannot be negative")
+	}
+
+	nodeFor, err := newNodeFunc(settings, shape)
+	if err != nil {
+		return nil, err
+	}
+
+	trajectories, err := newTrajectoryTable(window, maxTrajectories, timeProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	trajectoryAttrs, err := simplelru.NewLRU[string, *trajectoryAttrs](stratifiedMaxTrajectoryAttrs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetry, err := metadata.NewTelemetryBuilder(settings)
+	if err != nil {
+		return nil, err
+	}
 
 	return &StratifiedProbabilisticSampler{
 		logger: settings.Logger,
 		// calculate threshold once
-		threshold:            stratifiedCalculateThreshold(samplingPercentage / 100),
-		hashSalt:             hashSalt,
-		traceTrajectoryCount: make(map[string]int),
-	}
+		threshold:              stratifiedCalculateThreshold(samplingPercentage / 100),
+		probability:            min(1, samplingPercentage/100),
+		hashSalt:               hashSalt,
+		minTracesPerTrajectory: minTraces,
+		trajectories:           trajectories,
+		telemetry:              telemetry,
+		policyName:             policyName,
+		policyAttr:             metric.WithAttributes(attribute.String("policy", policyName)),
+		trajectoryAttrs:        trajectoryAttrs,
+		otherTrajectoryAttrs:   newTrajectoryAttrs(stratifiedOtherTrajectoryAttr),
+		nodeFor:                nodeFor,
+		maxDepth:               shape.MaxDepth,
+		target:                 target,
+		mode:                   mode,
+		// No traces beyond the minimum per trajectory are sampled until rates are known.
+		allocatedAt: timeProvider.getCurSecond(),
+	}, nil
 }
 
 // Evaluate looks at the trace data and returns a corresponding SamplingDecision.
-func (s *StratifiedProbabilisticSampler) Evaluate(_ context.Context, traceID pcommon.TraceID, traceData *TraceData) (Decision, error) {
+func (s *StratifiedProbabilisticSampler) Evaluate(ctx context.Context, traceID pcommon.TraceID, traceData *TraceData) (Decision, error) {
 	s.logger.Debug("Evaluating spans in stratified probabilistic filter")
-	hash, _ := s.getTraceTrajectoryHash(traceData)
-	s.logger.Debug("Graph representation recieved", zap.String("Trace Identifier", traceID.String()), zap.String("Trace Hash", hash),)
-
-	var count int
-	var exists bool
+	hash, edges, err := s.getTraceTrajectoryHash(ctx, traceData)
+	if err != nil {
+		return Error, err
+	}
+	s.logger.Debug("Graph representation received", zap.String("Trace Identifier", traceID.String()), zap.String("Trace Hash", hash))
 
 	s.mu.Lock()
-	count, exists = s.traceTrajectoryCount[hash]
-	if exists {
-		// Trajectory exist. Update the count
-		s.traceTrajectoryCount[hash] = count + 1
-		s.logger.Debug("Trajectory exists", zap.String("Trace Hash", hash), zap.String("Check for Trace Identifier", traceID.String()), zap.Int("TraceCount", count))
+	defer s.mu.Unlock()
+
+	s.rotate()
+	stats, isNew := s.trajectories.record(hash)
+	if traceData.SpanCount != nil {
+		stats.spans += traceData.SpanCount.Load()
+	}
+	if isNew {
+		stats.edges = edges
+		s.logger.Debug("New Trajectory", zap.String("Trace Hash", hash), zap.String("Check for Trace Identifier", traceID.String()))
+		s.telemetry.ProcessorTailSamplingStratifiedNewTrajectories.Add(ctx, 1, s.policyAttr)
+	}
+	s.telemetry.ProcessorTailSamplingStratifiedTrajectories.Record(ctx, int64(s.trajectories.distinctInWindow()), s.policyAttr)
+
+	decision := NotSampled
+	probability := 1.0
+	switch {
+	case stats.sampled < s.minTracesPerTrajectory:
+		// Keep the configured minimum of traces per trajectory and window, so rare trajectories are
+		// sampled at a steady rate.
+		decision = Sampled
+		if s.mode == ConsistentMode {
+			// Record the threshold of the trace, unchanged by a sampling probability of 1.
+			consistentDecision(s.logger, traceID, traceData, probability)
+		}
+	case s.mode == ConsistentMode:
+		// Same as below, comparing the randomness of the trace with the threshold of the probability instead.
+		probability = s.probability
+		if s.target.enabled() {
+			_, probability = s.adaptiveThreshold(stats)
+		}
+		decision = consistentDecision(s.logger, traceID, traceData, probability)
+	case s.target.enabled():
+		// Spread the target throughput across trajectories once the minimum for the trajectory has been reached.
+		var threshold uint64
+		threshold, probability = s.adaptiveThreshold(stats)
+		if stratifiedHashTraceID(s.hashSalt, traceID[:]) <= threshold {
+			decision = Sampled
+		}
+	case stratifiedHashTraceID(s.hashSalt, traceID[:]) <= s.threshold:
+		// Fallback to probabilistic sampling once the minimum for the trajectory has been reached.
+		decision = Sampled
+		probability = s.probability
+	}
+
+	attrs := s.getTrajectoryAttrs(hash)
+	if decision == Sampled {
+		if s.mode != ConsistentMode {
+			traceData.SetSampling(probability, "")
+		}
+		stats.sampled++
+		s.telemetry.ProcessorTailSamplingStratifiedCountTracesSampled.Add(ctx, 1, s.policyAttr, attrs.sampled)
 	} else {
-		// Trajectory is new for the interval
-		s.traceTrajectoryCount[hash] = 1
-		s.mu.Unlock()
-		s.logger.Debug("New Trajectory", zap.String("Trace Hash", hash), zap.String("Check for Trace Identifier", traceID.String()), zap.Int("TraceCount", count))
-		return Sampled, nil
+		s.telemetry.ProcessorTailSamplingStratifiedCountTracesSampled.Add(ctx, 1, s.policyAttr, attrs.notSampled)
 	}
-	s.mu.Unlock()
 
-	// Fallback to probabilistic sampling if the trajectory is seen before 
+	return decision, nil
+}
 
-	if stratifiedHashTraceID(s.hashSalt, traceID[:]) <= s.threshold {
-		return Sampled, nil
+// rotate starts a new window of the trajectories if the current one has ended. The caller holds the lock.
+func (s *StratifiedProbabilisticSampler) rotate() {
+	if s.trajectories.rotate() {
+		s.window++
 	}
-
-	return NotSampled, nil
 }
 
-func (s *StratifiedProbabilisticSampler) ResetWindow() {
-	s.logger.Debug("Resetting Evaluation Window")
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.logger.Debug("Trace trajectory count map", zap.Any("traceTrajectoryCount", s.traceTrajectoryCount))
-	s.traceTrajectoryCount = make(map[string]int)
+// getTrajectoryAttrs returns the measurement options for the given trajectory hash. The hash is truncated and the
+// number of distinct values is bounded across windows, to keep the cardinality of the telemetry under control. The
+// caller holds the lock.
+func (s *StratifiedProbabilisticSampler) getTrajectoryAttrs(hash string) *trajectoryAttrs {
+	key := hash
+	if len(key) > stratifiedTrajectoryAttrLength {
+		key = key[:stratifiedTrajectoryAttrLength]
+	}
+	if attrs, ok := s.trajectoryAttrs.Get(key); ok {
+		attrs.window = s.window
+		return attrs
+	}
+	if s.trajectoryAttrs.Len() >= stratifiedMaxTrajectoryAttrs {
+		// The least recently seen trajectory gives its value up only if it wasn't seen in the current or previous window.
+		if _, oldest, _ := s.trajectoryAttrs.GetOldest(); oldest.window+1 >= s.window {
+			return s.otherTrajectoryAttrs
+		}
+		s.trajectoryAttrs.RemoveOldest()
+	}
+
+	attrs := newTrajectoryAttrs(key)
+	attrs.window = s.window
+	s.trajectoryAttrs.Add(key, attrs)
+	return attrs
 }
 
-func (s *StratifiedProbabilisticSampler) getTraceTrajectoryHash(traceData *TraceData) (string, error) {
-	nodes, edges := s.getTraceSpanDetails(traceData)
+// getTraceTrajectoryHash returns the hash of the trajectory of the trace, along with the canonical list of its
+// edges the hash is computed from.
+func (s *StratifiedProbabilisticSampler) getTraceTrajectoryHash(ctx context.Context, traceData *TraceData) (string, []string, error) {
+	nodes, edges, err := s.getTraceSpanDetails(ctx, traceData)
+	if err != nil {
+		return "", nil, err
+	}
 
 	// Build adjacency list and in-degree map
 	adj := make(map[Node][]Node)
@@ -116,7 +313,6 @@ func (s *StratifiedProbabilisticSampler) getTraceTrajectoryHash(traceData *Trace
 		}
 	}
 
-
 	for _, edge := range edges {
 		if !isEmptyNode(edge.From) {
 			adj[edge.From] = append(adj[edge.From], edge.To)
@@ -131,7 +327,6 @@ func (s *StratifiedProbabilisticSampler) getTraceTrajectoryHash(traceData *Trace
 		allNodes[edge.To] = struct{}{}
 	}
 
-
 	// Canonical topological sort
 	var sorted []Node
 	zeroInDegree := []Node{}
@@ -166,7 +361,6 @@ func (s *StratifiedProbabilisticSampler) getTraceTrajectoryHash(traceData *Trace
 		})
 	}
 
-
 	// Serialize edges deterministically with quoting
 	edgeStrs := make([]string, len(edges))
 	for i, e := range edges {
@@ -188,7 +382,7 @@ func (s *StratifiedProbabilisticSampler) getTraceTrajectoryHash(traceData *Trace
 		zap.String("Trace Hash", hash),
 	)
 
-	return hash, nil
+	return hash, edgeStrs, nil
 }
 
 func isEmptyNode(n Node) bool {
@@ -212,13 +406,11 @@ func compareString(a, b string) int {
 	return 0
 }
 
-
 func joinWithComma(items []string) string {
 	return strings.Join(items, ",")
 }
 
-
-func (s *StratifiedProbabilisticSampler) getTraceSpanDetails(traceData *TraceData) ([]Node, []Edge) {
+func (s *StratifiedProbabilisticSampler) getTraceSpanDetails(ctx context.Context, traceData *TraceData) ([]Node, []Edge, error) {
 	s.logger.Debug("Extracting span details for the trace")
 	traceData.Lock()
 	defer traceData.Unlock()
@@ -245,22 +437,30 @@ func (s *StratifiedProbabilisticSampler) getTraceSpanDetails(traceData *TraceDat
 				span := ss.Spans().At(k)
 				spanID := span.SpanID().String()
 				parentSpanID := span.ParentSpanID().String()
-				operationName := span.Name()
-				// traceID := span.TraceID().String()
 
-				node := Node{Service: serviceName, Operation: operationName}
-				nodeSet[node] = struct{}{}
+				node, err := s.nodeFor(ctx, span, ss, rs, serviceName)
+				if err != nil {
+					return nil, nil, err
+				}
 				spanIDToNode[spanID] = node
 				spanIDToParentID[spanID] = parentSpanID
-
 			}
 		}
 	}
 
-	// Second pass: build edges from parent to child
+	var depths map[string]int
+	if s.maxDepth > 0 {
+		depths = spanDepths(spanIDToParentID)
+	}
+
+	// Second pass: build edges from parent to child, leaving out spans deeper than the maximum depth
 	edges := []Edge{}
 	for childSpanID, parentSpanID := range spanIDToParentID {
+		if depths != nil && depths[childSpanID] > s.maxDepth {
+			continue
+		}
 		childNode := spanIDToNode[childSpanID]
+		nodeSet[childNode] = struct{}{}
 		var parentNode Node
 		if parentSpanID == "" {
 			// Root span
@@ -278,7 +478,7 @@ func (s *StratifiedProbabilisticSampler) getTraceSpanDetails(traceData *TraceDat
 		nodes = append(nodes, node)
 	}
 
-	return nodes, edges
+	return nodes, edges, nil
 }
 
 // Helper function to insert an edge in sorted order
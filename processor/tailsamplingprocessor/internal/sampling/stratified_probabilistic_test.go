@@ -6,12 +6,19 @@ package sampling
 import (
 	"context"
 	"encoding/binary"
+	"fmt"
 	"math/rand/v2"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
 )
 
 func TestStratifiedProbabilisticSampling(t *testing.T) {
@@ -68,7 +75,8 @@ func TestStratifiedProbabilisticSampling(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			traceCount := 100_000
 
-			stratifiedProbabilisticSampler := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), tt.hashSalt, tt.samplingPercentage)
+			stratifiedProbabilisticSampler, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", tt.hashSalt, HashSeedMode, tt.samplingPercentage, 0, 0, nil, ThroughputTarget{}, TrajectoryShape{}, MonotonicClock{})
+			require.NoError(t, err)
 
 			sampled := 0
 			for _, traceID := range genStratifiedRandomTraceIDs(traceCount) {
@@ -90,6 +98,244 @@ func TestStratifiedProbabilisticSampling(t *testing.T) {
 	}
 }
 
+func TestStratifiedProbabilisticSamplingKeepsMinimumPerTrajectory(t *testing.T) {
+	timeProvider := &FakeTimeProvider{second: 100}
+	sampler, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 10*time.Second, 0, minTraces(3), ThroughputTarget{}, TrajectoryShape{}, timeProvider)
+	require.NoError(t, err)
+
+	evaluate := func(trace *TraceData, count int) (sampled int) {
+		for _, traceID := range genStratifiedRandomTraceIDs(count) {
+			decision, err := sampler.Evaluate(context.Background(), traceID, trace)
+			require.NoError(t, err)
+			if decision == Sampled {
+				sampled++
+			}
+		}
+		return sampled
+	}
+
+	common := newTraceStringAttrs(map[string]any{"service.name": "frontend"}, "example", "value")
+	rare := newTraceStringAttrs(map[string]any{"service.name": "backend"}, "example", "value")
+
+	assert.Equal(t, 3, evaluate(common, 100))
+	assert.Equal(t, 1, evaluate(rare, 1))
+
+	// Trajectory statistics survive within the window, regardless of how often decisions are evaluated.
+	timeProvider.second = 109
+	assert.Equal(t, 0, evaluate(common, 100))
+	assert.Equal(t, 2, evaluate(rare, 5))
+
+	// A new window allows sampling the minimum again.
+	timeProvider.second = 110
+	assert.Equal(t, 3, evaluate(common, 100))
+}
+
+func TestStratifiedProbabilisticSamplingMinimumPerTrajectory(t *testing.T) {
+	trace := newTraceStringAttrs(map[string]any{"service.name": "frontend"}, "example", "value")
+	for _, tt := range []struct {
+		name      string
+		minTraces *int64
+		sampled   bool
+	}{
+		{name: "unset", sampled: true},
+		{name: "zero", minTraces: minTraces(0)},
+		{name: "one", minTraces: minTraces(1), sampled: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, tt.minTraces, ThroughputTarget{}, TrajectoryShape{}, &FakeTimeProvider{})
+			require.NoError(t, err)
+			decision, err := sampler.Evaluate(context.Background(), pcommon.TraceID{1}, trace)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sampled, decision == Sampled)
+		})
+	}
+
+	_, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, minTraces(-1), ThroughputTarget{}, TrajectoryShape{}, &FakeTimeProvider{})
+	assert.EqualError(t, err, "minimum number of traces per trajectory cannot be negative")
+}
+
+func TestStratifiedProbabilisticSamplingProbability(t *testing.T) {
+	sampler, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 25, 10*time.Second, 0, minTraces(1), ThroughputTarget{}, TrajectoryShape{}, &FakeTimeProvider{})
+	require.NoError(t, err)
+
+	trace := newTraceStringAttrs(map[string]any{"service.name": "frontend"}, "example", "value")
+	probabilities := map[float64]int{}
+	for _, traceID := range genStratifiedRandomTraceIDs(100) {
+		trace.SamplingProbability = 0
+		decision, err := sampler.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+		if decision == Sampled {
+			probabilities[trace.SamplingProbability]++
+		} else {
+			assert.Zero(t, trace.SamplingProbability)
+		}
+	}
+
+	// The minimum per trajectory is always sampled, the other traces with the sampling percentage.
+	assert.Equal(t, 1, probabilities[1])
+	assert.Positive(t, probabilities[0.25])
+	assert.Len(t, probabilities, 2)
+}
+
+func TestStratifiedProbabilisticSamplingForgetsUnseenTrajectories(t *testing.T) {
+	timeProvider := &FakeTimeProvider{second: 100}
+	s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 10*time.Second, 0, nil, ThroughputTarget{}, TrajectoryShape{}, timeProvider)
+	require.NoError(t, err)
+	sampler := s.(*StratifiedProbabilisticSampler)
+
+	common := newTraceStringAttrs(map[string]any{"service.name": "frontend"}, "example", "value")
+	rare := newTraceStringAttrs(map[string]any{"service.name": "backend"}, "example", "value")
+	ids := genStratifiedRandomTraceIDs(4)
+
+	for _, trace := range []*TraceData{common, rare} {
+		decision, err := sampler.Evaluate(context.Background(), ids[0], trace)
+		require.NoError(t, err)
+		assert.Equal(t, Sampled, decision)
+	}
+	assert.Equal(t, 2, sampler.trajectories.len())
+
+	// Only the common trajectory is seen in the second window.
+	timeProvider.second = 110
+	decision, err := sampler.Evaluate(context.Background(), ids[1], common)
+	require.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+	assert.Equal(t, 2, sampler.trajectories.len())
+
+	// The rare trajectory wasn't seen for a whole window and is forgotten.
+	timeProvider.second = 120
+	decision, err = sampler.Evaluate(context.Background(), ids[2], common)
+	require.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+	assert.Equal(t, 1, sampler.trajectories.len())
+}
+
+func TestStratifiedProbabilisticSamplingTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	settings := componenttest.NewNopTelemetrySettings()
+	settings.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	s, err := NewStratifiedProbabilisticSampler(settings, "stratified", "", "", 0, 0, 0, nil, ThroughputTarget{}, TrajectoryShape{}, &FakeTimeProvider{})
+	require.NoError(t, err)
+	sampler := s.(*StratifiedProbabilisticSampler)
+
+	trace := newTraceStringAttrs(map[string]any{"service.name": "frontend"}, "example", "value")
+	for _, traceID := range genStratifiedRandomTraceIDs(3) {
+		_, err = sampler.Evaluate(context.Background(), traceID, trace)
+		require.NoError(t, err)
+	}
+	hash, _, err := sampler.getTraceTrajectoryHash(context.Background(), trace)
+	require.NoError(t, err)
+	trajectory := attribute.String("trajectory", hash[:8])
+	policy := attribute.String("policy", "stratified")
+
+	var md metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &md))
+	require.Len(t, md.ScopeMetrics, 1)
+	got := make(map[string]metricdata.Metrics)
+	for _, m := range md.ScopeMetrics[0].Metrics {
+		got[m.Name] = m
+	}
+
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_processor_tail_sampling_stratified_trajectories",
+		Description: "Number of distinct trajectories seen by the stratified policy in the current window",
+		Unit:        "{trajectories}",
+		Data: metricdata.Gauge[int64]{
+			DataPoints: []metricdata.DataPoint[int64]{{Attributes: attribute.NewSet(policy), Value: 1}},
+		},
+	}, got["otelcol_processor_tail_sampling_stratified_trajectories"], metricdatatest.IgnoreTimestamp())
+
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_processor_tail_sampling_stratified_new_trajectories",
+		Description: "Count of trajectories seen for the first time by the stratified policy",
+		Unit:        "{trajectories}",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  []metricdata.DataPoint[int64]{{Attributes: attribute.NewSet(policy), Value: 1}},
+		},
+	}, got["otelcol_processor_tail_sampling_stratified_new_trajectories"], metricdatatest.IgnoreTimestamp())
+
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_processor_tail_sampling_stratified_count_traces_sampled",
+		Description: "Count of traces that were sampled or not per trajectory by the stratified policy",
+		Unit:        "{traces}",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints: []metricdata.DataPoint[int64]{
+				{
+					Attributes: attribute.NewSet(policy, trajectory, attribute.String("sampled", "true")),
+					Value:      1,
+				},
+				{
+					Attributes: attribute.NewSet(policy, trajectory, attribute.String("sampled", "false")),
+					Value:      2,
+				},
+			},
+		},
+	}, got["otelcol_processor_tail_sampling_stratified_count_traces_sampled"], metricdatatest.IgnoreTimestamp())
+}
+
+func TestStratifiedProbabilisticSamplingBoundsTrajectoryAttributes(t *testing.T) {
+	s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, nil, ThroughputTarget{}, TrajectoryShape{}, MonotonicClock{})
+	require.NoError(t, err)
+	sampler := s.(*StratifiedProbabilisticSampler)
+
+	for i := 0; i < stratifiedMaxTrajectoryAttrs; i++ {
+		sampler.getTrajectoryAttrs(fmt.Sprintf("%08x%08x", i, 0))
+	}
+	assert.Equal(t, stratifiedMaxTrajectoryAttrs, sampler.trajectoryAttrs.Len())
+
+	// Known trajectories keep their attribute, new ones are reported as "other".
+	known, _ := sampler.trajectoryAttrs.Peek("00000000")
+	assert.Same(t, known, sampler.getTrajectoryAttrs("0000000000000000"))
+	assert.Same(t, sampler.otherTrajectoryAttrs, sampler.getTrajectoryAttrs("ffffffffffffffff"))
+	assert.Equal(t, stratifiedMaxTrajectoryAttrs, sampler.trajectoryAttrs.Len())
+}
+
+func TestStratifiedProbabilisticSamplingBoundsTrajectoryAttributesAcrossWindows(t *testing.T) {
+	clock := &FakeTimeProvider{}
+	s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 10*time.Second, 0, nil, ThroughputTarget{}, TrajectoryShape{}, clock)
+	require.NoError(t, err)
+	sampler := s.(*StratifiedProbabilisticSampler)
+	nextWindow := func() {
+		clock.second += 10
+		sampler.rotate()
+	}
+
+	for i := 0; i < stratifiedMaxTrajectoryAttrs; i++ {
+		sampler.getTrajectoryAttrs(fmt.Sprintf("%08x%08x", i, 0))
+	}
+	assert.Same(t, sampler.otherTrajectoryAttrs, sampler.getTrajectoryAttrs("ffffffffffffffff"))
+
+	// The trajectories of the previous window keep their value.
+	nextWindow()
+	kept := sampler.getTrajectoryAttrs("0000000000000000")
+	assert.Same(t, sampler.otherTrajectoryAttrs, sampler.getTrajectoryAttrs("ffffffffffffffff"))
+
+	// A trajectory not seen for a whole window gives its value up to a new one, the others keep theirs.
+	nextWindow()
+	replacing := sampler.getTrajectoryAttrs("ffffffffffffffff")
+	assert.NotSame(t, sampler.otherTrajectoryAttrs, replacing)
+	assert.Same(t, kept, sampler.getTrajectoryAttrs("0000000000000000"))
+	assert.False(t, sampler.trajectoryAttrs.Contains("00000001"))
+
+	// However many windows pass and trajectories are seen, the number of values stays bounded.
+	for w := 0; w < 5; w++ {
+		nextWindow()
+		for i := 0; i < 2*stratifiedMaxTrajectoryAttrs; i++ {
+			sampler.getTrajectoryAttrs(fmt.Sprintf("%08x%08x", w*2*stratifiedMaxTrajectoryAttrs+i, 1))
+		}
+		assert.Equal(t, stratifiedMaxTrajectoryAttrs, sampler.trajectoryAttrs.Len())
+	}
+}
+
+// minTraces returns the minimum number of traces per trajectory to sample.
+func minTraces(n int64) *int64 {
+	return &n
+}
+
 func genStratifiedRandomTraceIDs(num int) (ids []pcommon.TraceID) {
 	// NOTE: using a fixed seed is intentional here,
 	// as otherwise the delta in the tests above will
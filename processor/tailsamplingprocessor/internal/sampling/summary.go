@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TraceSummary is a compact summary of the spans of a trace, kept once its spans are released. It isn't safe for
+// concurrent use.
+type TraceSummary struct {
+	minStart  pcommon.Timestamp
+	maxEnd    pcommon.Timestamp
+	spanCount int64
+	// statusCodes is a bit set of the status codes of the spans.
+	statusCodes uint8
+}
+
+// NewTraceSummary returns the summary of the given spans.
+func NewTraceSummary(td ptrace.Traces) *TraceSummary {
+	s := &TraceSummary{}
+	s.AddTraces(td)
+	return s
+}
+
+// AddTraces adds the given spans to the summary.
+func (s *TraceSummary) AddTraces(td ptrace.Traces) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				s.Add(spans.At(k))
+			}
+		}
+	}
+}
+
+// Add adds a span to the summary.
+func (s *TraceSummary) Add(span ptrace.Span) {
+	if s.minStart == 0 || span.StartTimestamp() < s.minStart {
+		s.minStart = span.StartTimestamp()
+	}
+	if s.maxEnd == 0 || span.EndTimestamp() > s.maxEnd {
+		s.maxEnd = span.EndTimestamp()
+	}
+	s.spanCount++
+	s.statusCodes |= 1 << span.Status().Code()
+}
+
+// Duration returns the time between the earliest start and the latest end of the spans.
+func (s *TraceSummary) Duration() time.Duration {
+	return s.maxEnd.AsTime().Sub(s.minStart.AsTime())
+}
+
+// SpanCount returns the number of spans.
+func (s *TraceSummary) SpanCount() int64 {
+	return s.spanCount
+}
+
+// HasStatusCode returns whether a span has the given status code.
+func (s *TraceSummary) HasStatusCode(code ptrace.StatusCode) bool {
+	return s.statusCodes&(1<<code) != 0
+}
+
+// SummaryEvaluator is implemented by the policy evaluators able to decide on the summary of a trace, e.g.: to
+// re-evaluate a trace that wasn't sampled once its late spans are received.
+type SummaryEvaluator interface {
+	// EvaluateSummary looks at the summary of a trace and returns a corresponding SamplingDecision.
+	EvaluateSummary(summary *TraceSummary) Decision
+}
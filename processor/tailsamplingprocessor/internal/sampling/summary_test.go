@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestTraceSummary(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	span := spans.AppendEmpty()
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Second)))
+	span.Status().SetCode(ptrace.StatusCodeOk)
+
+	summary := NewTraceSummary(td)
+	assert.Equal(t, time.Second, summary.Duration())
+	assert.Equal(t, int64(1), summary.SpanCount())
+	assert.True(t, summary.HasStatusCode(ptrace.StatusCodeOk))
+	assert.False(t, summary.HasStatusCode(ptrace.StatusCodeError))
+
+	late := ptrace.NewSpan()
+	late.SetStartTimestamp(pcommon.NewTimestampFromTime(start.Add(500 * time.Millisecond)))
+	late.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(3 * time.Second)))
+	late.Status().SetCode(ptrace.StatusCodeError)
+	summary.Add(late)
+	assert.Equal(t, 3*time.Second, summary.Duration())
+	assert.Equal(t, int64(2), summary.SpanCount())
+	assert.True(t, summary.HasStatusCode(ptrace.StatusCodeError))
+	assert.False(t, summary.HasStatusCode(ptrace.StatusCodeUnset))
+}
+
+func TestEvaluateSummary(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	span := ptrace.NewSpan()
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(2 * time.Second)))
+	span.Status().SetCode(ptrace.StatusCodeError)
+	summary := &TraceSummary{}
+	summary.Add(span)
+
+	statusCode, err := NewStatusCodeFilter(componenttest.NewNopTelemetrySettings(), []string{"ERROR"})
+	require.NoError(t, err)
+	unset, err := NewStatusCodeFilter(componenttest.NewNopTelemetrySettings(), []string{"UNSET"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		evaluator PolicyEvaluator
+		decision  Decision
+	}{
+		{name: "status code matching", evaluator: statusCode, decision: Sampled},
+		{name: "status code not matching", evaluator: unset, decision: NotSampled},
+		{name: "latency above threshold", evaluator: NewLatency(componenttest.NewNopTelemetrySettings(), 1000, 0), decision: Sampled},
+		{name: "latency below threshold", evaluator: NewLatency(componenttest.NewNopTelemetrySettings(), 5000, 0), decision: NotSampled},
+		{name: "latency within range", evaluator: NewLatency(componenttest.NewNopTelemetrySettings(), 1000, 3000), decision: Sampled},
+		{name: "latency above range", evaluator: NewLatency(componenttest.NewNopTelemetrySettings(), 500, 1000), decision: NotSampled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluator, ok := tt.evaluator.(SummaryEvaluator)
+			require.True(t, ok)
+			assert.Equal(t, tt.decision, evaluator.EvaluateSummary(summary))
+		})
+	}
+}
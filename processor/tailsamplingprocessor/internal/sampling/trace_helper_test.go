@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+var (
+	testTraceID = pcommon.TraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	// testTraceStart is the time the spans of the test traces start from.
+	testTraceStart = time.Unix(1700000000, 0)
+)
+
+// testSpan describes a span of a test trace. The span and parent IDs are left empty when zero, and the start and end
+// of the span are offsets from testTraceStart.
+type testSpan struct {
+	service       string
+	name          string
+	kind          ptrace.SpanKind
+	id            byte
+	parent        byte
+	start         time.Duration
+	end           time.Duration
+	err           bool
+	attrs         map[string]any
+	resourceAttrs map[string]any
+}
+
+// newTestTraces returns traces holding the given spans, each in a resource of its own.
+func newTestTraces(spans ...testSpan) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	for _, s := range spans {
+		rs := traces.ResourceSpans().AppendEmpty()
+		//nolint:errcheck
+		rs.Resource().Attributes().FromRaw(s.resourceAttrs)
+		if s.service != "" {
+			rs.Resource().Attributes().PutStr("service.name", s.service)
+		}
+		span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.SetTraceID(testTraceID)
+		if s.id != 0 {
+			span.SetSpanID(pcommon.SpanID{s.id})
+		}
+		if s.parent != 0 {
+			span.SetParentSpanID(pcommon.SpanID{s.parent})
+		}
+		span.SetName(s.name)
+		span.SetKind(s.kind)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(testTraceStart.Add(s.start)))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(testTraceStart.Add(s.end)))
+		if s.err {
+			span.Status().SetCode(ptrace.StatusCodeError)
+		}
+		//nolint:errcheck
+		span.Attributes().FromRaw(s.attrs)
+	}
+	return traces
+}
+
+// newTestTrace returns the data of a trace holding the given spans, see newTestTraces.
+func newTestTrace(spans ...testSpan) *TraceData {
+	return &TraceData{ReceivedBatches: newTestTraces(spans...)}
+}
@@ -0,0 +1,171 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// otelTraceStateKey is the key of the OpenTelemetry entry of the W3C tracestate.
+	otelTraceStateKey = "ot"
+	// thresholdKey is the key of the sampling threshold in the OpenTelemetry tracestate entry.
+	thresholdKey = "th"
+	// randomnessKey is the key of the explicit randomness value in the OpenTelemetry tracestate entry.
+	randomnessKey = "rv"
+	// thresholdDigits is the number of hex digits of a 56-bit threshold or randomness value.
+	thresholdDigits = 14
+	// maxThreshold is the number of distinct 56-bit randomness values, i.e.: the rejection threshold of a zero
+	// probability.
+	maxThreshold = uint64(1) << 56
+)
+
+// otelTraceState is a W3C tracestate split into its OpenTelemetry entry, as ordered key/value pairs, and the
+// other list members, kept as is.
+type otelTraceState struct {
+	values  [][2]string
+	members []string
+}
+
+func parseOTelTraceState(raw string) otelTraceState {
+	var ts otelTraceState
+	for _, member := range strings.Split(raw, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		value, ok := strings.CutPrefix(member, otelTraceStateKey+"=")
+		if !ok {
+			ts.members = append(ts.members, member)
+			continue
+		}
+		for _, kv := range strings.Split(value, ";") {
+			if k, v, ok := strings.Cut(kv, ":"); ok {
+				ts.values = append(ts.values, [2]string{k, v})
+			}
+		}
+	}
+	return ts
+}
+
+func (ts *otelTraceState) get(key string) (string, bool) {
+	for _, kv := range ts.values {
+		if kv[0] == key {
+			return kv[1], true
+		}
+	}
+	return "", false
+}
+
+func (ts *otelTraceState) set(key, value string) {
+	for i, kv := range ts.values {
+		if kv[0] == key {
+			ts.values[i][1] = value
+			return
+		}
+	}
+	ts.values = append(ts.values, [2]string{key, value})
+}
+
+// String encodes the tracestate, the OpenTelemetry entry being moved first as it was updated.
+func (ts *otelTraceState) String() string {
+	members := make([]string, 0, len(ts.members)+1)
+	if len(ts.values) > 0 {
+		kvs := make([]string, len(ts.values))
+		for i, kv := range ts.values {
+			kvs[i] = kv[0] + ":" + kv[1]
+		}
+		members = append(members, otelTraceStateKey+"="+strings.Join(kvs, ";"))
+	}
+	members = append(members, ts.members...)
+	return strings.Join(members, ",")
+}
+
+// probabilityToThreshold returns the 56-bit rejection threshold of a sampling probability. Probabilities too small to
+// be represented are rounded up to the smallest one.
+func probabilityToThreshold(probability float64) uint64 {
+	if probability >= 1 {
+		return 0
+	}
+	accepted := uint64(math.Round(max(0, probability) * float64(maxThreshold)))
+	return maxThreshold - max(1, accepted)
+}
+
+// thresholdToProbability returns the sampling probability of a 56-bit rejection threshold.
+func thresholdToProbability(threshold uint64) float64 {
+	return float64(maxThreshold-min(threshold, maxThreshold)) / float64(maxThreshold)
+}
+
+// encodeThreshold encodes a threshold as the value of the th key, i.e.: as 14 hex digits without trailing zeros.
+func encodeThreshold(threshold uint64) string {
+	if threshold == 0 {
+		return "0"
+	}
+	return strings.TrimRight(fmt.Sprintf("%0*x", thresholdDigits, threshold), "0")
+}
+
+// decodeThreshold decodes the value of the th key.
+func decodeThreshold(value string) (uint64, error) {
+	if value == "" || len(value) > thresholdDigits {
+		return 0, fmt.Errorf("invalid sampling threshold %q", value)
+	}
+	threshold, err := strconv.ParseUint(value+strings.Repeat("0", thresholdDigits-len(value)), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sampling threshold %q: %w", value, err)
+	}
+	return threshold, nil
+}
+
+// decodeRandomness decodes the value of the rv key, i.e.: exactly 14 hex digits.
+func decodeRandomness(value string) (uint64, error) {
+	if len(value) != thresholdDigits {
+		return 0, fmt.Errorf("invalid randomness value %q", value)
+	}
+	randomness, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid randomness value %q: %w", value, err)
+	}
+	return randomness, nil
+}
+
+// CompareThresholds compares two encoded thresholds, returning -1 if a is lower than b, i.e.: samples with a higher
+// probability, 0 if they are equal, and 1 otherwise. Invalid thresholds compare as zero.
+func CompareThresholds(a, b string) int {
+	ta, _ := decodeThreshold(a)
+	tb, _ := decodeThreshold(b)
+	return cmp.Compare(ta, tb)
+}
+
+// SetTraceStateThreshold sets the th key of the OpenTelemetry entry of the given W3C tracestate to the given encoded
+// threshold, keeping the other keys and list members.
+func SetTraceStateThreshold(raw string, threshold string) string {
+	ts := parseOTelTraceState(raw)
+	ts.set(thresholdKey, threshold)
+	return ts.String()
+}
+
+// UpdateTraceStateProbability records in the given W3C tracestate that the trace was sampled with the given
+// probability, by setting the th key of its OpenTelemetry entry. If the tracestate already holds a threshold, e.g.: set
+// by a head sampler, the recorded probability is the product of both probabilities.
+func UpdateTraceStateProbability(raw string, probability float64) (string, error) {
+	if probability <= 0 || probability > 1 {
+		return raw, errors.New("sampling probability must be in (0, 1]")
+	}
+
+	ts := parseOTelTraceState(raw)
+	if value, ok := ts.get(thresholdKey); ok {
+		threshold, err := decodeThreshold(value)
+		if err != nil {
+			return raw, err
+		}
+		probability *= thresholdToProbability(threshold)
+	}
+	ts.set(thresholdKey, encodeThreshold(probabilityToThreshold(probability)))
+	return ts.String(), nil
+}
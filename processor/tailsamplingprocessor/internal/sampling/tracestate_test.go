@@ -0,0 +1,107 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThresholdEncoding(t *testing.T) {
+	tests := []struct {
+		probability float64
+		encoded     string
+	}{
+		{probability: 1, encoded: "0"},
+		{probability: 0.5, encoded: "8"},
+		{probability: 0.25, encoded: "c"},
+		{probability: 0.1, encoded: "e6666666666666"},
+		{probability: 0.01, encoded: "fd70a3d70a3d71"},
+		{probability: 1e-20, encoded: "ffffffffffffff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			threshold := probabilityToThreshold(tt.probability)
+			assert.Equal(t, tt.encoded, encodeThreshold(threshold))
+
+			decoded, err := decodeThreshold(tt.encoded)
+			require.NoError(t, err)
+			assert.Equal(t, threshold, decoded)
+		})
+	}
+
+	for _, invalid := range []string{"", "x", "100000000000000"} {
+		_, err := decodeThreshold(invalid)
+		assert.Error(t, err, "threshold %q", invalid)
+	}
+}
+
+func TestUpdateTraceStateProbability(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceState  string
+		probability float64
+		expected    string
+	}{
+		{
+			name:        "empty",
+			traceState:  "",
+			probability: 0.25,
+			expected:    "ot=th:c",
+		},
+		{
+			name:        "other members",
+			traceState:  "vendor=value,other=1",
+			probability: 0.5,
+			expected:    "ot=th:8,vendor=value,other=1",
+		},
+		{
+			name:        "other keys",
+			traceState:  "vendor=value,ot=rv:0123456789abcd;p:8",
+			probability: 1,
+			expected:    "ot=rv:0123456789abcd;p:8;th:0,vendor=value",
+		},
+		{
+			name:        "head sampled",
+			traceState:  "ot=th:8",
+			probability: 0.5,
+			expected:    "ot=th:c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpdateTraceStateProbability(tt.traceState, tt.probability)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestUpdateTraceStateProbabilityInvalid(t *testing.T) {
+	_, err := UpdateTraceStateProbability("ot=th:zz", 0.5)
+	assert.Error(t, err)
+
+	for _, probability := range []float64{0, -1, 2} {
+		got, err := UpdateTraceStateProbability("vendor=value", probability)
+		assert.Error(t, err)
+		assert.Equal(t, "vendor=value", got)
+	}
+}
+
+func TestSetTraceStateThreshold(t *testing.T) {
+	assert.Equal(t, "ot=th:c", SetTraceStateThreshold("", "c"))
+	assert.Equal(t, "ot=rv:0123456789abcd;th:8,vendor=value", SetTraceStateThreshold("vendor=value,ot=rv:0123456789abcd;th:c", "8"))
+}
+
+func TestCompareThresholds(t *testing.T) {
+	assert.Equal(t, -1, CompareThresholds("8", "c"))
+	assert.Equal(t, 1, CompareThresholds("c", "8"))
+	// Trailing zeros are omitted.
+	assert.Equal(t, 0, CompareThresholds("8", "80"))
+	assert.Equal(t, -1, CompareThresholds("0", "0001"))
+}
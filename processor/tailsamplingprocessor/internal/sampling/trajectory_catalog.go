@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"sort"
+)
+
+// TrajectoryCatalog is implemented by policy evaluators that keep track of the trajectories of the traces they
+// evaluate, so they can be inspected.
+type TrajectoryCatalog interface {
+	// PolicyName returns the name of the policy evaluated.
+	PolicyName() string
+	// Trajectories returns the trajectories currently tracked, the most frequent in the current window first.
+	Trajectories() []TrajectoryInfo
+}
+
+// TrajectoryInfo describes a trajectory and how its traces were sampled.
+type TrajectoryInfo struct {
+	// Hash identifies the trajectory.
+	Hash string `json:"hash"`
+	// Edges is the canonical list of parent -> child edges the hash is computed from.
+	Edges []string `json:"edges"`
+	// Current holds the statistics of the current window.
+	Current TrajectoryWindowStats `json:"current"`
+	// Previous holds the statistics of the previous window.
+	Previous TrajectoryWindowStats `json:"previous"`
+}
+
+// TrajectoryWindowStats holds the number of traces of a trajectory seen and sampled during a window.
+type TrajectoryWindowStats struct {
+	Seen         int64   `json:"seen"`
+	Sampled      int64   `json:"sampled"`
+	SampledRatio float64 `json:"sampled_ratio"`
+}
+
+func newTrajectoryWindowStats(seen, sampled int64) TrajectoryWindowStats {
+	stats := TrajectoryWindowStats{Seen: seen, Sampled: sampled}
+	if seen > 0 {
+		stats.SampledRatio = float64(sampled) / float64(seen)
+	}
+	return stats
+}
+
+var _ TrajectoryCatalog = (*StratifiedProbabilisticSampler)(nil)
+
+// TrajectoryCatalogs returns the trajectory catalogs of a policy evaluator, including the ones of its sub-policies,
+// e.g.: the stratified sub-policies of an and or a composite policy.
+func TrajectoryCatalogs(evaluator PolicyEvaluator) []TrajectoryCatalog {
+	var subpolicies []PolicyEvaluator
+	switch e := evaluator.(type) {
+	case TrajectoryCatalog:
+		return []TrajectoryCatalog{e}
+	case *And:
+		subpolicies = e.subpolicies
+	case *Drop:
+		subpolicies = e.subpolicies
+	case *Composite:
+		for _, sub := range e.subpolicies {
+			subpolicies = append(subpolicies, sub.evaluator)
+		}
+	}
+
+	var catalogs []TrajectoryCatalog
+	for _, sub := range subpolicies {
+		catalogs = append(catalogs, TrajectoryCatalogs(sub)...)
+	}
+	return catalogs
+}
+
+// PolicyName returns the name of the policy of the sampler.
+func (s *StratifiedProbabilisticSampler) PolicyName() string {
+	return s.policyName
+}
+
+// Trajectories returns the trajectories tracked by the sampler.
+func (s *StratifiedProbabilisticSampler) Trajectories() []TrajectoryInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotate()
+	infos := make([]TrajectoryInfo, 0, s.trajectories.len())
+	s.trajectories.forEach(func(hash string, stats *trajectoryStats) {
+		infos = append(infos, TrajectoryInfo{
+			Hash:     hash,
+			Edges:    append([]string(nil), stats.edges...),
+			Current:  newTrajectoryWindowStats(stats.seen, stats.sampled),
+			Previous: newTrajectoryWindowStats(stats.prevSeen, stats.prevSampled),
+		})
+	})
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Current.Seen != infos[j].Current.Seen {
+			return infos[i].Current.Seen > infos[j].Current.Seen
+		}
+		if infos[i].Previous.Seen != infos[j].Previous.Seen {
+			return infos[i].Previous.Seen > infos[j].Previous.Seen
+		}
+		return infos[i].Hash < infos[j].Hash
+	})
+	return infos
+}
@@ -0,0 +1,54 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestStratifiedProbabilisticSamplerTrajectories(t *testing.T) {
+	timeProvider := &FakeTimeProvider{second: 0}
+	s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 10*time.Second, 0, minTraces(2), ThroughputTarget{}, TrajectoryShape{}, timeProvider)
+	require.NoError(t, err)
+	sampler := s.(*StratifiedProbabilisticSampler)
+
+	frequent := newTestTrace([]testSpan{
+		{service: "frontend", name: "GET", id: 1},
+		{service: "backend", name: "query", id: 2, parent: 1},
+	}...)
+	rare := newTestTrace([]testSpan{
+		{service: "frontend", name: "POST", id: 1},
+	}...)
+
+	traceIDs := genStratifiedRandomTraceIDs(5)
+	for _, traceID := range traceIDs[:4] {
+		_, err = sampler.Evaluate(context.Background(), traceID, frequent)
+		require.NoError(t, err)
+	}
+	_, err = sampler.Evaluate(context.Background(), traceIDs[4], rare)
+	require.NoError(t, err)
+
+	trajectories := sampler.Trajectories()
+	require.Len(t, trajectories, 2)
+
+	assert.Equal(t, []string{`":"->"frontend:GET"`, `"frontend:GET"->"backend:query"`}, trajectories[0].Edges)
+	assert.Equal(t, TrajectoryWindowStats{Seen: 4, Sampled: 2, SampledRatio: 0.5}, trajectories[0].Current)
+	assert.Equal(t, TrajectoryWindowStats{}, trajectories[0].Previous)
+	assert.Equal(t, []string{`":"->"frontend:POST"`}, trajectories[1].Edges)
+	assert.Equal(t, TrajectoryWindowStats{Seen: 1, Sampled: 1, SampledRatio: 1}, trajectories[1].Current)
+
+	// The statistics move to the previous window once the current one ends.
+	timeProvider.second = 10
+	trajectories = sampler.Trajectories()
+	require.Len(t, trajectories, 2)
+	assert.Equal(t, TrajectoryWindowStats{}, trajectories[0].Current)
+	assert.Equal(t, TrajectoryWindowStats{Seen: 4, Sampled: 2, SampledRatio: 0.5}, trajectories[0].Previous)
+	assert.Equal(t, TrajectoryWindowStats{Seen: 1, Sampled: 1, SampledRatio: 1}, trajectories[1].Previous)
+}
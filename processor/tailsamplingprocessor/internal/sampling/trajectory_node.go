@@ -0,0 +1,153 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/filter/filterottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+)
+
+// NodeIdentity defines which properties of a span identify it as a node of a trajectory.
+type NodeIdentity string
+
+const (
+	// NodeIdentityServiceOperation identifies a node by its service name and span name. This is the default.
+	NodeIdentityServiceOperation NodeIdentity = "service_operation"
+	// NodeIdentityService identifies a node by its service name only.
+	NodeIdentityService NodeIdentity = "service"
+	// NodeIdentityServiceKind identifies a node by its service name and span kind.
+	NodeIdentityServiceKind NodeIdentity = "service_kind"
+	// NodeIdentityAttributes identifies a node by its service name and the values of a list of attributes.
+	NodeIdentityAttributes NodeIdentity = "attributes"
+	// NodeIdentityOTTL identifies a node by its service name and the result of an OTTL value expression.
+	NodeIdentityOTTL NodeIdentity = "ottl"
+)
+
+// TrajectoryShape defines how the trajectory of a trace is built from its spans.
+type TrajectoryShape struct {
+	// NodeIdentity defines which properties of a span identify it as a node of the trajectory.
+	NodeIdentity NodeIdentity
+	// AttributeKeys are the span or resource attributes identifying a node, used with NodeIdentityAttributes.
+	AttributeKeys []string
+	// Expression is the OTTL value expression, in the span context, identifying a node, used with NodeIdentityOTTL.
+	Expression string
+	// MaxDepth is the maximum depth of spans taken into account, the root span being at depth 1.
+	// Zero means no limit.
+	MaxDepth int
+}
+
+// nodeFunc returns the trajectory node for the given span of a resource with the given service name.
+type nodeFunc func(ctx context.Context, span ptrace.Span, ss ptrace.ScopeSpans, rs ptrace.ResourceSpans, service string) (Node, error)
+
+func newNodeFunc(settings component.TelemetrySettings, shape TrajectoryShape) (nodeFunc, error) {
+	switch shape.NodeIdentity {
+	case "", NodeIdentityServiceOperation:
+		return func(_ context.Context, span ptrace.Span, _ ptrace.ScopeSpans, _ ptrace.ResourceSpans, service string) (Node, error) {
+			return Node{Service: service, Operation: span.Name()}, nil
+		}, nil
+	case NodeIdentityService:
+		return func(_ context.Context, _ ptrace.Span, _ ptrace.ScopeSpans, _ ptrace.ResourceSpans, service string) (Node, error) {
+			return Node{Service: service}, nil
+		}, nil
+	case NodeIdentityServiceKind:
+		return func(_ context.Context, span ptrace.Span, _ ptrace.ScopeSpans, _ ptrace.ResourceSpans, service string) (Node, error) {
+			return Node{Service: service, Operation: span.Kind().String()}, nil
+		}, nil
+	case NodeIdentityAttributes:
+		if len(shape.AttributeKeys) == 0 {
+			return nil, errors.New("expected at least one attribute key to identify trajectory nodes")
+		}
+		keys := shape.AttributeKeys
+		return func(_ context.Context, span ptrace.Span, _ ptrace.ScopeSpans, rs ptrace.ResourceSpans, service string) (Node, error) {
+			values := make([]string, len(keys))
+			for i, key := range keys {
+				v, ok := span.Attributes().Get(key)
+				if !ok {
+					v, ok = rs.Resource().Attributes().Get(key)
+				}
+				if ok {
+					values[i] = key + "=" + v.AsString()
+				} else {
+					values[i] = key + "="
+				}
+			}
+			return Node{Service: service, Operation: strings.Join(values, ";")}, nil
+		}, nil
+	case NodeIdentityOTTL:
+		if shape.Expression == "" {
+			return nil, errors.New("expected an OTTL expression to identify trajectory nodes")
+		}
+		parser, err := ottlspan.NewParser(filterottl.StandardSpanFuncs(), settings)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := parser.ParseValueExpression(shape.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, span ptrace.Span, ss ptrace.ScopeSpans, rs ptrace.ResourceSpans, service string) (Node, error) {
+			v, err := expr.Eval(ctx, ottlspan.NewTransformContext(span, ss.Scope(), rs.Resource(), ss, rs))
+			if err != nil {
+				return Node{}, err
+			}
+			return Node{Service: service, Operation: valueToString(v)}, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown trajectory node identity %q", shape.NodeIdentity)
+	}
+}
+
+func valueToString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case pcommon.Value:
+		return val.AsString()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// spanDepths returns the depth of each span, given the parent of each span. Root spans and spans whose parent is
+// unknown are at depth 1.
+func spanDepths(spanIDToParentID map[string]string) map[string]int {
+	depths := make(map[string]int, len(spanIDToParentID))
+	var chain []string
+	for spanID := range spanIDToParentID {
+		// Walk up until a span with a known depth or a root is found, guarding against cycles.
+		chain = chain[:0]
+		depth := 0
+		for current := spanID; len(chain) <= len(spanIDToParentID); {
+			if d, ok := depths[current]; ok {
+				depth = d
+				break
+			}
+			chain = append(chain, current)
+			parentID, ok := spanIDToParentID[current]
+			if !ok || parentID == "" {
+				break
+			}
+			if _, ok = spanIDToParentID[parentID]; !ok {
+				break
+			}
+			current = parentID
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			depth++
+			depths[chain[i]] = depth
+		}
+	}
+	return depths
+}
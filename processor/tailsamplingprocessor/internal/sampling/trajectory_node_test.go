@@ -0,0 +1,171 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestNodeFunc(t *testing.T) {
+	span := ptrace.NewSpan()
+	span.SetName("GET /users/123")
+	span.SetKind(ptrace.SpanKindServer)
+	span.Attributes().PutStr("http.route", "/users/{id}")
+	rs := ptrace.NewResourceSpans()
+	rs.Resource().Attributes().PutStr("deployment.environment", "prod")
+	ss := rs.ScopeSpans().AppendEmpty()
+
+	tests := []struct {
+		name     string
+		shape    TrajectoryShape
+		expected Node
+	}{
+		{
+			name:     "default",
+			shape:    TrajectoryShape{},
+			expected: Node{Service: "svc", Operation: "GET /users/123"},
+		},
+		{
+			name:     "service and operation",
+			shape:    TrajectoryShape{NodeIdentity: NodeIdentityServiceOperation},
+			expected: Node{Service: "svc", Operation: "GET /users/123"},
+		},
+		{
+			name:     "service only",
+			shape:    TrajectoryShape{NodeIdentity: NodeIdentityService},
+			expected: Node{Service: "svc"},
+		},
+		{
+			name:     "service and kind",
+			shape:    TrajectoryShape{NodeIdentity: NodeIdentityServiceKind},
+			expected: Node{Service: "svc", Operation: "Server"},
+		},
+		{
+			name:     "attributes",
+			shape:    TrajectoryShape{NodeIdentity: NodeIdentityAttributes, AttributeKeys: []string{"http.route", "deployment.environment", "missing"}},
+			expected: Node{Service: "svc", Operation: "http.route=/users/{id};deployment.environment=prod;missing="},
+		},
+		{
+			name:     "ottl",
+			shape:    TrajectoryShape{NodeIdentity: NodeIdentityOTTL, Expression: `Concat([attributes["http.route"], resource.attributes["deployment.environment"]], "@")`},
+			expected: Node{Service: "svc", Operation: "/users/{id}@prod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeFor, err := newNodeFunc(componenttest.NewNopTelemetrySettings(), tt.shape)
+			require.NoError(t, err)
+			node, err := nodeFor(context.Background(), span, ss, rs, "svc")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, node)
+		})
+	}
+}
+
+func TestNodeFuncInvalidShape(t *testing.T) {
+	for _, shape := range []TrajectoryShape{
+		{NodeIdentity: "unknown"},
+		{NodeIdentity: NodeIdentityAttributes},
+		{NodeIdentity: NodeIdentityOTTL},
+		{NodeIdentity: NodeIdentityOTTL, Expression: "not a valid expression("},
+	} {
+		_, err := newNodeFunc(componenttest.NewNopTelemetrySettings(), shape)
+		assert.Error(t, err, "shape %v", shape)
+	}
+}
+
+func TestSpanDepths(t *testing.T) {
+	depths := spanDepths(map[string]string{
+		"root":     "",
+		"child":    "root",
+		"grand":    "child",
+		"orphan":   "unknown",
+		"orphan-c": "orphan",
+		"cycle-a":  "cycle-b",
+		"cycle-b":  "cycle-a",
+	})
+
+	assert.Equal(t, 1, depths["root"])
+	assert.Equal(t, 2, depths["child"])
+	assert.Equal(t, 3, depths["grand"])
+	assert.Equal(t, 1, depths["orphan"])
+	assert.Equal(t, 2, depths["orphan-c"])
+	assert.Contains(t, depths, "cycle-a")
+	assert.Contains(t, depths, "cycle-b")
+}
+
+func TestTrajectoryHashWithShape(t *testing.T) {
+	first := newTestTrace([]testSpan{
+		{service: "frontend", name: "GET /users/1", kind: ptrace.SpanKindServer, id: 1},
+		{service: "backend", name: "SELECT * FROM users WHERE id = 1", kind: ptrace.SpanKindClient, id: 2, parent: 1},
+		{service: "db", name: "query", kind: ptrace.SpanKindServer, id: 3, parent: 2},
+	}...)
+	second := newTestTrace([]testSpan{
+		{service: "frontend", name: "GET /users/2", kind: ptrace.SpanKindServer, id: 1},
+		{service: "backend", name: "SELECT * FROM users WHERE id = 2", kind: ptrace.SpanKindClient, id: 2, parent: 1},
+		{service: "cache", name: "get", kind: ptrace.SpanKindServer, id: 3, parent: 2},
+	}...)
+
+	tests := []struct {
+		name      string
+		shape     TrajectoryShape
+		sameShape bool
+	}{
+		{
+			name:      "span names differ",
+			shape:     TrajectoryShape{},
+			sameShape: false,
+		},
+		{
+			name:      "service only, leaves differ",
+			shape:     TrajectoryShape{NodeIdentity: NodeIdentityService},
+			sameShape: false,
+		},
+		{
+			name:      "service and kind, depth limited",
+			shape:     TrajectoryShape{NodeIdentity: NodeIdentityServiceKind, MaxDepth: 2},
+			sameShape: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, nil, ThroughputTarget{}, tt.shape, MonotonicClock{})
+			require.NoError(t, err)
+			sampler := s.(*StratifiedProbabilisticSampler)
+
+			firstHash, _, err := sampler.getTraceTrajectoryHash(context.Background(), first)
+			require.NoError(t, err)
+			secondHash, _, err := sampler.getTraceTrajectoryHash(context.Background(), second)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sameShape, firstHash == secondHash)
+		})
+	}
+}
+
+func TestStratifiedProbabilisticSamplingOTTLError(t *testing.T) {
+	shape := TrajectoryShape{NodeIdentity: NodeIdentityOTTL, Expression: `Int(attributes["count"])`}
+	s, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, nil, ThroughputTarget{}, shape, MonotonicClock{})
+	require.NoError(t, err)
+
+	trace := newTestTrace([]testSpan{
+		{service: "frontend", name: "GET", id: 1, attrs: map[string]any{"count": "1"}},
+	}...)
+	decision, err := s.Evaluate(context.Background(), pcommon.TraceID{1}, trace)
+	require.NoError(t, err)
+	assert.Equal(t, Sampled, decision)
+}
+
+func TestStratifiedProbabilisticSamplingInvalidMaxDepth(t *testing.T) {
+	_, err := NewStratifiedProbabilisticSampler(componenttest.NewNopTelemetrySettings(), "stratified", "", "", 0, 0, 0, nil, ThroughputTarget{}, TrajectoryShape{MaxDepth: -1}, MonotonicClock{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// trajectoryStats holds the number of traces seen and sampled, and the number
+// of spans seen, for a single trajectory in the current and in the previous
+// window.
+type trajectoryStats struct {
+	// canonical edges of the trajectory, kept for inspection.
+	edges       []string
+	seen        int64
+	sampled     int64
+	spans       int64
+	prevSeen    int64
+	prevSampled int64
+	prevSpans   int64
+}
+
+// trajectoryTable is a bounded table of trajectory statistics that survives
+// across decision ticks. Statistics are kept per window: when a window ends,
+// the counts of the current window become the counts of the previous window
+// and trajectories that were not seen during the whole previous window are
+// forgotten. When the table is full, the least recently seen trajectory is
+// evicted. trajectoryTable is not safe for concurrent use.
+type trajectoryTable struct {
+	window  window
+	entries *simplelru.LRU[string, *trajectoryStats]
+	// number of distinct trajectories seen in the current window.
+	distinct int
+}
+
+func newTrajectoryTable(window time.Duration, maxTrajectories int, timeProvider TimeProvider) (*trajectoryTable, error) {
+	t := &trajectoryTable{
+		window: newWindow(window, timeProvider),
+	}
+
+	entries, err := simplelru.NewLRU[string, *trajectoryStats](maxTrajectories, t.onEvict)
+	if err != nil {
+		return nil, err
+	}
+	t.entries = entries
+
+	return t, nil
+}
+
+// record counts a trace for the given trajectory in the current window and
+// returns the statistics of the trajectory, adding an entry if it is not known
+// yet. The returned boolean is true if the trajectory was added by this call.
+func (t *trajectoryTable) record(hash string) (*trajectoryStats, bool) {
+	t.rotate()
+
+	stats, ok := t.entries.Get(hash)
+	if !ok {
+		stats = &trajectoryStats{}
+		t.entries.Add(hash, stats)
+	}
+	if stats.seen == 0 {
+		t.distinct++
+	}
+	stats.seen++
+	return stats, !ok
+}
+
+// elapsedSecs returns the number of seconds elapsed since the start of the
+// current window, at least 1.
+func (t *trajectoryTable) elapsedSecs() int64 {
+	return t.window.elapsedSecs()
+}
+
+// len returns the number of trajectories currently tracked.
+func (t *trajectoryTable) len() int {
+	return t.entries.Len()
+}
+
+// distinctInWindow returns the number of distinct trajectories seen in the
+// current window.
+func (t *trajectoryTable) distinctInWindow() int {
+	return t.distinct
+}
+
+// forEach rotates the window if needed and calls fn for each trajectory
+// currently tracked, from the least to the most recently seen.
+func (t *trajectoryTable) forEach(fn func(hash string, stats *trajectoryStats)) {
+	t.rotate()
+
+	for _, hash := range t.entries.Keys() {
+		stats, _ := t.entries.Peek(hash)
+		fn(hash, stats)
+	}
+}
+
+// rotate starts a new window if the current one has ended, and returns whether
+// it did.
+func (t *trajectoryTable) rotate() bool {
+	rotated := rotateWindow(&t.window, t.entries, func(stats *trajectoryStats) bool {
+		if stats.seen == 0 {
+			return false
+		}
+		stats.prevSeen, stats.prevSampled, stats.prevSpans = stats.seen, stats.sampled, stats.spans
+		stats.seen, stats.sampled, stats.spans = 0, 0, 0
+		return true
+	})
+	if rotated {
+		t.distinct = 0
+	}
+	return rotated
+}
+
+// onEvict keeps the number of distinct trajectories in the current window
+// accurate when a trajectory is removed from the table.
+func (t *trajectoryTable) onEvict(_ string, stats *trajectoryStats) {
+	if stats.seen > 0 {
+		t.distinct--
+	}
+}
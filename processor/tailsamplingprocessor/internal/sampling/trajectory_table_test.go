@@ -0,0 +1,65 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrajectoryTableRotation(t *testing.T) {
+	timeProvider := &FakeTimeProvider{second: 0}
+	table, err := newTrajectoryTable(time.Minute, 10, timeProvider)
+	require.NoError(t, err)
+
+	stats, isNew := table.record("a")
+	assert.True(t, isNew)
+	stats.seen, stats.sampled = 10, 2
+
+	stats, isNew = table.record("a")
+	assert.False(t, isNew)
+	assert.Equal(t, int64(11), stats.seen)
+	_, isNew = table.record("b")
+	assert.True(t, isNew)
+	assert.Equal(t, 2, table.distinctInWindow())
+
+	// "b" was seen in the previous window, so it's still tracked.
+	timeProvider.second = 90
+	stats, isNew = table.record("a")
+	assert.False(t, isNew)
+	assert.Equal(t, &trajectoryStats{seen: 1, prevSeen: 11, prevSampled: 2}, stats)
+	assert.Equal(t, 2, table.len())
+	assert.Equal(t, 1, table.distinctInWindow())
+
+	// More than one window without any trace clears the table.
+	timeProvider.second = 300
+	_, isNew = table.record("b")
+	assert.True(t, isNew)
+	assert.Equal(t, 1, table.len())
+}
+
+func TestTrajectoryTableIsBounded(t *testing.T) {
+	table, err := newTrajectoryTable(time.Minute, 2, &FakeTimeProvider{})
+	require.NoError(t, err)
+
+	table.record("a")
+	table.record("b")
+	table.record("a")
+	table.record("c")
+	assert.Equal(t, 2, table.len())
+	assert.Equal(t, 2, table.distinctInWindow())
+
+	_, isNew := table.record("a")
+	assert.False(t, isNew)
+	_, isNew = table.record("b")
+	assert.True(t, isNew)
+}
+
+func TestTrajectoryTableInvalidSize(t *testing.T) {
+	_, err := newTrajectoryTable(time.Minute, 0, &FakeTimeProvider{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+
+import (
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// window splits time in windows of a fixed length, for the evaluators keeping
+// statistics per key over the current and the previous window. window is not
+// safe for concurrent use.
+type window struct {
+	// length of the window, in seconds.
+	secs int64
+	// start of the current window, in Unix seconds.
+	start        int64
+	timeProvider TimeProvider
+}
+
+// newWindow returns a window of the given length, at least one second,
+// starting now.
+func newWindow(length time.Duration, timeProvider TimeProvider) window {
+	return window{
+		secs:         max(1, int64(length/time.Second)),
+		start:        timeProvider.getCurSecond(),
+		timeProvider: timeProvider,
+	}
+}
+
+// elapsedSecs returns the number of seconds elapsed since the start of the
+// current window, at least 1.
+func (w *window) elapsedSecs() int64 {
+	return max(1, w.timeProvider.getCurSecond()-w.start)
+}
+
+// rotateWindow starts a new window if the current one has ended, and returns
+// whether it did. The statistics of each entry are then moved to the previous
+// window by rotate, which returns false if the entry wasn't seen during the
+// window that ended, so that it's forgotten. If more than one full window went
+// by without any trace, the previous window is empty as well, and all the
+// entries are forgotten.
+func rotateWindow[K comparable, V any](w *window, entries *simplelru.LRU[K, V], rotate func(V) bool) bool {
+	now := w.timeProvider.getCurSecond()
+	elapsed := now - w.start
+	if elapsed < w.secs {
+		return false
+	}
+
+	skipped := elapsed >= 2*w.secs
+	w.start = now - elapsed%w.secs
+
+	for _, key := range entries.Keys() {
+		value, _ := entries.Peek(key)
+		if skipped || !rotate(value) {
+			entries.Remove(key)
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWindow(t *testing.T) {
+	w := newWindow(500*time.Millisecond, &FakeTimeProvider{second: 7})
+	assert.Equal(t, int64(1), w.secs)
+	assert.Equal(t, int64(7), w.start)
+	assert.Equal(t, int64(1), w.elapsedSecs())
+}
+
+func TestRotateWindow(t *testing.T) {
+	clock := &FakeTimeProvider{second: 0}
+	w := newWindow(10*time.Second, clock)
+	entries, err := simplelru.NewLRU[string, *int](10, nil)
+	require.NoError(t, err)
+	seen := func(n int) *int { return &n }
+	entries.Add("seen", seen(1))
+	entries.Add("unseen", seen(0))
+
+	var rotated []int
+	rotate := func(count *int) bool {
+		rotated = append(rotated, *count)
+		if *count == 0 {
+			return false
+		}
+		*count = 0
+		return true
+	}
+
+	clock.second = 9
+	assert.False(t, rotateWindow(&w, entries, rotate))
+	assert.Empty(t, rotated)
+	assert.Equal(t, int64(9), w.elapsedSecs())
+
+	// The new window starts at a multiple of the length from the previous one.
+	clock.second = 13
+	assert.True(t, rotateWindow(&w, entries, rotate))
+	assert.Equal(t, []int{1, 0}, rotated)
+	assert.Equal(t, int64(10), w.start)
+	assert.Equal(t, []string{"seen"}, entries.Keys())
+
+	// After a whole window without any trace, the entries are forgotten without being rotated.
+	rotated = nil
+	entries.Add("seen-again", seen(1))
+	clock.second = 30
+	assert.True(t, rotateWindow(&w, entries, rotate))
+	assert.Empty(t, rotated)
+	assert.Equal(t, int64(30), w.start)
+	assert.Zero(t, entries.Len())
+}
@@ -0,0 +1,229 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package spanmetrics aggregates the request, error and duration (RED)
+// metrics of spans, whether their trace is sampled or not.
+package spanmetrics // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spanmetrics"
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/traceutil"
+)
+
+const (
+	// CallsMetric is the name of the sum counting spans.
+	CallsMetric = "traces.span.metrics.calls"
+	// DurationMetric is the name of the histogram of the duration of spans, in milliseconds.
+	DurationMetric = "traces.span.metrics.duration"
+
+	serviceNameKey = "service.name"
+	spanNameKey    = "span.name"
+	spanKindKey    = "span.kind"
+	statusCodeKey  = "status.code"
+	// overflowKey marks the overflow series, as in the OpenTelemetry SDKs.
+	overflowKey = "otel.metric.overflow"
+
+	// maxExemplars is the number of exemplars kept for each series between two
+	// collections.
+	maxExemplars = 5
+)
+
+type seriesKey struct {
+	service string
+	name    string
+	kind    ptrace.SpanKind
+	status  ptrace.StatusCode
+	// overflow is true for the series of the spans beyond the maximum number of series.
+	overflow bool
+}
+
+type series struct {
+	count        uint64
+	sum          float64
+	bucketCounts []uint64
+	exemplars    pmetric.ExemplarSlice
+}
+
+// Aggregator aggregates the metrics of spans into cumulative series, one per
+// service, span name, span kind and status code. It is safe for concurrent use.
+type Aggregator struct {
+	mu        sync.Mutex
+	scopeName string
+	bounds    []float64
+	startTime pcommon.Timestamp
+	maxSeries int
+	series    map[seriesKey]*series
+}
+
+// New returns an Aggregator of the duration of spans into histograms with the
+// given bucket bounds. Metrics are reported in the scope of the given name.
+// Once maxSeries series exist, the spans of new series are aggregated into a
+// single overflow series.
+func New(scopeName string, buckets []time.Duration, maxSeries int) *Aggregator {
+	bounds := make([]float64, 0, len(buckets))
+	for _, b := range buckets {
+		bounds = append(bounds, float64(b)/float64(time.Millisecond))
+	}
+	slices.Sort(bounds)
+	return &Aggregator{
+		scopeName: scopeName,
+		bounds:    slices.Compact(bounds),
+		startTime: pcommon.NewTimestampFromTime(time.Now()),
+		maxSeries: maxSeries,
+		series:    map[seriesKey]*series{},
+	}
+}
+
+// Add aggregates the spans of a trace. The spans of sampled traces are kept
+// as exemplars.
+func (a *Aggregator) Add(td ptrace.Traces, sampled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		service := ""
+		if v, ok := rs.Resource().Attributes().Get(serviceNameKey); ok {
+			service = v.AsString()
+		}
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				a.add(service, spans.At(k), sampled)
+			}
+		}
+	}
+}
+
+func (a *Aggregator) add(service string, span ptrace.Span, sampled bool) {
+	key := seriesKey{service: service, name: span.Name(), kind: span.Kind(), status: span.Status().Code()}
+	s, ok := a.series[key]
+	if !ok && len(a.series) >= a.maxSeries {
+		key = seriesKey{overflow: true}
+		s, ok = a.series[key]
+	}
+	if !ok {
+		s = &series{bucketCounts: make([]uint64, len(a.bounds)+1), exemplars: pmetric.NewExemplarSlice()}
+		a.series[key] = s
+	}
+
+	duration := float64(span.EndTimestamp()-span.StartTimestamp()) / float64(time.Millisecond)
+	if span.EndTimestamp() < span.StartTimestamp() {
+		duration = 0
+	}
+	s.count++
+	s.sum += duration
+	i, _ := slices.BinarySearch(a.bounds, duration)
+	s.bucketCounts[i]++
+
+	if sampled && s.exemplars.Len() < maxExemplars {
+		e := s.exemplars.AppendEmpty()
+		e.SetTraceID(span.TraceID())
+		e.SetSpanID(span.SpanID())
+		e.SetTimestamp(span.EndTimestamp())
+		e.SetDoubleValue(duration)
+	}
+}
+
+// Metrics returns the cumulative metrics of all the spans aggregated so far,
+// grouped by service. The exemplars are reset.
+func (a *Aggregator) Metrics() pmetric.Metrics {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	md := pmetric.NewMetrics()
+	if len(a.series) == 0 {
+		return md
+	}
+
+	keys := make([]seriesKey, 0, len(a.series))
+	for key := range a.series {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(x, y seriesKey) int {
+		return cmp.Or(
+			compareBool(x.overflow, y.overflow),
+			cmp.Compare(x.service, y.service),
+			cmp.Compare(x.name, y.name),
+			cmp.Compare(x.kind, y.kind),
+			cmp.Compare(x.status, y.status),
+		)
+	})
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	var calls pmetric.Sum
+	var durations pmetric.Histogram
+	for i, key := range keys {
+		if i == 0 || key.service != keys[i-1].service || key.overflow {
+			rm := md.ResourceMetrics().AppendEmpty()
+			if !key.overflow {
+				rm.Resource().Attributes().PutStr(serviceNameKey, key.service)
+			}
+			sm := rm.ScopeMetrics().AppendEmpty()
+			sm.Scope().SetName(a.scopeName)
+
+			callsMetric := sm.Metrics().AppendEmpty()
+			callsMetric.SetName(CallsMetric)
+			callsMetric.SetUnit("{calls}")
+			calls = callsMetric.SetEmptySum()
+			calls.SetIsMonotonic(true)
+			calls.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+
+			durationMetric := sm.Metrics().AppendEmpty()
+			durationMetric.SetName(DurationMetric)
+			durationMetric.SetUnit("ms")
+			durations = durationMetric.SetEmptyHistogram()
+			durations.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+		}
+
+		s := a.series[key]
+		dp := calls.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(a.startTime)
+		dp.SetTimestamp(now)
+		dp.SetIntValue(int64(s.count))
+		putAttributes(dp.Attributes(), key)
+
+		hdp := durations.DataPoints().AppendEmpty()
+		hdp.SetStartTimestamp(a.startTime)
+		hdp.SetTimestamp(now)
+		hdp.SetCount(s.count)
+		hdp.SetSum(s.sum)
+		hdp.ExplicitBounds().FromRaw(a.bounds)
+		hdp.BucketCounts().FromRaw(s.bucketCounts)
+		putAttributes(hdp.Attributes(), key)
+		s.exemplars.MoveAndAppendTo(hdp.Exemplars())
+	}
+	return md
+}
+
+func putAttributes(attrs pcommon.Map, key seriesKey) {
+	if key.overflow {
+		attrs.PutBool(overflowKey, true)
+		return
+	}
+	attrs.PutStr(spanNameKey, key.name)
+	attrs.PutStr(spanKindKey, traceutil.SpanKindStr(key.kind))
+	attrs.PutStr(statusCodeKey, traceutil.StatusCodeStr(key.status))
+}
+
+// compareBool orders false before true.
+func compareBool(x, y bool) int {
+	switch {
+	case x == y:
+		return 0
+	case x:
+		return 1
+	default:
+		return -1
+	}
+}
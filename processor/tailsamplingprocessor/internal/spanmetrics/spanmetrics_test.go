@@ -0,0 +1,158 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package spanmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+var start = time.Unix(1700000000, 0)
+
+type testSpan struct {
+	id       byte
+	name     string
+	duration time.Duration
+	err      bool
+}
+
+func newTrace(traceID byte, service string, spans ...testSpan) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	ss := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, s := range spans {
+		span := ss.AppendEmpty()
+		span.SetTraceID(pcommon.TraceID{traceID})
+		span.SetSpanID(pcommon.SpanID{s.id})
+		span.SetName(s.name)
+		span.SetKind(ptrace.SpanKindServer)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(s.duration)))
+		if s.err {
+			span.Status().SetCode(ptrace.StatusCodeError)
+		}
+	}
+	return td
+}
+
+func TestAggregator(t *testing.T) {
+	a := New("scope", []time.Duration{100 * time.Millisecond, 10 * time.Millisecond}, 10)
+	a.Add(newTrace(1, "checkout",
+		testSpan{id: 1, name: "GET /cart", duration: 5 * time.Millisecond},
+		testSpan{id: 2, name: "GET /cart", duration: 50 * time.Millisecond},
+		testSpan{id: 3, name: "GET /cart", duration: time.Second, err: true},
+	), false)
+	a.Add(newTrace(2, "checkout", testSpan{id: 4, name: "GET /cart", duration: 20 * time.Millisecond}), true)
+	a.Add(newTrace(3, "cart", testSpan{id: 5, name: "get", duration: 10 * time.Millisecond}), false)
+
+	md := a.Metrics()
+	require.Equal(t, 2, md.ResourceMetrics().Len())
+
+	// Resources are sorted by service.
+	cart := md.ResourceMetrics().At(0)
+	service, _ := cart.Resource().Attributes().Get("service.name")
+	assert.Equal(t, "cart", service.Str())
+	require.Equal(t, 1, cart.ScopeMetrics().Len())
+	assert.Equal(t, "scope", cart.ScopeMetrics().At(0).Scope().Name())
+
+	checkout := md.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 2, checkout.Len())
+	calls := checkout.At(0)
+	assert.Equal(t, CallsMetric, calls.Name())
+	require.Equal(t, pmetric.MetricTypeSum, calls.Type())
+	assert.True(t, calls.Sum().IsMonotonic())
+	assert.Equal(t, pmetric.AggregationTemporalityCumulative, calls.Sum().AggregationTemporality())
+	require.Equal(t, 2, calls.Sum().DataPoints().Len())
+
+	// The ok and error series of the span name.
+	ok := calls.Sum().DataPoints().At(0)
+	assert.Equal(t, map[string]any{"span.name": "GET /cart", "span.kind": "SPAN_KIND_SERVER", "status.code": "STATUS_CODE_UNSET"}, ok.Attributes().AsRaw())
+	assert.Equal(t, int64(3), ok.IntValue())
+	errored := calls.Sum().DataPoints().At(1)
+	assert.Equal(t, "STATUS_CODE_ERROR", errored.Attributes().AsRaw()["status.code"])
+	assert.Equal(t, int64(1), errored.IntValue())
+
+	durations := checkout.At(1)
+	assert.Equal(t, DurationMetric, durations.Name())
+	assert.Equal(t, "ms", durations.Unit())
+	require.Equal(t, pmetric.MetricTypeHistogram, durations.Type())
+	require.Equal(t, 2, durations.Histogram().DataPoints().Len())
+	hdp := durations.Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(3), hdp.Count())
+	assert.InDelta(t, 75, hdp.Sum(), 0.001)
+	assert.Equal(t, []float64{10, 100}, hdp.ExplicitBounds().AsRaw())
+	assert.Equal(t, []uint64{1, 2, 0}, hdp.BucketCounts().AsRaw())
+
+	// Only the spans of sampled traces are exemplars.
+	require.Equal(t, 1, hdp.Exemplars().Len())
+	exemplar := hdp.Exemplars().At(0)
+	assert.Equal(t, pcommon.TraceID{2}, exemplar.TraceID())
+	assert.Equal(t, pcommon.SpanID{4}, exemplar.SpanID())
+	assert.InDelta(t, 20, exemplar.DoubleValue(), 0.001)
+	assert.Equal(t, 0, durations.Histogram().DataPoints().At(1).Exemplars().Len())
+}
+
+func TestAggregatorIsCumulative(t *testing.T) {
+	a := New("scope", []time.Duration{time.Second}, 10)
+	assert.Equal(t, 0, a.Metrics().ResourceMetrics().Len())
+
+	a.Add(newTrace(1, "checkout", testSpan{id: 1, name: "GET /cart"}), true)
+	first := a.Metrics()
+	a.Add(newTrace(2, "checkout", testSpan{id: 2, name: "GET /cart"}), false)
+	second := a.Metrics()
+
+	firstCalls := first.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	secondCalls := second.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	assert.Equal(t, int64(1), firstCalls.IntValue())
+	assert.Equal(t, int64(2), secondCalls.IntValue())
+	assert.Equal(t, firstCalls.StartTimestamp(), secondCalls.StartTimestamp())
+
+	// Exemplars are reset once collected.
+	firstDurations := first.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(1).Histogram().DataPoints().At(0)
+	secondDurations := second.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(1).Histogram().DataPoints().At(0)
+	assert.Equal(t, 1, firstDurations.Exemplars().Len())
+	assert.Equal(t, 0, secondDurations.Exemplars().Len())
+}
+
+func TestAggregatorLimitsExemplars(t *testing.T) {
+	a := New("scope", nil, 10)
+	for i := byte(1); i <= 2*maxExemplars; i++ {
+		a.Add(newTrace(i, "checkout", testSpan{id: i, name: "GET /cart"}), true)
+	}
+	hdp := a.Metrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(1).Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(2*maxExemplars), hdp.Count())
+	assert.Equal(t, maxExemplars, hdp.Exemplars().Len())
+}
+
+func TestAggregatorLimitsSeries(t *testing.T) {
+	a := New("scope", nil, 2)
+	a.Add(newTrace(1, "checkout", testSpan{id: 1, name: "GET /cart"}, testSpan{id: 2, name: "POST /cart"}), false)
+	a.Add(newTrace(2, "checkout", testSpan{id: 3, name: "GET /order"}, testSpan{id: 4, name: "GET /cart"}), false)
+	a.Add(newTrace(3, "cart", testSpan{id: 5, name: "get"}), false)
+
+	md := a.Metrics()
+	require.Equal(t, 2, md.ResourceMetrics().Len())
+
+	// Existing series keep being counted.
+	checkout := md.ResourceMetrics().At(0)
+	calls := checkout.ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+	require.Equal(t, 2, calls.Len())
+	assert.Equal(t, int64(2), calls.At(0).IntValue())
+	assert.Equal(t, int64(1), calls.At(1).IntValue())
+
+	// Spans of new series, whatever their service, are counted in the overflow series.
+	overflow := md.ResourceMetrics().At(1)
+	assert.Equal(t, 0, overflow.Resource().Attributes().Len())
+	calls = overflow.ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints()
+	require.Equal(t, 1, calls.Len())
+	assert.Equal(t, int64(2), calls.At(0).IntValue())
+	assert.Equal(t, map[string]any{overflowKey: true}, calls.At(0).Attributes().AsRaw())
+}
@@ -0,0 +1,236 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package spill defines a bounded store of pending traces on disk, holding the
+// spans of traces that don't fit in memory until a sampling decision is made on them.
+package spill // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spill"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const fileExtension = ".spill"
+
+var (
+	// ErrFull occurs when spilling spans would exceed the maximum size of the store.
+	ErrFull = errors.New("spill directory is full")
+	// ErrInvalidMaxSize occurs when an invalid maximum size is specified.
+	ErrInvalidMaxSize = errors.New("invalid spill maximum size, it must be greater than zero")
+)
+
+// Trace is a trace reloaded from the store.
+type Trace struct {
+	// ArrivalTime is the time the first span of the trace was received.
+	ArrivalTime time.Time
+	// SpanCount is the number of spans of the trace.
+	SpanCount int64
+	// Batches holds the spans of the trace.
+	Batches ptrace.Traces
+	// State is the state of the trace given when spilling it.
+	State any
+}
+
+// entry describes a trace in the store.
+type entry struct {
+	arrivalTime time.Time
+	spanCount   int64
+	size        int64
+	state       any
+}
+
+// Store holds the spans of pending traces in a directory, one file per trace.
+// Each file is a sequence of records, each record being the length of a batch
+// of spans followed by the batch, encoded as OTLP protobuf. The total size of
+// the files is bounded. It is safe for concurrent use.
+type Store struct {
+	mu        sync.Mutex
+	dir       string
+	maxSize   int64
+	size      int64
+	traces    map[pcommon.TraceID]*entry
+	marshaler ptrace.ProtoMarshaler
+}
+
+// New returns a Store holding traces in the given directory, up to the given
+// size in bytes. The directory must exist. Traces left in it by a previous
+// Store are removed, as they can't be decided on anymore.
+func New(dir string, maxSize int64) (*Store, error) {
+	if maxSize <= 0 {
+		return nil, ErrInvalidMaxSize
+	}
+	s := &Store{
+		dir:     dir,
+		maxSize: maxSize,
+		traces:  map[pcommon.TraceID]*entry{},
+	}
+	if err := s.removeAll(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Contains returns whether the given trace is in the store.
+func (s *Store) Contains(id pcommon.TraceID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.traces[id]
+	return ok
+}
+
+// Spill adds the spans of a trace to the store. The state of the trace is kept
+// in memory, and returned as is when the trace is loaded.
+func (s *Store) Spill(id pcommon.TraceID, td ptrace.Traces, arrivalTime time.Time, spanCount int64, state any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.traces[id]
+	if !ok {
+		e = &entry{arrivalTime: arrivalTime}
+	}
+	if err := s.write(id, e, td); err != nil {
+		if !ok {
+			_ = os.Remove(s.path(id))
+		}
+		return err
+	}
+	e.spanCount += spanCount
+	e.state = state
+	s.traces[id] = e
+	return nil
+}
+
+// Append adds spans to a trace of the store. It returns false if the trace
+// isn't in the store, in which case the spans are not added.
+func (s *Store) Append(id pcommon.TraceID, td ptrace.Traces, spanCount int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.traces[id]
+	if !ok {
+		return false, nil
+	}
+	if err := s.write(id, e, td); err != nil {
+		return true, err
+	}
+	e.spanCount += spanCount
+	return true, nil
+}
+
+// Load removes a trace from the store and returns it. It returns false if the
+// trace isn't in the store.
+func (s *Store) Load(id pcommon.TraceID) (Trace, bool, error) {
+	s.mu.Lock()
+	e, ok := s.traces[id]
+	if ok {
+		delete(s.traces, id)
+		s.size -= e.size
+	}
+	s.mu.Unlock()
+	if !ok {
+		return Trace{}, false, nil
+	}
+
+	path := s.path(id)
+	batches, err := readBatches(path)
+	err = errors.Join(err, os.Remove(path))
+	return Trace{ArrivalTime: e.arrivalTime, SpanCount: e.spanCount, Batches: batches, State: e.state}, true, err
+}
+
+// Size returns the size of the store, in bytes.
+func (s *Store) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Close removes all the traces of the store.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.traces = map[pcommon.TraceID]*entry{}
+	s.size = 0
+	return s.removeAll()
+}
+
+// write appends a record holding the given spans to the file of a trace.
+func (s *Store) write(id pcommon.TraceID, e *entry, td ptrace.Traces) error {
+	buf, err := s.marshaler.MarshalTraces(td)
+	if err != nil {
+		return err
+	}
+	record := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(buf)), uint32(len(buf)))
+	record = append(record, buf...)
+	if s.size+int64(len(record)) > s.maxSize {
+		return ErrFull
+	}
+
+	file, err := os.OpenFile(s.path(id), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(record); err != nil {
+		// Drop the partial record, so that the next records can be read.
+		err = errors.Join(fmt.Errorf("failed to spill trace %s: %w", id, err), file.Truncate(e.size))
+	}
+	if err = errors.Join(err, file.Close()); err != nil {
+		return err
+	}
+	e.size += int64(len(record))
+	s.size += int64(len(record))
+	return nil
+}
+
+func (s *Store) path(id pcommon.TraceID) string {
+	return filepath.Join(s.dir, id.String()+fileExtension)
+}
+
+// removeAll removes the files of all traces from the directory.
+func (s *Store) removeAll() error {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var errs error
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), fileExtension) {
+			continue
+		}
+		errs = errors.Join(errs, os.Remove(filepath.Join(s.dir, dirEntry.Name())))
+	}
+	return errs
+}
+
+// readBatches reads the records of a trace file and returns their spans. A
+// partial record at the end of the file is ignored.
+func readBatches(path string) (ptrace.Traces, error) {
+	batches := ptrace.NewTraces()
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return batches, err
+	}
+
+	var unmarshaler ptrace.ProtoUnmarshaler
+	for len(buf) >= 4 {
+		n := int(binary.LittleEndian.Uint32(buf))
+		if len(buf)-4 < n {
+			break
+		}
+		td, err := unmarshaler.UnmarshalTraces(buf[4 : 4+n])
+		if err != nil {
+			return batches, err
+		}
+		td.ResourceSpans().MoveAndAppendTo(batches.ResourceSpans())
+		buf = buf[4+n:]
+	}
+	return batches, nil
+}
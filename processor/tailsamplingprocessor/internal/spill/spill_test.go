@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package spill
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newTraces(id pcommon.TraceID, names ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for _, name := range names {
+		span := spans.AppendEmpty()
+		span.SetTraceID(id)
+		span.SetName(name)
+	}
+	return td
+}
+
+func spanNames(td ptrace.Traces) []string {
+	var names []string
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		ilss := td.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				names = append(names, spans.At(k).Name())
+			}
+		}
+	}
+	return names
+}
+
+func TestSpillAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 1<<20)
+	require.NoError(t, err)
+
+	id := pcommon.TraceID([16]byte{1})
+	arrivalTime := time.Unix(1000, 0)
+	require.NoError(t, s.Spill(id, newTraces(id, "a", "b"), arrivalTime, 2, "state"))
+	assert.True(t, s.Contains(id))
+	assert.Positive(t, s.Size())
+
+	ok, err := s.Append(id, newTraces(id, "c"), 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = s.Append(pcommon.TraceID([16]byte{2}), newTraces(id, "d"), 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	trace, ok, err := s.Load(id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, arrivalTime, trace.ArrivalTime)
+	assert.Equal(t, "state", trace.State)
+	assert.Equal(t, int64(3), trace.SpanCount)
+	assert.Equal(t, []string{"a", "b", "c"}, spanNames(trace.Batches))
+
+	assert.False(t, s.Contains(id))
+	assert.Zero(t, s.Size())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	_, ok, err = s.Load(id)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSpillFull(t *testing.T) {
+	id1 := pcommon.TraceID([16]byte{1})
+	id2 := pcommon.TraceID([16]byte{2})
+	var marshaler ptrace.ProtoMarshaler
+	size := int64(4 + marshaler.TracesSize(newTraces(id1, "a")))
+
+	s, err := New(t.TempDir(), size)
+	require.NoError(t, err)
+	require.NoError(t, s.Spill(id1, newTraces(id1, "a"), time.Now(), 1, nil))
+	assert.Equal(t, size, s.Size())
+
+	require.ErrorIs(t, s.Spill(id2, newTraces(id2, "a"), time.Now(), 1, nil), ErrFull)
+	assert.False(t, s.Contains(id2))
+	ok, err := s.Append(id1, newTraces(id1, "b"), 1)
+	require.ErrorIs(t, err, ErrFull)
+	assert.True(t, ok)
+
+	// Loading a trace frees its space.
+	trace, ok, err := s.Load(id1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), trace.SpanCount)
+	require.NoError(t, s.Spill(id2, newTraces(id2, "a"), time.Now(), 1, nil))
+}
+
+func TestNewRemovesPreviousTraces(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.txt")
+	require.NoError(t, os.WriteFile(other, []byte("other"), 0o600))
+
+	s, err := New(dir, 1<<20)
+	require.NoError(t, err)
+	id := pcommon.TraceID([16]byte{1})
+	require.NoError(t, s.Spill(id, newTraces(id, "a"), time.Now(), 1, nil))
+
+	_, err = New(dir, 1<<20)
+	require.NoError(t, err)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "other.txt", files[0].Name())
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 1<<20)
+	require.NoError(t, err)
+	id := pcommon.TraceID([16]byte{1})
+	require.NoError(t, s.Spill(id, newTraces(id, "a"), time.Now(), 1, nil))
+
+	require.NoError(t, s.Close())
+	assert.False(t, s.Contains(id))
+	assert.Zero(t, s.Size())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestLoadIgnoresPartialRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(dir, 1<<20)
+	require.NoError(t, err)
+	id := pcommon.TraceID([16]byte{1})
+	require.NoError(t, s.Spill(id, newTraces(id, "a"), time.Now(), 1, nil))
+
+	file, err := os.OpenFile(s.path(id), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.Write([]byte{100, 0, 0, 0, 1})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	trace, ok, err := s.Load(id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []string{"a"}, spanNames(trace.Batches))
+}
+
+func TestInvalidMaxSize(t *testing.T) {
+	_, err := New(t.TempDir(), 0)
+	require.ErrorIs(t, err, ErrInvalidMaxSize)
+}
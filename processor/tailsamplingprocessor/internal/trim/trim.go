@@ -0,0 +1,163 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package trim removes the spans of large sampled traces that add little to
+// them, keeping their structure, errors and slow spans.
+package trim // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/trim"
+
+import (
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// TrimmedSpansAttribute is the attribute counting the spans removed below a
+// span, down to the next spans kept.
+const TrimmedSpansAttribute = "tailsampling.trimmed_spans"
+
+// Trimmer trims traces.
+type Trimmer struct {
+	minSpans         int64
+	latencyThreshold time.Duration
+	spansPerGroup    int
+}
+
+// New returns a Trimmer of traces with more than minSpans spans. It keeps the
+// spans lasting at least latencyThreshold, if not zero, and the spansPerGroup
+// first spans of each service and span name.
+func New(minSpans int64, latencyThreshold time.Duration, spansPerGroup int) *Trimmer {
+	return &Trimmer{
+		minSpans:         minSpans,
+		latencyThreshold: latencyThreshold,
+		spansPerGroup:    spansPerGroup,
+	}
+}
+
+type groupKey struct {
+	service string
+	name    string
+}
+
+// spanInfo describes a span of the trace being trimmed.
+type spanInfo struct {
+	span  ptrace.Span
+	group groupKey
+	keep  bool
+	// trimmed is the number of removed spans whose closest kept ancestor is this span.
+	trimmed int64
+}
+
+// Trim removes the spans of a trace with more spans than the minimum, except
+// its root spans, error spans, slow spans, the representative spans of each
+// group and the ancestors of all of them. Spans whose parent isn't in the
+// trace are root spans. The number of removed spans is added to their closest
+// kept ancestor, in the TrimmedSpansAttribute. It returns the number of
+// removed spans.
+func (t *Trimmer) Trim(td ptrace.Traces) int64 {
+	if int64(td.SpanCount()) <= t.minSpans {
+		return 0
+	}
+
+	spans := map[pcommon.SpanID]*spanInfo{}
+	var ordered []*spanInfo
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		service := ""
+		if v, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			service = v.AsString()
+		}
+		ilss := rs.ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			ss := ilss.At(j).Spans()
+			for k := 0; k < ss.Len(); k++ {
+				span := ss.At(k)
+				info := &spanInfo{span: span, group: groupKey{service: service, name: span.Name()}}
+				spans[span.SpanID()] = info
+				ordered = append(ordered, info)
+			}
+		}
+	}
+
+	// Representative spans are the first ones of each group.
+	slices.SortStableFunc(ordered, func(a, b *spanInfo) int {
+		return a.span.StartTimestamp().AsTime().Compare(b.span.StartTimestamp().AsTime())
+	})
+	groups := map[groupKey]int{}
+	for _, info := range ordered {
+		span := info.span
+		_, hasParent := spans[span.ParentSpanID()]
+		switch {
+		case span.ParentSpanID().IsEmpty() || !hasParent:
+			info.keep = true
+		case span.Status().Code() == ptrace.StatusCodeError:
+			info.keep = true
+		case t.latencyThreshold > 0 && span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()) >= t.latencyThreshold:
+			info.keep = true
+		case groups[info.group] < t.spansPerGroup:
+			info.keep = true
+		}
+		if info.keep {
+			groups[info.group]++
+		}
+	}
+
+	// Keep the path from the root to each kept span.
+	for _, info := range ordered {
+		if !info.keep {
+			continue
+		}
+		for parent := spans[info.span.ParentSpanID()]; parent != nil && !parent.keep; parent = spans[parent.span.ParentSpanID()] {
+			parent.keep = true
+		}
+	}
+
+	var removed int64
+	for _, info := range ordered {
+		if info.keep {
+			continue
+		}
+		parent := closestKeptAncestor(spans, info)
+		if parent == nil {
+			// The parents of the span loop, keep it as a root span.
+			info.keep = true
+			continue
+		}
+		parent.trimmed++
+		removed++
+	}
+	if removed == 0 {
+		return 0
+	}
+
+	for _, info := range ordered {
+		if info.keep && info.trimmed > 0 {
+			info.span.Attributes().PutInt(TrimmedSpansAttribute, info.trimmed)
+		}
+	}
+	rss.RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ils ptrace.ScopeSpans) bool {
+			ils.Spans().RemoveIf(func(span ptrace.Span) bool {
+				return !spans[span.SpanID()].keep
+			})
+			return ils.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return removed
+}
+
+// closestKeptAncestor returns the closest kept ancestor of a span, or nil if
+// its ancestors loop without any being kept.
+func closestKeptAncestor(spans map[pcommon.SpanID]*spanInfo, info *spanInfo) *spanInfo {
+	parent := spans[info.span.ParentSpanID()]
+	for range spans {
+		if parent == nil || parent.keep {
+			return parent
+		}
+		parent = spans[parent.span.ParentSpanID()]
+	}
+	return nil
+}
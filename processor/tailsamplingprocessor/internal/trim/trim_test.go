@@ -0,0 +1,155 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package trim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+var start = time.Unix(1700000000, 0)
+
+type testSpan struct {
+	id       byte
+	parent   byte
+	name     string
+	offset   time.Duration
+	duration time.Duration
+	err      bool
+}
+
+func newTrace(service string, spans ...testSpan) ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", service)
+	ss := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, s := range spans {
+		span := ss.AppendEmpty()
+		span.SetSpanID(pcommon.SpanID{s.id})
+		if s.parent != 0 {
+			span.SetParentSpanID(pcommon.SpanID{s.parent})
+		}
+		span.SetName(s.name)
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(start.Add(s.offset)))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(s.offset + s.duration)))
+		if s.err {
+			span.Status().SetCode(ptrace.StatusCodeError)
+		}
+	}
+	return td
+}
+
+// spansOf returns the kept spans, by ID, with the number of spans trimmed below them.
+func spansOf(td ptrace.Traces) map[byte]int64 {
+	spans := map[byte]int64{}
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			ss := ilss.At(j).Spans()
+			for k := 0; k < ss.Len(); k++ {
+				span := ss.At(k)
+				trimmed := int64(0)
+				if v, ok := span.Attributes().Get(TrimmedSpansAttribute); ok {
+					trimmed = v.Int()
+				}
+				spans[span.SpanID()[0]] = trimmed
+			}
+		}
+	}
+	return spans
+}
+
+func TestTrim(t *testing.T) {
+	// A root span calling a service, which queries a database many times.
+	td := newTrace("frontend",
+		testSpan{id: 1, name: "GET /", duration: time.Second},
+		testSpan{id: 2, parent: 1, name: "call", offset: time.Millisecond, duration: 900 * time.Millisecond},
+		testSpan{id: 3, parent: 2, name: "SELECT", offset: 2 * time.Millisecond, duration: time.Millisecond},
+		testSpan{id: 4, parent: 2, name: "SELECT", offset: 3 * time.Millisecond, duration: time.Millisecond},
+		testSpan{id: 5, parent: 2, name: "SELECT", offset: 4 * time.Millisecond, duration: 300 * time.Millisecond},
+		testSpan{id: 6, parent: 2, name: "SELECT", offset: 5 * time.Millisecond, duration: time.Millisecond},
+		testSpan{id: 7, parent: 6, name: "fetch", offset: 5 * time.Millisecond, duration: time.Millisecond, err: true},
+		testSpan{id: 8, parent: 2, name: "SELECT", offset: 6 * time.Millisecond, duration: time.Millisecond},
+		testSpan{id: 9, parent: 8, name: "fetch", offset: 6 * time.Millisecond, duration: time.Millisecond},
+	)
+
+	removed := New(5, 100*time.Millisecond, 1).Trim(td)
+
+	// The first SELECT is representative, the third one is slow, the fourth one is on the path to an error. The
+	// first fetch is an error, so that the second one isn't representative, and is counted along with its parent.
+	assert.Equal(t, int64(3), removed)
+	assert.Equal(t, map[byte]int64{1: 0, 2: 3, 3: 0, 5: 0, 6: 0, 7: 0}, spansOf(td))
+}
+
+func TestTrimSmallTrace(t *testing.T) {
+	td := newTrace("frontend",
+		testSpan{id: 1, name: "GET /"},
+		testSpan{id: 2, parent: 1, name: "SELECT"},
+		testSpan{id: 3, parent: 1, name: "SELECT"},
+	)
+	assert.Equal(t, int64(0), New(3, 0, 1).Trim(td))
+	assert.Equal(t, 3, td.SpanCount())
+
+	assert.Equal(t, int64(1), New(2, 0, 1).Trim(td))
+	assert.Equal(t, map[byte]int64{1: 1, 2: 0}, spansOf(td))
+}
+
+func TestTrimKeepsSpansWithMissingParent(t *testing.T) {
+	td := newTrace("backend",
+		testSpan{id: 2, parent: 1, name: "call"},
+		testSpan{id: 3, parent: 2, name: "SELECT"},
+		testSpan{id: 4, parent: 2, name: "SELECT", offset: time.Millisecond},
+	)
+	assert.Equal(t, int64(1), New(1, 0, 1).Trim(td))
+	assert.Equal(t, map[byte]int64{2: 1, 3: 0}, spansOf(td))
+}
+
+func TestTrimGroupsByService(t *testing.T) {
+	td := newTrace("frontend",
+		testSpan{id: 1, name: "GET /"},
+		testSpan{id: 2, parent: 1, name: "SELECT"},
+		testSpan{id: 3, parent: 1, name: "SELECT", offset: time.Millisecond},
+	)
+	backend := newTrace("backend",
+		testSpan{id: 4, parent: 1, name: "SELECT"},
+		testSpan{id: 5, parent: 1, name: "SELECT", offset: time.Millisecond},
+	)
+	backend.ResourceSpans().MoveAndAppendTo(td.ResourceSpans())
+
+	assert.Equal(t, int64(2), New(1, 0, 1).Trim(td))
+	assert.Equal(t, map[byte]int64{1: 2, 2: 0, 4: 0}, spansOf(td))
+}
+
+func TestTrimLoopingParents(t *testing.T) {
+	td := newTrace("frontend",
+		testSpan{id: 1, parent: 2, name: "a"},
+		testSpan{id: 2, parent: 1, name: "a", offset: time.Millisecond},
+		testSpan{id: 3, parent: 2, name: "a", offset: 2 * time.Millisecond},
+	)
+	require.NotPanics(t, func() {
+		New(1, 0, 1).Trim(td)
+	})
+	// The first span is representative, keeping its parent along with it.
+	assert.Equal(t, map[byte]int64{1: 0, 2: 1}, spansOf(td))
+}
+
+func TestTrimRemovesEmptyScopes(t *testing.T) {
+	td := newTrace("frontend",
+		testSpan{id: 1, name: "GET /"},
+		testSpan{id: 2, parent: 1, name: "SELECT"},
+	)
+	other := newTrace("frontend",
+		testSpan{id: 3, parent: 1, name: "SELECT", offset: time.Millisecond},
+	)
+	other.ResourceSpans().MoveAndAppendTo(td.ResourceSpans())
+
+	assert.Equal(t, int64(1), New(1, 0, 1).Trim(td))
+	assert.Equal(t, 1, td.ResourceSpans().Len())
+}
@@ -0,0 +1,100 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/cache"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+// newLateSpanSummaries returns the cache of the summaries of the traces not sampled.
+func newLateSpanSummaries(cfg Config) (cache.Cache[*sampling.TraceSummary], error) {
+	size := cfg.LateSpanReevaluation.CacheSize
+	if size <= 0 {
+		size = cfg.DecisionCache.NonSampledCacheSize
+	}
+	if size <= 0 {
+		size = int(cfg.NumTraces)
+	}
+	return cache.NewLRUDecisionCache[*sampling.TraceSummary](size, cache.WithTTL(cfg.DecisionCache.TTL))
+}
+
+// summarizeNotSampledTrace keeps the summary of a trace not sampled, to re-evaluate it when late spans are received.
+func (tsp *tailSamplingSpanProcessor) summarizeNotSampledTrace(id pcommon.TraceID, td ptrace.Traces) {
+	summary := sampling.NewTraceSummary(td)
+
+	tsp.lateSpanSummariesMux.Lock()
+	defer tsp.lateSpanSummariesMux.Unlock()
+	tsp.lateSpanSummaries.Put(id, summary)
+}
+
+// upgradeNotSampledTrace adds late spans to the summary of a trace not sampled, and evaluates it again. If a policy
+// samples it, the trace is sampled from then on and the late spans are released, in which case it returns true. The
+// trace is nil if it isn't in memory anymore.
+func (tsp *tailSamplingSpanProcessor) upgradeNotSampledTrace(id pcommon.TraceID, trace *sampling.TraceData, resourceSpans ptrace.ResourceSpans, spans []spanAndScope) bool {
+	if tsp.lateSpanSummaries == nil {
+		return false
+	}
+
+	tsp.lateSpanSummariesMux.Lock()
+	summary, ok := tsp.lateSpanSummaries.Get(id)
+	if !ok {
+		tsp.lateSpanSummariesMux.Unlock()
+		return false
+	}
+	for _, s := range spans {
+		summary.Add(*s.span)
+	}
+	sampledBy := tsp.evaluateSummary(summary)
+	if sampledBy == nil {
+		tsp.lateSpanSummariesMux.Unlock()
+		return false
+	}
+	tsp.lateSpanSummaries.Delete(id)
+	tsp.lateSpanSummariesMux.Unlock()
+
+	tsp.logger.Debug("Late spans sampled a trace not sampled", zap.Stringer("id", id), zap.String("policy", sampledBy.name))
+	tsp.nonSampledIDCache.Delete(id)
+	if trace != nil {
+		trace.Lock()
+		trace.FinalDecision = sampling.Sampled
+		trace.SampledBy = sampledBy.name
+		trace.Unlock()
+	}
+	tsp.telemetry.ProcessorTailSamplingLateSpanUpgrades.Add(tsp.ctx, 1, sampledBy.attribute)
+
+	traceTd := ptrace.NewTraces()
+	appendToTraces(traceTd, resourceSpans, spans)
+	tsp.recordDecisionOnSpans(traceTd, sampledBy.name, reportedSampling{})
+	tsp.releaseSampledTrace(tsp.ctx, id, traceTd)
+	return true
+}
+
+// evaluateSummary returns the first policy sampling the summary of a trace, or nil if none does. The policies unable to
+// evaluate summaries are considered to keep their decision of not sampling the trace. In the priority mode, the first
+// policy not abstaining decides on the summary, as it did on the trace. The all mode isn't supported.
+func (tsp *tailSamplingSpanProcessor) evaluateSummary(summary *sampling.TraceSummary) *policy {
+	// The policies may be replaced by the decision ticks meanwhile.
+	tsp.setPolicyMux.Lock()
+	policies := tsp.policies
+	tsp.setPolicyMux.Unlock()
+
+	for _, p := range policies {
+		if p.shadow {
+			continue
+		}
+		if evaluator, ok := p.evaluator.(sampling.SummaryEvaluator); ok && evaluator.EvaluateSummary(summary) == sampling.Sampled {
+			return p
+		}
+		if tsp.decisionMode == DecisionModePriority && !p.abstainOnNoMatch {
+			// The policy decides not to sample the trace, whatever the policies of lower priority decide.
+			return nil
+		}
+	}
+	return nil
+}
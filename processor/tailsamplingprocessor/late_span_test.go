@@ -0,0 +1,208 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func TestLateSpanReevaluation(t *testing.T) {
+	tests := []struct {
+		name                 string
+		reevaluation         LateSpanReevaluationCfg
+		decisionCache        DecisionCacheConfig
+		dropped              bool
+		lateStatusCode       ptrace.StatusCode
+		expectedSpans        int
+		expectedUpgradeCount int64
+	}{
+		{
+			name:                 "trace in memory",
+			reevaluation:         LateSpanReevaluationCfg{Enabled: true},
+			lateStatusCode:       ptrace.StatusCodeError,
+			expectedSpans:        2,
+			expectedUpgradeCount: 1,
+		},
+		{
+			name:                 "trace in the non-sampled cache",
+			reevaluation:         LateSpanReevaluationCfg{Enabled: true},
+			decisionCache:        DecisionCacheConfig{SampledCacheSize: 10, NonSampledCacheSize: 10},
+			lateStatusCode:       ptrace.StatusCodeError,
+			expectedSpans:        2,
+			expectedUpgradeCount: 1,
+		},
+		{
+			name:           "late span not sampled",
+			reevaluation:   LateSpanReevaluationCfg{Enabled: true},
+			lateStatusCode: ptrace.StatusCodeOk,
+		},
+		{
+			name:           "trace dropped",
+			reevaluation:   LateSpanReevaluationCfg{Enabled: true},
+			dropped:        true,
+			lateStatusCode: ptrace.StatusCodeError,
+		},
+		{
+			name:           "disabled",
+			lateStatusCode: ptrace.StatusCodeError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupTestTelemetry()
+			sink := new(consumertest.TracesSink)
+			statusCode, err := sampling.NewStatusCodeFilter(componenttest.NewNopTelemetrySettings(), []string{"ERROR"})
+			require.NoError(t, err)
+			dropDecision := sampling.NotSampled
+			if tt.dropped {
+				dropDecision = sampling.Dropped
+			}
+			cfg := Config{
+				DecisionWait:         defaultTestDecisionWait,
+				NumTraces:            defaultNumTraces,
+				DecisionCache:        tt.decisionCache,
+				LateSpanReevaluation: tt.reevaluation,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{
+						{name: "drop-policy", evaluator: &mockPolicyEvaluator{NextDecision: dropDecision}, attribute: metric.WithAttributes(attribute.String("policy", "drop-policy")), drop: true},
+						{name: "errors", evaluator: statusCode, attribute: metric.WithAttributes(attribute.String("policy", "errors"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), s.newSettings(), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			traceID := uInt64ToTraceID(1)
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithStatus(traceID, 1, ptrace.StatusCodeOk)))
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.policyTicker.OnTick() // the first tick always gets an empty batch
+			tsp.policyTicker.OnTick()
+			require.Zero(t, sink.SpanCount())
+
+			// The late span upgrades the trace, then the next one inherits the decision.
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithStatus(traceID, 2, tt.lateStatusCode)))
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithStatus(traceID, 3, ptrace.StatusCodeOk)))
+			assert.Equal(t, tt.expectedSpans, sink.SpanCount())
+
+			if tt.expectedUpgradeCount == 0 {
+				return
+			}
+			m := metricdata.Metrics{
+				Name:        "otelcol_processor_tail_sampling_late_span_upgrades",
+				Description: "Count of traces not sampled, then sampled when re-evaluated with their late spans.",
+				Unit:        "{traces}",
+				Data: metricdata.Sum[int64]{
+					IsMonotonic: true,
+					Temporality: metricdata.CumulativeTemporality,
+					DataPoints: []metricdata.DataPoint[int64]{
+						{Attributes: attribute.NewSet(attribute.String("policy", "errors")), Value: tt.expectedUpgradeCount},
+					},
+				},
+			}
+			md := metricdata.ResourceMetrics{}
+			require.NoError(t, s.reader.Collect(context.Background(), &md))
+			got := s.getMetric(m.Name, md)
+			metricdatatest.AssertEqual(t, m, got, metricdatatest.IgnoreTimestamp())
+		})
+	}
+}
+
+func TestLateSpanReevaluationDecisionModes(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          DecisionMode
+		firstAbstains bool
+		expectedSpans int
+	}{
+		{
+			name:          "first match",
+			mode:          DecisionModeFirstMatch,
+			expectedSpans: 2,
+		},
+		{
+			name:          "priority with a policy of higher priority deciding",
+			mode:          DecisionModePriority,
+			expectedSpans: 0,
+		},
+		{
+			name:          "priority with a policy of higher priority abstaining",
+			mode:          DecisionModePriority,
+			firstAbstains: true,
+			expectedSpans: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := new(consumertest.TracesSink)
+			statusCode, err := sampling.NewStatusCodeFilter(componenttest.NewNopTelemetrySettings(), []string{"ERROR"})
+			require.NoError(t, err)
+			cfg := Config{
+				DecisionWait:         defaultTestDecisionWait,
+				NumTraces:            defaultNumTraces,
+				DecisionMode:         tt.mode,
+				LateSpanReevaluation: LateSpanReevaluationCfg{Enabled: true},
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{
+						{
+							name:             "not-sampling",
+							evaluator:        &mockPolicyEvaluator{NextDecision: sampling.NotSampled},
+							attribute:        metric.WithAttributes(attribute.String("policy", "not-sampling")),
+							priority:         10,
+							abstainOnNoMatch: tt.firstAbstains,
+						},
+						{name: "errors", evaluator: statusCode, attribute: metric.WithAttributes(attribute.String("policy", "errors"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			traceID := uInt64ToTraceID(1)
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithStatus(traceID, 1, ptrace.StatusCodeOk)))
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.policyTicker.OnTick()
+			tsp.policyTicker.OnTick()
+			require.Zero(t, sink.SpanCount())
+
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithStatus(traceID, 2, ptrace.StatusCodeError)))
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithStatus(traceID, 3, ptrace.StatusCodeOk)))
+			assert.Equal(t, tt.expectedSpans, sink.SpanCount())
+		})
+	}
+}
+
+func tracesWithStatus(traceID pcommon.TraceID, spanID uint64, code ptrace.StatusCode) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(uInt64ToSpanID(spanID))
+	span.Status().SetCode(code)
+	return traces
+}
@@ -0,0 +1,154 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+// logAndScope a structure for holding information about a log record and its instrumentation scope.
+type logAndScope struct {
+	logRecord            *plog.LogRecord
+	instrumentationScope *pcommon.InstrumentationScope
+}
+
+// ConsumeLogs is required by the processor.Logs interface. Log records are released or dropped along with the spans of
+// their trace, once the decision on the trace is made. Log records without a trace ID are released right away.
+func (tsp *tailSamplingSpanProcessor) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	uncorrelated := plog.NewLogs()
+	resourceLogs := ld.ResourceLogs()
+	for i := 0; i < resourceLogs.Len(); i++ {
+		tsp.processLogs(resourceLogs.At(i), uncorrelated)
+	}
+	if uncorrelated.ResourceLogs().Len() == 0 {
+		return nil
+	}
+	return tsp.logsConsumer.ConsumeLogs(ctx, uncorrelated)
+}
+
+func groupLogsByTraceKey(resourceLogs plog.ResourceLogs) map[pcommon.TraceID][]logAndScope {
+	idToLogs := make(map[pcommon.TraceID][]logAndScope)
+	ills := resourceLogs.ScopeLogs()
+	for j := 0; j < ills.Len(); j++ {
+		scope := ills.At(j)
+		logRecords := scope.LogRecords()
+		is := scope.Scope()
+		for k := 0; k < logRecords.Len(); k++ {
+			logRecord := logRecords.At(k)
+			key := logRecord.TraceID()
+			idToLogs[key] = append(idToLogs[key], logAndScope{
+				logRecord:            &logRecord,
+				instrumentationScope: &is,
+			})
+		}
+	}
+	return idToLogs
+}
+
+// processLogs buffers the log records of the traces pending a decision, and releases or drops the others according to
+// the decision on their trace. Log records without a trace ID are added to the uncorrelated ones.
+func (tsp *tailSamplingSpanProcessor) processLogs(resourceLogs plog.ResourceLogs, uncorrelated plog.Logs) {
+	currTime := time.Now()
+
+	var newTraceIDs int64
+	for id, logs := range groupLogsByTraceKey(resourceLogs) {
+		if id.IsEmpty() {
+			appendToLogs(uncorrelated, resourceLogs, logs)
+			continue
+		}
+		if tsp.lookupDecisionCache(tsp.sampledIDCache, id, attrSampledTrue) {
+			logsLd := plog.NewLogs()
+			appendToLogs(logsLd, resourceLogs, logs)
+			tsp.releaseSampledLogs(tsp.ctx, logsLd)
+			continue
+		}
+		if tsp.lookupDecisionCache(tsp.nonSampledIDCache, id, attrSampledFalse) {
+			tsp.telemetry.ProcessorTailSamplingCountLogRecordsSampled.Add(tsp.ctx, int64(len(logs)), attrSampledFalse)
+			continue
+		}
+
+		d, loaded := tsp.idToTrace.Load(id)
+		if !loaded && tsp.spill != nil && tsp.spill.Contains(id) {
+			// Only spans are spilled, the log records of a spilled trace are dropped.
+			tsp.logger.Debug("Dropping log records of a spilled trace", zap.Stringer("id", id))
+			tsp.telemetry.ProcessorTailSamplingCountLogRecordsSampled.Add(tsp.ctx, int64(len(logs)), attrSampledFalse)
+			continue
+		}
+		var actualData *sampling.TraceData
+		if loaded {
+			actualData = d.(*sampling.TraceData)
+		} else {
+			// The log records may be received before the spans of their trace, whose decision starts with them.
+			var stored bool
+			if actualData, stored = tsp.storeTrace(id, currTime, 0); stored {
+				newTraceIDs++
+			}
+		}
+
+		actualData.Lock()
+		finalDecision := actualData.FinalDecision
+		if finalDecision == sampling.Unspecified {
+			if actualData.ReceivedLogs == (plog.Logs{}) {
+				actualData.ReceivedLogs = plog.NewLogs()
+			}
+			appendToLogs(actualData.ReceivedLogs, resourceLogs, logs)
+			actualData.Unlock()
+			continue
+		}
+		actualData.Unlock()
+
+		switch finalDecision {
+		case sampling.Sampled:
+			logsLd := plog.NewLogs()
+			appendToLogs(logsLd, resourceLogs, logs)
+			tsp.releaseSampledLogs(tsp.ctx, logsLd)
+		case sampling.NotSampled:
+			tsp.telemetry.ProcessorTailSamplingCountLogRecordsSampled.Add(tsp.ctx, int64(len(logs)), attrSampledFalse)
+		default:
+			tsp.logger.Warn("Unexpected sampling decision", zap.Int("decision", int(finalDecision)))
+		}
+	}
+
+	tsp.telemetry.ProcessorTailSamplingNewTraceIDReceived.Add(tsp.ctx, newTraceIDs)
+}
+
+// releaseSampledLogs sends the log records of a sampled trace to the next logs consumer.
+func (tsp *tailSamplingSpanProcessor) releaseSampledLogs(ctx context.Context, ld plog.Logs) {
+	tsp.telemetry.ProcessorTailSamplingCountLogRecordsSampled.Add(tsp.ctx, int64(ld.LogRecordCount()), attrSampledTrue)
+	if err := tsp.logsConsumer.ConsumeLogs(ctx, ld); err != nil {
+		tsp.logger.Warn(
+			"Error sending log records to destination",
+			zap.Error(err))
+	}
+}
+
+// releaseNotSampledLogs drops the log records of a trace not sampled.
+func (tsp *tailSamplingSpanProcessor) releaseNotSampledLogs(ld plog.Logs) {
+	tsp.telemetry.ProcessorTailSamplingCountLogRecordsSampled.Add(tsp.ctx, int64(ld.LogRecordCount()), attrSampledFalse)
+}
+
+func appendToLogs(dest plog.Logs, rls plog.ResourceLogs, logAndScopes []logAndScope) {
+	rl := dest.ResourceLogs().AppendEmpty()
+	rls.Resource().CopyTo(rl.Resource())
+	rl.SetSchemaUrl(rls.SchemaUrl())
+
+	scopePointerToNewScope := make(map[*pcommon.InstrumentationScope]*plog.ScopeLogs)
+	for _, logAndScope := range logAndScopes {
+		scope, ok := scopePointerToNewScope[logAndScope.instrumentationScope]
+		if !ok {
+			sl := rl.ScopeLogs().AppendEmpty()
+			logAndScope.instrumentationScope.CopyTo(sl.Scope())
+			scopePointerToNewScope[logAndScope.instrumentationScope] = &sl
+			scope = &sl
+		}
+		logAndScope.logRecord.CopyTo(scope.LogRecords().AppendEmpty())
+	}
+}
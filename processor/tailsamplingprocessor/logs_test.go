@@ -0,0 +1,322 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func TestLogsFollowTraceDecision(t *testing.T) {
+	tests := []struct {
+		name             string
+		decision         sampling.Decision
+		decisionCache    DecisionCacheConfig
+		expectedSpans    int
+		expectedLogs     int
+		expectedSampled  int64
+		expectedDropped  int64
+		logsBeforeSpans  bool
+		logsAfterRelease bool
+	}{
+		{
+			name:            "sampled",
+			decision:        sampling.Sampled,
+			expectedSpans:   1,
+			expectedLogs:    2,
+			expectedSampled: 2,
+		},
+		{
+			name:            "not sampled",
+			decision:        sampling.NotSampled,
+			expectedDropped: 2,
+		},
+		{
+			name:            "log records before spans",
+			decision:        sampling.Sampled,
+			logsBeforeSpans: true,
+			expectedSpans:   1,
+			expectedLogs:    2,
+			expectedSampled: 2,
+		},
+		{
+			name:             "sampled log records after the decision",
+			decision:         sampling.Sampled,
+			logsAfterRelease: true,
+			expectedSpans:    1,
+			expectedLogs:     2,
+			expectedSampled:  2,
+		},
+		{
+			name:             "sampled log records in the decision cache",
+			decision:         sampling.Sampled,
+			decisionCache:    DecisionCacheConfig{SampledCacheSize: 10},
+			logsAfterRelease: true,
+			expectedSpans:    1,
+			expectedLogs:     2,
+			expectedSampled:  2,
+		},
+		{
+			name:             "not sampled log records in the decision cache",
+			decision:         sampling.NotSampled,
+			decisionCache:    DecisionCacheConfig{NonSampledCacheSize: 10},
+			logsAfterRelease: true,
+			expectedDropped:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupTestTelemetry()
+			traceSink := new(consumertest.TracesSink)
+			logSink := new(consumertest.LogsSink)
+			mpe := &mockPolicyEvaluator{NextDecision: tt.decision}
+			cfg := Config{
+				DecisionWait:  defaultTestDecisionWait,
+				NumTraces:     defaultNumTraces,
+				DecisionCache: tt.decisionCache,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))}}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), s.newSettings(), traceSink, cfg)
+			require.NoError(t, err)
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.logsConsumer = logSink
+			require.NoError(t, tsp.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, tsp.Shutdown(context.Background()))
+			}()
+
+			traceID := uInt64ToTraceID(1)
+			if tt.logsBeforeSpans {
+				require.NoError(t, tsp.ConsumeLogs(context.Background(), logsWithTraceID(traceID, 2)))
+			}
+			require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(traceID)))
+			if !tt.logsBeforeSpans && !tt.logsAfterRelease {
+				require.NoError(t, tsp.ConsumeLogs(context.Background(), logsWithTraceID(traceID, 2)))
+			}
+
+			tsp.policyTicker.OnTick()
+			tsp.policyTicker.OnTick()
+			if tt.logsAfterRelease {
+				require.NoError(t, tsp.ConsumeLogs(context.Background(), logsWithTraceID(traceID, 2)))
+			}
+
+			assert.Equal(t, 1, mpe.EvaluationCount)
+			assert.Equal(t, tt.expectedSpans, traceSink.SpanCount())
+			assert.Equal(t, tt.expectedLogs, logSink.LogRecordCount())
+
+			var md metricdata.ResourceMetrics
+			require.NoError(t, s.reader.Collect(context.Background(), &md))
+			var dataPoints []metricdata.DataPoint[int64]
+			if tt.expectedSampled > 0 {
+				dataPoints = append(dataPoints, metricdata.DataPoint[int64]{
+					Attributes: attribute.NewSet(attribute.String("sampled", "true")),
+					Value:      tt.expectedSampled,
+				})
+			}
+			if tt.expectedDropped > 0 {
+				dataPoints = append(dataPoints, metricdata.DataPoint[int64]{
+					Attributes: attribute.NewSet(attribute.String("sampled", "false")),
+					Value:      tt.expectedDropped,
+				})
+			}
+			expected := metricdata.Metrics{
+				Name:        "otelcol_processor_tail_sampling_count_log_records_sampled",
+				Description: "Count of log records that were sampled or not along with their trace",
+				Unit:        "{records}",
+				Data: metricdata.Sum[int64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints:  dataPoints,
+				},
+			}
+			metricdatatest.AssertEqual(t, expected, s.getMetric(expected.Name, md), metricdatatest.IgnoreTimestamp())
+		})
+	}
+}
+
+func TestLogsOnlyTrace(t *testing.T) {
+	traceSink := new(consumertest.TracesSink)
+	logSink := new(consumertest.LogsSink)
+	mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))}}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), traceSink, cfg)
+	require.NoError(t, err)
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.logsConsumer = logSink
+	require.NoError(t, tsp.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+
+	require.NoError(t, tsp.ConsumeLogs(context.Background(), logsWithTraceID(uInt64ToTraceID(1), 3)))
+	tsp.policyTicker.OnTick()
+	assert.Equal(t, 0, logSink.LogRecordCount())
+	tsp.policyTicker.OnTick()
+
+	// The trace is decided without spans.
+	assert.Equal(t, 1, mpe.EvaluationCount)
+	assert.Equal(t, 3, logSink.LogRecordCount())
+	assert.Empty(t, traceSink.AllTraces())
+}
+
+func TestUncorrelatedLogsAreReleased(t *testing.T) {
+	logSink := new(consumertest.LogsSink)
+	mpe := &mockPolicyEvaluator{NextDecision: sampling.NotSampled}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))}}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+	require.NoError(t, err)
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.logsConsumer = logSink
+	require.NoError(t, tsp.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+
+	ld := logsWithTraceID(pcommon.NewTraceIDEmpty(), 2)
+	logsWithTraceID(uInt64ToTraceID(1), 1).ResourceLogs().MoveAndAppendTo(ld.ResourceLogs())
+	require.NoError(t, tsp.ConsumeLogs(context.Background(), ld))
+
+	// Only the log records without a trace ID are released right away.
+	assert.Equal(t, 2, logSink.LogRecordCount())
+	_, ok := tsp.idToTrace.Load(uInt64ToTraceID(1))
+	assert.True(t, ok)
+}
+
+func TestTracesAndLogsShareProcessor(t *testing.T) {
+	cm, err := confmaptest.LoadConf(filepath.Join("testdata", "tail_sampling_config.yaml"))
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	sub, err := cm.Sub(component.NewIDWithName(metadata.Type, "").String())
+	require.NoError(t, err)
+	require.NoError(t, sub.Unmarshal(cfg))
+	// Log records can't be sampled along with traces routed to peers.
+	cfg.(*Config).Routing = RoutingCfg{}
+	// The nop host has no extension to add the debug pages to.
+	cfg.(*Config).Debug = DebugCfg{}
+
+	params := processortest.NewNopSettings(metadata.Type)
+	tp, err := factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	lp, err := factory.CreateLogs(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.Same(t, tp.(*tracesProcessor).tsp, lp.(*logsProcessor).tsp)
+
+	host := componenttest.NewNopHost()
+	require.NoError(t, tp.Start(context.Background(), host))
+	require.NoError(t, lp.Start(context.Background(), host))
+	require.NoError(t, lp.Shutdown(context.Background()))
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func TestSeveralTracesPipelines(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.DecisionWait = 10 * time.Millisecond
+	cfg.PolicyCfgs = testPolicy
+	cfg.Options = []Option{withTickerFrequency(10 * time.Millisecond)}
+
+	params := processortest.NewNopSettings(metadata.Type)
+	sinkA, sinkB := new(consumertest.TracesSink), new(consumertest.TracesSink)
+	tpA, err := factory.CreateTraces(context.Background(), params, cfg, sinkA)
+	require.NoError(t, err)
+	tpB, err := factory.CreateTraces(context.Background(), params, cfg, sinkB)
+	require.NoError(t, err)
+
+	host := componenttest.NewNopHost()
+	require.NoError(t, tpA.Start(context.Background(), host))
+	require.NoError(t, tpB.Start(context.Background(), host))
+	defer func() {
+		require.NoError(t, tpA.Shutdown(context.Background()))
+		require.NoError(t, tpB.Shutdown(context.Background()))
+	}()
+
+	// Each pipeline releases its spans to its own consumer.
+	require.NoError(t, tpA.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(1))))
+	require.NoError(t, tpB.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(2))))
+	require.Eventually(t, func() bool {
+		return sinkA.SpanCount() == 1 && sinkB.SpanCount() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uInt64ToTraceID(1), sinkA.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+	assert.Equal(t, uInt64ToTraceID(2), sinkB.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+
+	// Logs can't tell which traces pipeline they follow.
+	_, err = factory.CreateLogs(context.Background(), params, cfg, consumertest.NewNop())
+	require.ErrorIs(t, err, errSharedWithSeveralTracesPipelines)
+}
+
+func TestSeveralLogsPipelines(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	params := processortest.NewNopSettings(metadata.Type)
+
+	lp, err := factory.CreateLogs(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	_, err = factory.CreateLogs(context.Background(), params, cfg, consumertest.NewNop())
+	require.ErrorIs(t, err, errSeveralLogsPipelines)
+
+	// A second traces pipeline can't be used along with the logs pipeline either.
+	tp, err := factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	_, err = factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.ErrorIs(t, err, errSharedWithSeveralTracesPipelines)
+
+	require.NoError(t, lp.Shutdown(context.Background()))
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func TestLogsWithRouting(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Routing = RoutingCfg{Self: "localhost:4317", Static: StaticRoutingCfg{Endpoints: []string{"localhost:4317"}}}
+
+	_, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(metadata.Type), cfg, consumertest.NewNop())
+	require.EqualError(t, err, "log records can't be sampled when routing spans to peers")
+}
+
+func logsWithTraceID(traceID pcommon.TraceID, count int) plog.Logs {
+	ld := plog.NewLogs()
+	logRecords := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for i := 0; i < count; i++ {
+		logRecords.AppendEmpty().SetTraceID(traceID)
+	}
+	return ld
+}
@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/policysource"
+)
+
+const (
+	defaultPolicySourceInterval = 30 * time.Second
+	defaultPolicySourceTimeout  = 10 * time.Second
+)
+
+var (
+	attrLoadSuccess = metric.WithAttributes(attribute.String("result", "success"))
+	attrLoadFailure = metric.WithAttributes(attribute.String("result", "failure"))
+)
+
+// policyDocument is the content of a policy source.
+type policyDocument struct {
+	// Version identifies the policies, it is reported by the policy_source_version metric.
+	Version int64 `mapstructure:"version"`
+	// Policies replace the policies of the processor.
+	Policies []PolicyCfg `mapstructure:"policies"`
+}
+
+// newPolicySource returns the watcher of the policy source, or nil if there's none.
+func (tsp *tailSamplingSpanProcessor) newPolicySource(cfg PolicySourceCfg) (*policysource.Watcher, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultPolicySourceTimeout
+	}
+
+	var fetcher policysource.Fetcher
+	switch {
+	case cfg.File != "" && cfg.URL != "":
+		return nil, errors.New("the policy source must be either a file or a URL, not both")
+	case cfg.File != "":
+		fetcher = policysource.NewFileFetcher(cfg.File)
+	case cfg.URL != "":
+		fetcher = policysource.NewHTTPFetcher(cfg.URL, &http.Client{Timeout: timeout})
+	default:
+		return nil, nil
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultPolicySourceInterval
+	}
+	return policysource.NewWatcher(fetcher, interval, timeout, tsp.onPolicySourceChange), nil
+}
+
+// onPolicySourceChange validates the policies of the policy source, and sets them to be loaded on the next tick. Their
+// version is recorded once they are loaded, see loadPendingSamplingPolicy.
+func (tsp *tailSamplingSpanProcessor) onPolicySourceChange(content []byte, err error) {
+	var doc policyDocument
+	var policies []*policy
+	if err == nil {
+		doc, policies, err = tsp.parsePolicyDocument(content)
+	}
+	if err != nil {
+		tsp.logger.Error("Failed to load the sampling policies from the policy source, keeping the current ones", zap.Error(err))
+		tsp.telemetry.ProcessorTailSamplingPolicySourceLoads.Add(tsp.ctx, 1, attrLoadFailure)
+		return
+	}
+
+	tsp.logger.Info("Loaded the sampling policies from the policy source", zap.Int64("version", doc.Version), zap.Int("policies.len", len(doc.Policies)))
+	tsp.setPendingPolicies(policies, doc.Version)
+	tsp.telemetry.ProcessorTailSamplingPolicySourceLoads.Add(tsp.ctx, 1, attrLoadSuccess)
+}
+
+// parsePolicyDocument parses the content of a policy source, and returns its policies, checked the same way as the ones
+// of the configuration. As the decision stages are only created at start, the decision_wait of each policy must be the
+// one of a stage, or of the processor.
+func (tsp *tailSamplingSpanProcessor) parsePolicyDocument(content []byte) (policyDocument, []*policy, error) {
+	var doc policyDocument
+	retrieved, err := confmap.NewRetrievedFromYAML(content)
+	if err != nil {
+		return doc, nil, err
+	}
+	conf, err := retrieved.AsConf()
+	if err != nil {
+		return doc, nil, err
+	}
+	if err = conf.Unmarshal(&doc); err != nil {
+		return doc, nil, err
+	}
+	if len(doc.Policies) == 0 {
+		return doc, nil, errors.New("the policy source has no policies")
+	}
+	if err = validatePolicies(doc.Policies); err != nil {
+		return doc, nil, err
+	}
+	for _, cfg := range doc.Policies {
+		if cfg.DecisionWait > 0 && cfg.DecisionWait < tsp.decisionWait && !tsp.hasDecisionStage(cfg.DecisionWait) {
+			return doc, nil, fmt.Errorf("the decision_wait of policy %q must be the one of a policy of the configuration, or of the processor", cfg.Name)
+		}
+	}
+	policies, err := tsp.newPolicies(doc.Policies)
+	if err != nil {
+		return doc, nil, err
+	}
+	return doc, policies, nil
+}
@@ -0,0 +1,217 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+)
+
+const testPolicyDocument = `
+version: 3
+policies:
+  - name: errors
+    type: status_code
+    status_code: {status_codes: [ERROR]}
+  - name: slow
+    type: latency
+    latency: {threshold_ms: 5000}
+`
+
+func TestPolicySource(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		expectedNames   []string
+		expectedResult  string
+		expectedVersion int64
+	}{
+		{
+			name:            "valid",
+			content:         testPolicyDocument,
+			expectedNames:   []string{"errors", "slow"},
+			expectedResult:  "success",
+			expectedVersion: 3,
+		},
+		{
+			name:           "invalid policy",
+			content:        "policies: [{name: errors, type: status_code, status_code: {status_codes: [FAILED]}}]",
+			expectedNames:  []string{"always"},
+			expectedResult: "failure",
+		},
+		{
+			name:           "unknown field",
+			content:        "policies: [{name: always, type: always_sample, unknown: true}]",
+			expectedNames:  []string{"always"},
+			expectedResult: "failure",
+		},
+		{
+			name:           "decision_wait without a stage",
+			content:        "policies: [{name: errors, type: status_code, decision_wait: 2s, status_code: {status_codes: [ERROR]}}]",
+			expectedNames:  []string{"always"},
+			expectedResult: "failure",
+		},
+		{
+			name:           "no policies",
+			content:        "version: 4",
+			expectedNames:  []string{"always"},
+			expectedResult: "failure",
+		},
+		{
+			name:           "not YAML",
+			content:        "{",
+			expectedNames:  []string{"always"},
+			expectedResult: "failure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policies.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			s := setupTestTelemetry()
+			tsp := newPolicySourceTestProcessor(t, &s, PolicySourceCfg{File: path, Interval: time.Hour})
+			tsp.policyTicker.OnTick()
+			assert.Equal(t, tt.expectedNames, policyNames(tsp))
+
+			md := metricdata.ResourceMetrics{}
+			require.NoError(t, s.reader.Collect(context.Background(), &md))
+			loads := metricdata.Metrics{
+				Name:        "otelcol_processor_tail_sampling_policy_source_loads",
+				Description: "Count of loads of the policies from the policy_source, by result.",
+				Unit:        "{loads}",
+				Data: metricdata.Sum[int64]{
+					IsMonotonic: true,
+					Temporality: metricdata.CumulativeTemporality,
+					DataPoints: []metricdata.DataPoint[int64]{
+						{Attributes: attribute.NewSet(attribute.String("result", tt.expectedResult)), Value: 1},
+					},
+				},
+			}
+			metricdatatest.AssertEqual(t, loads, s.getMetric(loads.Name, md), metricdatatest.IgnoreTimestamp())
+
+			version := s.getMetric("otelcol_processor_tail_sampling_policy_source_version", md)
+			if tt.expectedResult == "failure" {
+				assert.Nil(t, version.Data)
+				return
+			}
+			metricdatatest.AssertEqual(t, metricdata.Metrics{
+				Name:        "otelcol_processor_tail_sampling_policy_source_version",
+				Description: "Version of the last policies loaded from the policy_source.",
+				Unit:        "1",
+				Data: metricdata.Gauge[int64]{
+					DataPoints: []metricdata.DataPoint[int64]{{Value: tt.expectedVersion}},
+				},
+			}, version, metricdatatest.IgnoreTimestamp(), metricdatatest.IgnoreExemplars())
+		})
+	}
+}
+
+func TestPolicySourceVersionRecordedWhenLoaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicyDocument), 0o600))
+	versionData := func(s *testTelemetry) metricdata.Aggregation {
+		md := metricdata.ResourceMetrics{}
+		require.NoError(t, s.reader.Collect(context.Background(), &md))
+		return s.getMetric("otelcol_processor_tail_sampling_policy_source_version", md).Data
+	}
+
+	// The version isn't recorded until the policies are loaded on the next tick.
+	s := setupTestTelemetry()
+	tsp := newPolicySourceTestProcessor(t, &s, PolicySourceCfg{File: path, Interval: time.Hour})
+	assert.Nil(t, versionData(&s))
+	tsp.policyTicker.OnTick()
+	assert.NotNil(t, versionData(&s))
+
+	// Nor at all when other policies replace them before.
+	s = setupTestTelemetry()
+	tsp = newPolicySourceTestProcessor(t, &s, PolicySourceCfg{File: path, Interval: time.Hour})
+	tsp.SetSamplingPolicy([]PolicyCfg{{sharedPolicyCfg: sharedPolicyCfg{Name: "all", Type: AlwaysSample}}})
+	tsp.policyTicker.OnTick()
+	assert.Equal(t, []string{"all"}, policyNames(tsp))
+	assert.Nil(t, versionData(&s))
+}
+
+func TestPolicySourceHTTP(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v3"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v3"`)
+		_, _ = w.Write([]byte(testPolicyDocument))
+	}))
+	defer server.Close()
+
+	tsp := newPolicySourceTestProcessor(t, &testTelemetry{}, PolicySourceCfg{URL: server.URL, Interval: time.Millisecond})
+	require.Eventually(t, func() bool {
+		return requests.Load() > 1
+	}, time.Second, time.Millisecond)
+	tsp.policyTicker.OnTick()
+	assert.Equal(t, []string{"errors", "slow"}, policyNames(tsp))
+}
+
+func TestPolicySourceConfigErrors(t *testing.T) {
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		PolicySource: PolicySourceCfg{File: "policies.yaml", URL: "http://localhost/policies.yaml"},
+	}
+	_, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+	require.ErrorContains(t, err, "either a file or a URL")
+}
+
+func newPolicySourceTestProcessor(t *testing.T, s *testTelemetry, source PolicySourceCfg) *tailSamplingSpanProcessor {
+	settings := processortest.NewNopSettings(metadata.Type)
+	if s.meterProvider != nil {
+		settings = s.newSettings()
+	}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		PolicyCfgs: []PolicyCfg{
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "always", Type: AlwaysSample}},
+		},
+		PolicySource: source,
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), settings, consumertest.NewNop(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+	})
+	return p.(*tailSamplingSpanProcessor)
+}
+
+func policyNames(tsp *tailSamplingSpanProcessor) []string {
+	tsp.setPolicyMux.Lock()
+	defer tsp.setPolicyMux.Unlock()
+	names := make([]string, 0, len(tsp.policies))
+	for _, p := range tsp.policies {
+		names = append(names, p.name)
+	}
+	return names
+}
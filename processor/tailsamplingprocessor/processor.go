@@ -4,10 +4,13 @@
 package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"sync"
@@ -15,8 +18,10 @@ import (
 	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configgrpc"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/processor"
 	"go.opentelemetry.io/otel/attribute"
@@ -27,8 +32,13 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/cache"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/idbatcher"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/policysource"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/routing"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spanmetrics"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spill"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/telemetry"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/trim"
 )
 
 // policy combines a sampling policy evaluator with the destinations to be
@@ -40,6 +50,28 @@ type policy struct {
 	evaluator sampling.PolicyEvaluator
 	// attribute to use in the telemetry to denote the policy.
 	attribute metric.MeasurementOption
+	// decisionWait is the time after which this policy can decide on a trace, zero meaning the decision_wait of the
+	// processor.
+	decisionWait time.Duration
+	// drop tells whether this is a drop policy, evaluated at every decision stage.
+	drop bool
+	// shadow tells whether this policy is only evaluated for its telemetry, without affecting the decision.
+	shadow bool
+	// priority orders the evaluation of the policies in the priority decision mode, higher first.
+	priority int
+	// abstainOnNoMatch tells whether this policy abstains instead of not sampling a trace.
+	abstainOnNoMatch bool
+	// trimmer trims the large traces sampled by this policy, nil if disabled.
+	trimmer *trim.Trimmer
+}
+
+// evaluate returns the decision of the policy on a trace, abstaining instead of not sampling it if configured so.
+func (p *policy) evaluate(ctx context.Context, id pcommon.TraceID, trace *sampling.TraceData) (sampling.Decision, error) {
+	decision, err := p.evaluator.Evaluate(ctx, id, trace)
+	if err == nil && decision == sampling.NotSampled && p.abstainOnNoMatch {
+		return sampling.Abstain, nil
+	}
+	return decision, err
 }
 
 // tailSamplingSpanProcessor handles the incoming trace data and uses the given sampling
@@ -52,12 +84,15 @@ type tailSamplingSpanProcessor struct {
 	logger    *zap.Logger
 
 	nextConsumer       consumer.Traces
+	logsConsumer       consumer.Logs
 	maxNumTraces       uint64
 	policies           []*policy
 	idToTrace          sync.Map
 	policyTicker       timeutils.TTicker
 	tickerFrequency    time.Duration
 	decisionBatcher    idbatcher.Batcher
+	decisionWait       time.Duration
+	decisionStages     []*decisionStage
 	sampledIDCache     cache.Cache[bool]
 	nonSampledIDCache  cache.Cache[bool]
 	deleteChan         chan pcommon.TraceID
@@ -65,7 +100,31 @@ type tailSamplingSpanProcessor struct {
 	recordPolicy       bool
 	setPolicyMux       sync.Mutex
 	pendingPolicy      []PolicyCfg
-	sampleOnFirstMatch bool
+	pendingPolicies    []*policy
+	pendingVersion     int64
+	decisionMode       DecisionMode
+	debugExtensionID   *component.ID
+	catalogMux         sync.Mutex
+	catalogs           []policyCatalog
+	recordDecision     RecordDecisionCfg
+	spill              *spill.Store
+	router             *routing.Router
+	routingClient      configgrpc.ClientConfig
+	policySource       *policysource.Watcher
+	earlyDecision      bool
+	earlyDecisionGrace time.Duration
+	completeTracesMux  sync.Mutex
+	// completeTraces holds the traces to be decided early, with the time they became complete.
+	completeTraces map[pcommon.TraceID]time.Time
+	// lateSpanSummaries holds the summaries of the traces not sampled, when late spans are re-evaluated.
+	lateSpanSummaries    cache.Cache[*sampling.TraceSummary]
+	lateSpanSummariesMux sync.Mutex
+	// spanMetrics aggregates the metrics of all the spans, when the processor is part of a metrics pipeline.
+	spanMetrics         *spanmetrics.Aggregator
+	spanMetricsInterval time.Duration
+	spanMetricsStop     chan struct{}
+	spanMetricsDone     chan struct{}
+	metricsConsumer     consumer.Metrics
 }
 
 // spanAndScope a structure for holding information about span and its instrumentation scope.
@@ -98,20 +157,9 @@ func newTracesProcessor(ctx context.Context, set processor.Settings, nextConsume
 	if err != nil {
 		return nil, err
 	}
-	nopCache := cache.NewNopDecisionCache[bool]()
-	sampledDecisions := nopCache
-	nonSampledDecisions := nopCache
-	if cfg.DecisionCache.SampledCacheSize > 0 {
-		sampledDecisions, err = cache.NewLRUDecisionCache[bool](cfg.DecisionCache.SampledCacheSize)
-		if err != nil {
-			return nil, err
-		}
-	}
-	if cfg.DecisionCache.NonSampledCacheSize > 0 {
-		nonSampledDecisions, err = cache.NewLRUDecisionCache[bool](cfg.DecisionCache.NonSampledCacheSize)
-		if err != nil {
-			return nil, err
-		}
+	decisionMode, err := newDecisionMode(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	tsp := &tailSamplingSpanProcessor{
@@ -120,12 +168,19 @@ func newTracesProcessor(ctx context.Context, set processor.Settings, nextConsume
 		telemetry:          telemetry,
 		nextConsumer:       nextConsumer,
 		maxNumTraces:       cfg.NumTraces,
-		sampledIDCache:     sampledDecisions,
-		nonSampledIDCache:  nonSampledDecisions,
+		decisionWait:       cfg.DecisionWait,
+		decisionMode:       decisionMode,
 		logger:             telemetrySettings.Logger,
 		numTracesOnMap:     &atomic.Uint64{},
 		deleteChan:         make(chan pcommon.TraceID, cfg.NumTraces),
-		sampleOnFirstMatch: cfg.SampleOnFirstMatch,
+		debugExtensionID:   cfg.Debug.Extension,
+		recordDecision:     cfg.RecordDecision,
+		earlyDecision:      cfg.EarlyDecision.Enabled,
+		earlyDecisionGrace: cfg.EarlyDecision.GracePeriod,
+		completeTraces:     map[pcommon.TraceID]time.Time{},
+	}
+	if tsp.earlyDecisionGrace <= 0 {
+		tsp.earlyDecisionGrace = defaultEarlyDecisionGracePeriod
 	}
 	tsp.policyTicker = &timeutils.PolicyTicker{OnTickFunc: tsp.samplingPolicyOnTick}
 
@@ -144,13 +199,58 @@ func newTracesProcessor(ctx context.Context, set processor.Settings, nextConsume
 		}
 	}
 
+	// Caches are created once the policies are known to be valid, as they may hold files open.
+	if tsp.sampledIDCache == nil {
+		tsp.sampledIDCache, err = tsp.newDecisionCache(cfg.DecisionCache, cfg.DecisionCache.SampledCacheSize, "sampled", attrSampledTrue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tsp.nonSampledIDCache == nil {
+		tsp.nonSampledIDCache, err = tsp.newDecisionCache(cfg.DecisionCache, cfg.DecisionCache.NonSampledCacheSize, "non_sampled", attrSampledFalse)
+		if err != nil {
+			return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache))
+		}
+	}
+
+	tsp.router, err = newRouter(tsp.logger, cfg.Routing)
+	if err != nil {
+		return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache))
+	}
+	tsp.routingClient = cfg.Routing.Client
+
+	tsp.spill, err = newSpillStore(cfg.Spill, set.ID)
+	if err != nil {
+		return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache))
+	}
+
+	if cfg.LateSpanReevaluation.Enabled {
+		tsp.lateSpanSummaries, err = newLateSpanSummaries(cfg)
+		if err != nil {
+			return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache), closeSpillStore(tsp.spill))
+		}
+	}
+
+	tsp.policySource, err = tsp.newPolicySource(cfg.PolicySource)
+	if err != nil {
+		return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache), closeSpillStore(tsp.spill))
+	}
+
+	if tsp.decisionStages == nil {
+		tsp.decisionStages, err = newDecisionStages(cfg)
+		if err != nil {
+			return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache), closeSpillStore(tsp.spill))
+		}
+	}
+
 	if tsp.decisionBatcher == nil {
 		// this will start a goroutine in the background, so we run it only if everything went
 		// well in creating the policies
 		numDecisionBatches := math.Max(1, cfg.DecisionWait.Seconds())
 		inBatcher, err := idbatcher.New(uint64(numDecisionBatches), cfg.ExpectedNewTracesPerSec, uint64(2*runtime.NumCPU()))
 		if err != nil {
-			return nil, err
+			stopDecisionStages(tsp.decisionStages)
+			return nil, errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache), closeSpillStore(tsp.spill))
 		}
 		tsp.decisionBatcher = inBatcher
 	}
@@ -158,6 +258,67 @@ func newTracesProcessor(ctx context.Context, set processor.Settings, nextConsume
 	return tsp, nil
 }
 
+// newDecisionMode returns the decision mode of the configuration.
+func newDecisionMode(cfg Config) (DecisionMode, error) {
+	mode := cfg.DecisionMode
+	switch mode {
+	case "":
+		mode = DecisionModeAny
+		if cfg.SampleOnFirstMatch {
+			mode = DecisionModeFirstMatch
+		}
+	case DecisionModeAny, DecisionModeAll, DecisionModeFirstMatch, DecisionModePriority:
+		if cfg.SampleOnFirstMatch && mode != DecisionModeFirstMatch {
+			return "", fmt.Errorf("sample_on_first_match can't be combined with the %q decision mode", mode)
+		}
+	default:
+		return "", fmt.Errorf("unknown decision mode %q", mode)
+	}
+
+	if mode == DecisionModeAll && cfg.LateSpanReevaluation.Enabled {
+		return "", fmt.Errorf("late span re-evaluation isn't supported by the %q decision mode", mode)
+	}
+	return mode, nil
+}
+
+// newDecisionCache returns a decision cache of the given size, as configured. The name identifies the cache among the
+// files of the disk decision caches, and attr among the metrics of the decision caches.
+func (tsp *tailSamplingSpanProcessor) newDecisionCache(cfg DecisionCacheConfig, size int, name string, attr metric.MeasurementOption) (cache.Cache[bool], error) {
+	if size <= 0 {
+		return cache.NewNopDecisionCache[bool](), nil
+	}
+
+	opts := []cache.Option{
+		cache.WithTTL(cfg.TTL),
+		cache.WithExpirationCallback(func() {
+			tsp.telemetry.ProcessorTailSamplingDecisionCacheExpirations.Add(tsp.ctx, 1, attr)
+		}),
+	}
+	switch cfg.Type {
+	case "", LRUDecisionCache:
+		return cache.NewLRUDecisionCache[bool](size, opts...)
+	case DiskDecisionCache:
+		if cfg.Disk.Directory == "" {
+			return nil, errors.New("a directory is required by the disk decision cache")
+		}
+		dir, err := instanceDirectory(cfg.Disk.Directory, tsp.set.ID)
+		if err != nil {
+			return nil, err
+		}
+		return cache.NewDiskDecisionCache(tsp.logger, filepath.Join(dir, name+"_decisions.log"), size, opts...)
+	default:
+		return nil, fmt.Errorf("unknown decision cache type %q", cfg.Type)
+	}
+}
+
+// closeDecisionCache closes the given decision cache, if it needs to be.
+func closeDecisionCache(c cache.Cache[bool]) error {
+	if closer, ok := c.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // withDecisionBatcher sets the batcher used to batch trace IDs for policy evaluation.
 func withDecisionBatcher(batcher idbatcher.Batcher) Option {
 	return func(tsp *tailSamplingSpanProcessor) {
@@ -169,6 +330,7 @@ func withDecisionBatcher(batcher idbatcher.Batcher) Option {
 func withPolicies(policies []*policy) Option {
 	return func(tsp *tailSamplingSpanProcessor) {
 		tsp.policies = policies
+		tsp.setTrajectoryCatalogs(policies)
 	}
 }
 
@@ -228,10 +390,28 @@ func getSharedPolicyEvaluator(settings component.TelemetrySettings, cfg *sharedP
 		return sampling.NewNumericAttributeFilter(settings, nafCfg.Key, &minValue, &maxValue, nafCfg.InvertMatch), nil
 	case Probabilistic:
 		pCfg := cfg.ProbabilisticCfg
-		return sampling.NewProbabilisticSampler(settings, pCfg.HashSalt, pCfg.SamplingPercentage), nil
+		switch sampling.SamplingMode(pCfg.Mode) {
+		case "", sampling.HashSeedMode:
+			return sampling.NewProbabilisticSampler(settings, pCfg.HashSalt, pCfg.SamplingPercentage), nil
+		case sampling.ConsistentMode:
+			return sampling.NewConsistentProbabilisticSampler(settings, pCfg.SamplingPercentage), nil
+		default:
+			return nil, fmt.Errorf("unknown sampling mode %q", pCfg.Mode)
+		}
 	case StratifiedProbabilistic:
-		pCfg := cfg.StratifiedProbabilisticCfg
-		return sampling.NewStratifiedProbabilisticSampler(settings, pCfg.HashSalt, pCfg.SamplingPercentage), nil
+		spCfg := cfg.StratifiedProbabilisticCfg
+		shape := sampling.TrajectoryShape{
+			NodeIdentity:  sampling.NodeIdentity(spCfg.Trajectory.NodeIdentity),
+			AttributeKeys: spCfg.Trajectory.AttributeKeys,
+			Expression:    spCfg.Trajectory.Expression,
+			MaxDepth:      spCfg.Trajectory.MaxDepth,
+		}
+		target := sampling.ThroughputTarget{
+			TracesPerSecond: spCfg.TargetTracesPerSecond,
+			SpansPerSecond:  spCfg.TargetSpansPerSecond,
+		}
+		return sampling.NewStratifiedProbabilisticSampler(settings, cfg.Name, spCfg.HashSalt, sampling.SamplingMode(spCfg.Mode), spCfg.SamplingPercentage, spCfg.Window,
+			spCfg.MaxTrajectories, spCfg.MinTracesPerTrajectory, target, shape, sampling.MonotonicClock{})
 	case StringAttribute:
 		safCfg := cfg.StringAttributeCfg
 		return sampling.NewStringAttributeFilter(settings, safCfg.Key, safCfg.Values, safCfg.EnabledRegexMatching, safCfg.CacheMaxSize, safCfg.InvertMatch), nil
@@ -253,6 +433,27 @@ func getSharedPolicyEvaluator(settings component.TelemetrySettings, cfg *sharedP
 	case OTTLCondition:
 		ottlfCfg := cfg.OTTLConditionCfg
 		return sampling.NewOTTLConditionFilter(settings, ottlfCfg.SpanConditions, ottlfCfg.SpanEventConditions, ottlfCfg.ErrorMode)
+	case ErrorOrigin:
+		eoCfg := cfg.ErrorOriginCfg
+		return sampling.NewErrorOrigin(settings, eoCfg.Services, eoCfg.Operations, eoCfg.TracesPerSecond, sampling.MonotonicClock{}), nil
+	case CriticalPath:
+		cpCfg := cfg.CriticalPathCfg
+		return sampling.NewCriticalPath(settings, cpCfg.Services, cpCfg.Operations, cpCfg.ThresholdMs, cpCfg.Percentage)
+	case LatencyAnomaly:
+		laCfg := cfg.LatencyAnomalyCfg
+		return sampling.NewLatencyAnomaly(settings, laCfg.Percentile, laCfg.MedianMultiplier, laCfg.Window, laCfg.WarmUpTraces,
+			laCfg.MaxOperations, sampling.MonotonicClock{})
+	case Novelty:
+		nCfg := cfg.NoveltyCfg
+		return sampling.NewNovelty(settings, nCfg.Keys, nCfg.FirstTraces, nCfg.Window, nCfg.MaxValues, sampling.MonotonicClock{})
+	case KeyedRateLimiting:
+		krlCfg := cfg.KeyedRateLimitingCfg
+		overrides := make(map[string]int64, len(krlCfg.Overrides))
+		for _, o := range krlCfg.Overrides {
+			overrides[o.Key] = o.SpansPerSecond
+		}
+		key := sampling.RateLimitKey{Attribute: krlCfg.Key, Expression: krlCfg.Expression}
+		return sampling.NewKeyedRateLimiting(settings, key, krlCfg.SpansPerSecond, overrides, krlCfg.MaxKeys, sampling.MonotonicClock{})
 
 	default:
 		return nil, fmt.Errorf("unknown sampling policy type %s", cfg.Type)
@@ -264,27 +465,55 @@ type policyMetrics struct {
 }
 
 func (tsp *tailSamplingSpanProcessor) loadSamplingPolicy(cfgs []PolicyCfg) error {
+	policies, err := tsp.newPolicies(cfgs)
+	if err != nil {
+		return err
+	}
+	tsp.setPolicies(policies)
+	return nil
+}
+
+// setPolicies replaces the policies evaluated on the traces.
+func (tsp *tailSamplingSpanProcessor) setPolicies(policies []*policy) {
+	tsp.policies = policies
+	tsp.setTrajectoryCatalogs(tsp.policies)
+
+	tsp.logger.Debug("Loaded sampling policy", zap.Int("policies.len", len(policies)))
+}
+
+// newPolicies returns the policies of the given configurations, in evaluation order.
+func (tsp *tailSamplingSpanProcessor) newPolicies(cfgs []PolicyCfg) ([]*policy, error) {
 	telemetrySettings := tsp.set.TelemetrySettings
 	componentID := tsp.set.ID.Name()
 
 	cLen := len(cfgs)
 	policies := make([]*policy, 0, cLen)
 	dropPolicies := make([]*policy, 0, cLen)
+	shadowPolicies := make([]*policy, 0, cLen)
 	policyNames := make(map[string]struct{}, cLen)
 
 	for _, cfg := range cfgs {
 		if cfg.Name == "" {
-			return errors.New("policy name cannot be empty")
+			return nil, errors.New("policy name cannot be empty")
 		}
 
 		if _, exists := policyNames[cfg.Name]; exists {
-			return fmt.Errorf("duplicate policy name %q", cfg.Name)
+			return nil, fmt.Errorf("duplicate policy name %q", cfg.Name)
 		}
 		policyNames[cfg.Name] = struct{}{}
 
+		if cfg.DecisionWait < 0 || cfg.DecisionWait > tsp.decisionWait {
+			return nil, fmt.Errorf("invalid decision_wait of policy %q, it must be between 0 and the decision_wait of the processor", cfg.Name)
+		}
+		// Stages would make a decision without the policies evaluated later, which may change it in these modes.
+		if cfg.DecisionWait > 0 && cfg.DecisionWait < tsp.decisionWait &&
+			(tsp.decisionMode == DecisionModeAll || tsp.decisionMode == DecisionModePriority) {
+			return nil, fmt.Errorf("the decision_wait of policy %q isn't supported by the %q decision mode", cfg.Name, tsp.decisionMode)
+		}
+
 		eval, err := getPolicyEvaluator(telemetrySettings, &cfg)
 		if err != nil {
-			return fmt.Errorf("failed to create policy evaluator for %q: %w", cfg.Name, err)
+			return nil, fmt.Errorf("failed to create policy evaluator for %q: %w", cfg.Name, err)
 		}
 
 		uniquePolicyName := cfg.Name
@@ -292,24 +521,44 @@ func (tsp *tailSamplingSpanProcessor) loadSamplingPolicy(cfgs []PolicyCfg) error
 			uniquePolicyName = fmt.Sprintf("%s.%s", componentID, cfg.Name)
 		}
 
+		attrs := []attribute.KeyValue{attribute.String("policy", uniquePolicyName)}
+		if cfg.Shadow {
+			attrs = append(attrs, attribute.Bool("shadow", true))
+		}
+		// A drop policy not dropping a trace leaves the decision to the next policies in the priority mode.
+		abstainOnNoMatch := cfg.AbstainOnNoMatch || (cfg.Type == Drop && tsp.decisionMode == DecisionModePriority)
 		p := &policy{
-			name:      cfg.Name,
-			evaluator: eval,
-			attribute: metric.WithAttributes(attribute.String("policy", uniquePolicyName)),
+			name:             cfg.Name,
+			evaluator:        eval,
+			attribute:        metric.WithAttributes(attrs...),
+			decisionWait:     cfg.DecisionWait,
+			drop:             cfg.Type == Drop,
+			shadow:           cfg.Shadow,
+			priority:         cfg.Priority,
+			abstainOnNoMatch: abstainOnNoMatch,
+			trimmer:          newTrimmer(cfg.Trim),
 		}
 
-		if cfg.Type == Drop {
+		switch {
+		case cfg.Shadow:
+			shadowPolicies = append(shadowPolicies, p)
+		case cfg.Type == Drop:
 			dropPolicies = append(dropPolicies, p)
-		} else {
+		default:
 			policies = append(policies, p)
 		}
 	}
-	// Dropped decision takes precedence over all others, therefore we evaluate them first.
-	tsp.policies = slices.Concat(dropPolicies, policies)
-
-	tsp.logger.Debug("Loaded sampling policy", zap.Int("policies.len", len(policies)))
-
-	return nil
+	// Shadow policies are evaluated apart, once the decision is made. Dropped decision takes precedence over all others,
+	// therefore we evaluate them before the rest.
+	if tsp.decisionMode == DecisionModePriority {
+		// Unless policies have a higher priority.
+		policies = slices.Concat(dropPolicies, policies)
+		slices.SortStableFunc(policies, func(a, b *policy) int {
+			return cmp.Compare(b.priority, a.priority)
+		})
+		return slices.Concat(shadowPolicies, policies), nil
+	}
+	return slices.Concat(shadowPolicies, dropPolicies, policies), nil
 }
 
 func (tsp *tailSamplingSpanProcessor) SetSamplingPolicy(cfgs []PolicyCfg) {
@@ -319,12 +568,33 @@ func (tsp *tailSamplingSpanProcessor) SetSamplingPolicy(cfgs []PolicyCfg) {
 	defer tsp.setPolicyMux.Unlock()
 
 	tsp.pendingPolicy = cfgs
+	tsp.pendingPolicies = nil
+}
+
+// setPendingPolicies sets policies already checked to be loaded on the next tick, along with the version of the policy
+// source they come from.
+func (tsp *tailSamplingSpanProcessor) setPendingPolicies(policies []*policy, version int64) {
+	tsp.logger.Debug("Setting pending sampling policy", zap.Int("pending.len", len(policies)))
+
+	tsp.setPolicyMux.Lock()
+	defer tsp.setPolicyMux.Unlock()
+
+	tsp.pendingPolicies = policies
+	tsp.pendingVersion = version
+	tsp.pendingPolicy = nil
 }
 
 func (tsp *tailSamplingSpanProcessor) loadPendingSamplingPolicy() {
 	tsp.setPolicyMux.Lock()
 	defer tsp.setPolicyMux.Unlock()
 
+	if len(tsp.pendingPolicies) > 0 {
+		tsp.setPolicies(tsp.pendingPolicies)
+		tsp.telemetry.ProcessorTailSamplingPolicySourceVersion.Record(tsp.ctx, tsp.pendingVersion)
+		tsp.pendingPolicies = nil
+		return
+	}
+
 	// Nothing pending, do nothing.
 	pLen := len(tsp.pendingPolicy)
 	if pLen == 0 {
@@ -348,52 +618,44 @@ func (tsp *tailSamplingSpanProcessor) loadPendingSamplingPolicy() {
 func (tsp *tailSamplingSpanProcessor) samplingPolicyOnTick() {
 	tsp.logger.Debug("Sampling Policy Evaluation ticked")
 
-	// [CHANGED]: To re-initialize
-	for _, p := range tsp.policies {
-		if stratified, ok := p.evaluator.(*sampling.StratifiedProbabilisticSampler); ok {
-			stratified.ResetWindow()
-		}
-	}
-
 	tsp.loadPendingSamplingPolicy()
 
 	ctx := context.Background()
 	metrics := policyMetrics{}
 	startTime := time.Now()
 
+	tsp.decideStages(ctx, &metrics)
+
 	batch, _ := tsp.decisionBatcher.CloseCurrentAndTakeFirstBatch()
 	batchLen := len(batch)
 
 	for _, id := range batch {
-		d, ok := tsp.idToTrace.Load(id)
-		if !ok {
+		var trace *sampling.TraceData
+		if d, ok := tsp.idToTrace.Load(id); ok {
+			trace = d.(*sampling.TraceData)
+		} else if trace = tsp.reloadSpilledTrace(id); trace == nil {
 			metrics.idNotFoundOnMapCount++
 			continue
 		}
-		trace := d.(*sampling.TraceData)
-		trace.DecisionTime = time.Now()
-
-		decision := tsp.makeDecision(id, trace, &metrics)
-
-		tsp.telemetry.ProcessorTailSamplingGlobalCountTracesSampled.Add(tsp.ctx, 1, decisionToAttribute[decision])
-
-		// Sampled or not, remove the batches
-		trace.Lock()
-		allSpans := trace.ReceivedBatches
-		trace.FinalDecision = decision
-		trace.ReceivedBatches = ptrace.NewTraces()
-		trace.Unlock()
-
-		switch decision {
-		case sampling.Sampled:
-			tsp.releaseSampledTrace(ctx, id, allSpans)
-		case sampling.NotSampled:
-			tsp.releaseNotSampledTrace(id)
+		if decided(trace) {
+			// The trace was decided by an earlier stage, or early because it was complete.
+			continue
 		}
+		tsp.decideTrace(ctx, id, trace, tsp.remainingPolicies(trace), true, &metrics)
+	}
+
+	if tsp.earlyDecision {
+		tsp.decideCompleteTraces(ctx, &metrics)
 	}
 
 	tsp.telemetry.ProcessorTailSamplingSamplingDecisionTimerLatency.Record(tsp.ctx, int64(time.Since(startTime)/time.Millisecond))
 	tsp.telemetry.ProcessorTailSamplingSamplingTracesOnMemory.Record(tsp.ctx, int64(tsp.numTracesOnMap.Load()))
+	if tsp.spill != nil {
+		tsp.telemetry.ProcessorTailSamplingSpilledTracesSize.Record(tsp.ctx, tsp.spill.Size())
+	}
+	if tsp.router != nil {
+		tsp.telemetry.ProcessorTailSamplingRoutingPeers.Record(tsp.ctx, int64(tsp.router.Peers()))
+	}
 	tsp.telemetry.ProcessorTailSamplingSamplingTraceDroppedTooEarly.Add(tsp.ctx, metrics.idNotFoundOnMapCount)
 	tsp.telemetry.ProcessorTailSamplingSamplingPolicyEvaluationError.Add(tsp.ctx, metrics.evaluateErrorCount)
 
@@ -406,7 +668,99 @@ func (tsp *tailSamplingSpanProcessor) samplingPolicyOnTick() {
 	)
 }
 
-func (tsp *tailSamplingSpanProcessor) makeDecision(id pcommon.TraceID, trace *sampling.TraceData, metrics *policyMetrics) sampling.Decision {
+// decideTrace makes the sampling decision on a trace with the given policies and releases its spans accordingly. Unless
+// the decision is final, the trace is left undecided when the policies don't sample nor drop it.
+func (tsp *tailSamplingSpanProcessor) decideTrace(ctx context.Context, id pcommon.TraceID, trace *sampling.TraceData, policies []*policy, final bool, metrics *policyMetrics) {
+	decision := tsp.makeDecision(id, trace, policies, final, metrics)
+	if decision == sampling.Unspecified {
+		return
+	}
+	trace.DecisionTime = time.Now()
+
+	tsp.telemetry.ProcessorTailSamplingGlobalCountTracesSampled.Add(tsp.ctx, 1, decisionToAttribute[decision])
+
+	// Sampled or not, remove the batches
+	trace.Lock()
+	allSpans := trace.ReceivedBatches
+	allLogs := trace.ReceivedLogs
+	sampledBy, droppedBy := trace.SampledBy, trace.DroppedBy
+	sampledWith := reportedSampling{probability: trace.SamplingProbability, threshold: trace.SamplingThreshold}
+	trace.FinalDecision = decision
+	trace.ReceivedBatches = ptrace.NewTraces()
+	trace.ReceivedLogs = plog.Logs{}
+	trace.Unlock()
+
+	switch decision {
+	case sampling.Sampled:
+		tsp.recordSpanMetrics(allSpans, true)
+		tsp.trimSampledTrace(sampledBy, tsp.policies, allSpans)
+		tsp.recordDecisionOnSpans(allSpans, sampledBy, sampledWith)
+		tsp.releaseSampledTrace(ctx, id, allSpans)
+		if allLogs != (plog.Logs{}) {
+			tsp.releaseSampledLogs(ctx, allLogs)
+		}
+	case sampling.NotSampled:
+		tsp.recordSpanMetrics(allSpans, false)
+		if tsp.lateSpanSummaries != nil && droppedBy == "" {
+			tsp.summarizeNotSampledTrace(id, allSpans)
+		}
+		tsp.releaseNotSampledTrace(id)
+		if allLogs != (plog.Logs{}) {
+			tsp.releaseNotSampledLogs(allLogs)
+		}
+	}
+}
+
+// isDecisive returns whether a policy decision makes the decision on a trace, according to the decision mode.
+func (tsp *tailSamplingSpanProcessor) isDecisive(decision sampling.Decision) bool {
+	if decision == sampling.Dropped {
+		return true
+	}
+	switch tsp.decisionMode {
+	case DecisionModeFirstMatch:
+		// Make decision as soon as a policy matches
+		return decision == sampling.Sampled
+	case DecisionModeAll:
+		// Any policy not sampling the trace makes the decision.
+		return decision == sampling.NotSampled || decision == sampling.InvertNotSampled
+	case DecisionModePriority:
+		// Policies are sorted by priority, the first one not abstaining makes the decision.
+		return decision != sampling.Abstain
+	default:
+		return false
+	}
+}
+
+// evaluateShadowPolicies evaluates the shadow policies on a trace once, when the decision is made on it, whichever
+// stage makes it. Their decisions are only counted in the metrics.
+func (tsp *tailSamplingSpanProcessor) evaluateShadowPolicies(ctx context.Context, id pcommon.TraceID, trace *sampling.TraceData, metrics *policyMetrics) {
+	for _, p := range tsp.policies {
+		if !p.shadow {
+			continue
+		}
+		startTime := time.Now()
+		decision, err := p.evaluate(ctx, id, trace)
+		tsp.telemetry.ProcessorTailSamplingSamplingDecisionLatency.Record(ctx, int64(time.Since(startTime)/time.Microsecond), p.attribute)
+		if err != nil {
+			metrics.evaluateErrorCount++
+			tsp.logger.Debug("Sampling policy error", zap.Error(err))
+			continue
+		}
+		if decision == sampling.Abstain {
+			continue
+		}
+
+		tsp.telemetry.ProcessorTailSamplingCountTracesSampled.Add(ctx, 1, p.attribute, decisionToAttribute[decision])
+		if telemetry.IsMetricStatCountSpansSampledEnabled() {
+			tsp.telemetry.ProcessorTailSamplingCountSpansSampled.Add(ctx, trace.SpanCount.Load(), p.attribute, decisionToAttribute[decision])
+		}
+	}
+}
+
+// makeDecision evaluates the given policies on a trace, and combines their decisions according to the decision mode.
+// Unless the decision is final, it returns Unspecified when the policies don't sample nor drop the trace, as policies
+// evaluated later may still sample it.
+func (tsp *tailSamplingSpanProcessor) makeDecision(id pcommon.TraceID, trace *sampling.TraceData, policies []*policy, final bool, metrics *policyMetrics) sampling.Decision {
 	finalDecision := sampling.NotSampled
 	samplingDecisions := map[sampling.Decision]*policy{
 		sampling.Error:            nil,
@@ -416,13 +770,40 @@ func (tsp *tailSamplingSpanProcessor) makeDecision(id pcommon.TraceID, trace *sa
 		sampling.InvertNotSampled: nil,
 		sampling.Dropped:          nil,
 	}
+	// Sampling probability and threshold reported by the first policy of each decision.
+	reported := map[sampling.Decision]reportedSampling{}
+	// Sampling probability and threshold reported by all the policies sampling the trace.
+	var sampledReports []reportedSampling
+	// Decisions depending on the policies evaluated later, kept on the trace if it's left undecided.
+	var pending []sampling.PolicyDecision
+	// The policy dropping the trace, if any.
+	var droppedBy string
+	// The policy deciding on the trace in the priority mode, and its decision.
+	var decider *policy
+	var deciderDecision sampling.Decision
+
+	// Decisions of the policies evaluated on the trace by the earlier stages.
+	for _, d := range trace.Stages.Decisions {
+		if samplingDecisions[d.Decision] == nil {
+			samplingDecisions[d.Decision] = &policy{name: d.Policy}
+			reported[d.Decision] = reportedSampling{probability: d.Probability, threshold: d.Threshold}
+		}
+		if d.Decision == sampling.InvertSampled {
+			sampledReports = append(sampledReports, reportedSampling{probability: d.Probability, threshold: d.Threshold})
+		}
+	}
 
 	ctx := context.Background()
 	startTime := time.Now()
 
 	// Check all policies before making a final decision.
-	for _, p := range tsp.policies {
-		decision, err := p.evaluator.Evaluate(ctx, id, trace)
+	for _, p := range policies {
+		if p.shadow {
+			// Shadow policies are evaluated once the decision is made.
+			continue
+		}
+		trace.SetSampling(0, "")
+		decision, err := p.evaluate(ctx, id, trace)
 		latency := time.Since(startTime)
 		tsp.telemetry.ProcessorTailSamplingSamplingDecisionLatency.Record(ctx, int64(latency/time.Microsecond), p.attribute)
 
@@ -435,42 +816,129 @@ func (tsp *tailSamplingSpanProcessor) makeDecision(id pcommon.TraceID, trace *sa
 			continue
 		}
 
+		if decision == sampling.Abstain {
+			// The policy has no opinion on the trace.
+			continue
+		}
+
 		tsp.telemetry.ProcessorTailSamplingCountTracesSampled.Add(ctx, 1, p.attribute, decisionToAttribute[decision])
 
 		if telemetry.IsMetricStatCountSpansSampledEnabled() {
 			tsp.telemetry.ProcessorTailSamplingCountSpansSampled.Add(ctx, trace.SpanCount.Load(), p.attribute, decisionToAttribute[decision])
 		}
 
+		probability, threshold := trace.Sampling()
 		// We associate the first policy with the sampling decision to understand what policy sampled a span
 		if samplingDecisions[decision] == nil {
 			samplingDecisions[decision] = p
+			reported[decision] = reportedSampling{probability: probability, threshold: threshold}
+		}
+		if decision == sampling.Sampled || decision == sampling.InvertSampled {
+			sampledReports = append(sampledReports, reportedSampling{probability: probability, threshold: threshold})
+		}
+		if decision == sampling.NotSampled || decision == sampling.InvertSampled {
+			pending = append(pending, sampling.PolicyDecision{Policy: p.name, Decision: decision, Probability: probability, Threshold: threshold})
 		}
 
-		// Break early if dropped. This can drastically reduce tick/decision latency.
-		if decision == sampling.Dropped {
-			break
+		if tsp.decisionMode == DecisionModePriority {
+			decider, deciderDecision = p, decision
 		}
-		// If sampleOnFirstMatch is enabled, make decision as soon as a policy matches
-		if tsp.sampleOnFirstMatch && decision == sampling.Sampled {
+
+		// Break early once the decision is made. This can drastically reduce tick/decision latency.
+		if tsp.isDecisive(decision) {
 			break
 		}
 	}
 
 	var sampledPolicy *policy
+	var sampledWith reportedSampling
+	// Other decisions depend on the policies evaluated later.
+	decisive := final
 
-	switch {
-	case samplingDecisions[sampling.Dropped] != nil: // Dropped takes precedence
-		finalDecision = sampling.NotSampled
-	case samplingDecisions[sampling.InvertNotSampled] != nil: // Then InvertNotSampled
-		finalDecision = sampling.NotSampled
-	case samplingDecisions[sampling.Sampled] != nil:
-		finalDecision = sampling.Sampled
-		sampledPolicy = samplingDecisions[sampling.Sampled]
-	case samplingDecisions[sampling.InvertSampled] != nil && samplingDecisions[sampling.NotSampled] == nil:
-		finalDecision = sampling.Sampled
-		sampledPolicy = samplingDecisions[sampling.InvertSampled]
+	switch tsp.decisionMode {
+	case DecisionModePriority:
+		switch deciderDecision {
+		case sampling.NotSampled:
+			finalDecision = sampling.NotSampled
+			decisive = true
+		case sampling.Dropped, sampling.InvertNotSampled:
+			finalDecision = sampling.NotSampled
+			droppedBy = decider.name
+			decisive = true
+		case sampling.Sampled, sampling.InvertSampled:
+			finalDecision = sampling.Sampled
+			sampledPolicy = decider
+			sampledWith = reported[deciderDecision]
+			decisive = true
+		}
+	case DecisionModeAll:
+		switch {
+		case samplingDecisions[sampling.Dropped] != nil:
+			finalDecision = sampling.NotSampled
+			droppedBy = samplingDecisions[sampling.Dropped].name
+			decisive = true
+		case samplingDecisions[sampling.InvertNotSampled] != nil:
+			finalDecision = sampling.NotSampled
+			droppedBy = samplingDecisions[sampling.InvertNotSampled].name
+			decisive = true
+		case samplingDecisions[sampling.NotSampled] != nil:
+			finalDecision = sampling.NotSampled
+		case samplingDecisions[sampling.Sampled] != nil:
+			finalDecision = sampling.Sampled
+			sampledPolicy = samplingDecisions[sampling.Sampled]
+			sampledWith = reported[sampling.Sampled]
+		case samplingDecisions[sampling.InvertSampled] != nil:
+			finalDecision = sampling.Sampled
+			sampledPolicy = samplingDecisions[sampling.InvertSampled]
+			sampledWith = reported[sampling.InvertSampled]
+		}
+	default:
+		switch {
+		case samplingDecisions[sampling.Dropped] != nil: // Dropped takes precedence
+			finalDecision = sampling.NotSampled
+			droppedBy = samplingDecisions[sampling.Dropped].name
+			decisive = true
+		case samplingDecisions[sampling.InvertNotSampled] != nil: // Then InvertNotSampled
+			finalDecision = sampling.NotSampled
+			droppedBy = samplingDecisions[sampling.InvertNotSampled].name
+			decisive = true
+		case samplingDecisions[sampling.Sampled] != nil:
+			finalDecision = sampling.Sampled
+			sampledPolicy = samplingDecisions[sampling.Sampled]
+			sampledWith = reported[sampling.Sampled]
+			decisive = true
+		case samplingDecisions[sampling.InvertSampled] != nil && samplingDecisions[sampling.NotSampled] == nil:
+			finalDecision = sampling.Sampled
+			sampledPolicy = samplingDecisions[sampling.InvertSampled]
+			sampledWith = reported[sampling.InvertSampled]
+		}
+	}
+	if !decisive {
+		trace.Lock()
+		trace.SamplingProbability, trace.SamplingThreshold = 0, ""
+		trace.Stages.Decisions = append(trace.Stages.Decisions, pending...)
+		trace.Unlock()
+		return sampling.Unspecified
+	}
+	tsp.evaluateShadowPolicies(ctx, id, trace, metrics)
+
+	// In the priority mode, the trace is sampled by the deciding policy only. Otherwise, it is sampled by all the
+	// policies sampling it.
+	if finalDecision == sampling.Sampled && tsp.decisionMode != DecisionModePriority {
+		sampledWith.probability = inclusionProbability(sampledReports, tsp.decisionMode == DecisionModeAll)
+		sampledWith.threshold = consistentThreshold(sampledReports, tsp.decisionMode == DecisionModeAll)
 	}
 
+	trace.Lock()
+	trace.SamplingProbability, trace.SamplingThreshold = sampledWith.probability, sampledWith.threshold
+	if sampledPolicy != nil {
+		trace.SampledBy = sampledPolicy.name
+	}
+	if droppedBy != "" {
+		trace.DroppedBy = droppedBy
+	}
+	trace.Unlock()
+
 	if tsp.recordPolicy && sampledPolicy != nil {
 		sampling.SetAttrOnScopeSpans(trace, "tailsampling.policy", sampledPolicy.name)
 	}
@@ -486,7 +954,11 @@ func (tsp *tailSamplingSpanProcessor) makeDecision(id pcommon.TraceID, trace *sa
 }
 
 // ConsumeTraces is required by the processor.Traces interface.
-func (tsp *tailSamplingSpanProcessor) ConsumeTraces(_ context.Context, td ptrace.Traces) error {
+func (tsp *tailSamplingSpanProcessor) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	// Spans forwarded by a peer are owned by this instance, even if it sees the peers differently.
+	if tsp.router != nil && !routing.IsForwarded(ctx) {
+		td = tsp.routeTraces(ctx, td)
+	}
 	resourceSpans := td.ResourceSpans()
 	for i := 0; i < resourceSpans.Len(); i++ {
 		tsp.processTraces(resourceSpans.At(i))
@@ -521,11 +993,13 @@ func (tsp *tailSamplingSpanProcessor) processTraces(resourceSpans ptrace.Resourc
 	idToSpansAndScope := tsp.groupSpansByTraceKey(resourceSpans)
 	var newTraceIDs int64
 	for id, spans := range idToSpansAndScope {
-		// If the trace ID is in the sampled cache, short circuit the decision
-		if _, ok := tsp.sampledIDCache.Get(id); ok {
+		// If the trace ID is in the sampled cache, short circuit the decision.
+		// The cache doesn't know how the trace was sampled, so the decision isn't recorded on these spans.
+		if tsp.lookupDecisionCache(tsp.sampledIDCache, id, attrSampledTrue) {
 			tsp.logger.Debug("Trace ID is in the sampled cache", zap.Stringer("id", id))
 			traceTd := ptrace.NewTraces()
 			appendToTraces(traceTd, resourceSpans, spans)
+			tsp.recordSpanMetrics(traceTd, true)
 			tsp.releaseSampledTrace(tsp.ctx, id, traceTd)
 			metric.WithAttributeSet(attribute.NewSet())
 			tsp.telemetry.ProcessorTailSamplingEarlyReleasesFromCacheDecision.
@@ -533,8 +1007,13 @@ func (tsp *tailSamplingSpanProcessor) processTraces(resourceSpans ptrace.Resourc
 			continue
 		}
 		// If the trace ID is in the non-sampled cache, short circuit the decision
-		if _, ok := tsp.nonSampledIDCache.Get(id); ok {
+		if tsp.lookupDecisionCache(tsp.nonSampledIDCache, id, attrSampledFalse) {
 			tsp.logger.Debug("Trace ID is in the non-sampled cache", zap.Stringer("id", id))
+			if tsp.upgradeNotSampledTrace(id, nil, resourceSpans, spans) {
+				tsp.recordLateSpanMetrics(resourceSpans, spans, true)
+				continue
+			}
+			tsp.recordLateSpanMetrics(resourceSpans, spans, false)
 			tsp.telemetry.ProcessorTailSamplingEarlyReleasesFromCacheDecision.
 				Add(tsp.ctx, int64(len(spans)), attrSampledFalse)
 			continue
@@ -543,44 +1022,39 @@ func (tsp *tailSamplingSpanProcessor) processTraces(resourceSpans ptrace.Resourc
 		lenSpans := int64(len(spans))
 
 		d, loaded := tsp.idToTrace.Load(id)
-		if !loaded {
-			spanCount := &atomic.Int64{}
-			spanCount.Store(lenSpans)
-
-			td := &sampling.TraceData{
-				ArrivalTime:     currTime,
-				SpanCount:       spanCount,
-				ReceivedBatches: ptrace.NewTraces(),
-			}
-
-			if d, loaded = tsp.idToTrace.LoadOrStore(id, td); !loaded {
-				newTraceIDs++
-				tsp.decisionBatcher.AddToCurrentBatch(id)
-				tsp.numTracesOnMap.Add(1)
-				postDeletion := false
-				for !postDeletion {
-					select {
-					case tsp.deleteChan <- id:
-						postDeletion = true
-					default:
-						traceKeyToDrop := <-tsp.deleteChan
-						tsp.dropTrace(traceKeyToDrop, currTime)
-					}
-				}
-			}
+		if !loaded && tsp.appendToSpilledTrace(id, resourceSpans, spans) {
+			continue
 		}
-
-		actualData := d.(*sampling.TraceData)
+		var actualData *sampling.TraceData
 		if loaded {
+			actualData = d.(*sampling.TraceData)
 			actualData.SpanCount.Add(lenSpans)
+		} else {
+			var stored bool
+			if actualData, stored = tsp.storeTrace(id, currTime, lenSpans); stored {
+				newTraceIDs++
+			} else {
+				actualData.SpanCount.Add(lenSpans)
+			}
 		}
 
 		actualData.Lock()
 		finalDecision := actualData.FinalDecision
+		sampledBy := actualData.SampledBy
+		sampledWith := reportedSampling{probability: actualData.SamplingProbability, threshold: actualData.SamplingThreshold}
 
+		if finalDecision == sampling.Unspecified && tsp.spill != nil && tsp.spill.Contains(id) {
+			// The trace was evicted and spilled since it was loaded, add the new spans to the spilled ones.
+			actualData.Unlock()
+			tsp.appendToSpilledTrace(id, resourceSpans, spans)
+			continue
+		}
 		if finalDecision == sampling.Unspecified {
 			// If the final decision hasn't been made, add the new spans under the lock.
 			appendToTraces(actualData.ReceivedBatches, resourceSpans, spans)
+			if actualData.Completeness != nil {
+				tsp.trackCompleteness(id, actualData, spans, currTime)
+			}
 			actualData.Unlock()
 			continue
 		}
@@ -591,9 +1065,15 @@ func (tsp *tailSamplingSpanProcessor) processTraces(resourceSpans ptrace.Resourc
 		case sampling.Sampled:
 			traceTd := ptrace.NewTraces()
 			appendToTraces(traceTd, resourceSpans, spans)
+			tsp.recordSpanMetrics(traceTd, true)
+			tsp.recordDecisionOnSpans(traceTd, sampledBy, sampledWith)
 			tsp.releaseSampledTrace(tsp.ctx, id, traceTd)
 		case sampling.NotSampled:
-			tsp.releaseNotSampledTrace(id)
+			upgraded := tsp.upgradeNotSampledTrace(id, actualData, resourceSpans, spans)
+			tsp.recordLateSpanMetrics(resourceSpans, spans, upgraded)
+			if !upgraded {
+				tsp.releaseNotSampledTrace(id)
+			}
 		default:
 			tsp.logger.Warn("Unexpected sampling decision", zap.Int("decision", int(finalDecision)))
 		}
@@ -606,21 +1086,80 @@ func (tsp *tailSamplingSpanProcessor) processTraces(resourceSpans ptrace.Resourc
 	tsp.telemetry.ProcessorTailSamplingNewTraceIDReceived.Add(tsp.ctx, newTraceIDs)
 }
 
+// storeTrace stores a new trace with the given number of spans, unless one was stored meanwhile, and schedules its
+// decision. It returns the trace in memory, and whether it is the new one.
+func (tsp *tailSamplingSpanProcessor) storeTrace(id pcommon.TraceID, currTime time.Time, lenSpans int64) (*sampling.TraceData, bool) {
+	spanCount := &atomic.Int64{}
+	spanCount.Store(lenSpans)
+
+	td := &sampling.TraceData{
+		ArrivalTime:     currTime,
+		SpanCount:       spanCount,
+		ReceivedBatches: ptrace.NewTraces(),
+	}
+	if tsp.earlyDecision {
+		td.Completeness = sampling.NewTraceCompleteness()
+	}
+
+	if d, loaded := tsp.idToTrace.LoadOrStore(id, td); loaded {
+		return d.(*sampling.TraceData), false
+	}
+	tsp.decisionBatcher.AddToCurrentBatch(id)
+	tsp.addToDecisionStages(id)
+	tsp.numTracesOnMap.Add(1)
+	postDeletion := false
+	for !postDeletion {
+		select {
+		case tsp.deleteChan <- id:
+			postDeletion = true
+		default:
+			traceKeyToDrop := <-tsp.deleteChan
+			tsp.evictTrace(traceKeyToDrop, currTime)
+		}
+	}
+	return td, true
+}
+
 func (tsp *tailSamplingSpanProcessor) Capabilities() consumer.Capabilities {
 	return consumer.Capabilities{MutatesData: false}
 }
 
 // Start is invoked during service startup.
-func (tsp *tailSamplingSpanProcessor) Start(context.Context, component.Host) error {
+func (tsp *tailSamplingSpanProcessor) Start(ctx context.Context, host component.Host) error {
+	if tsp.debugExtensionID != nil {
+		if err := tsp.registerTrajectoryz(host, *tsp.debugExtensionID); err != nil {
+			return err
+		}
+	}
+	if tsp.router != nil {
+		if err := tsp.startRouter(ctx, host); err != nil {
+			return err
+		}
+	}
+	if tsp.policySource != nil {
+		if err := tsp.policySource.Start(ctx); err != nil {
+			return err
+		}
+	}
 	tsp.policyTicker.Start(tsp.tickerFrequency)
+	tsp.startSpanMetrics()
 	return nil
 }
 
 // Shutdown is invoked during service shutdown.
-func (tsp *tailSamplingSpanProcessor) Shutdown(context.Context) error {
+func (tsp *tailSamplingSpanProcessor) Shutdown(ctx context.Context) error {
 	tsp.decisionBatcher.Stop()
+	stopDecisionStages(tsp.decisionStages)
 	tsp.policyTicker.Stop()
-	return nil
+	tsp.stopSpanMetrics(ctx)
+	var err error
+	if tsp.router != nil {
+		err = errors.Join(err, tsp.router.Shutdown(ctx))
+	}
+	if tsp.policySource != nil {
+		err = errors.Join(err, tsp.policySource.Shutdown(ctx))
+	}
+	return errors.Join(err, closeDecisionCache(tsp.sampledIDCache), closeDecisionCache(tsp.nonSampledIDCache), closeSpillStore(tsp.spill))
 }
 
 func (tsp *tailSamplingSpanProcessor) dropTrace(traceID pcommon.TraceID, deletionTime time.Time) {
@@ -639,15 +1178,33 @@ func (tsp *tailSamplingSpanProcessor) dropTrace(traceID pcommon.TraceID, deletio
 	tsp.telemetry.ProcessorTailSamplingSamplingTraceRemovalAge.Record(tsp.ctx, int64(deletionTime.Sub(trace.ArrivalTime)/time.Second))
 }
 
+// lookupDecisionCache returns whether the trace ID is in the given decision cache, recording the hit or miss with the
+// given attribute unless the cache is disabled.
+func (tsp *tailSamplingSpanProcessor) lookupDecisionCache(c cache.Cache[bool], id pcommon.TraceID, attr metric.MeasurementOption) bool {
+	_, ok := c.Get(id)
+	if cache.IsNopDecisionCache(c) {
+		return ok
+	}
+	if ok {
+		tsp.telemetry.ProcessorTailSamplingDecisionCacheHits.Add(tsp.ctx, 1, attr)
+		return true
+	}
+	tsp.telemetry.ProcessorTailSamplingDecisionCacheMisses.Add(tsp.ctx, 1, attr)
+	return false
+}
+
 // releaseSampledTrace sends the trace data to the next consumer. It
 // additionally adds the trace ID to the cache of sampled trace IDs. If the
 // trace ID is cached, it deletes the spans from the internal map.
 func (tsp *tailSamplingSpanProcessor) releaseSampledTrace(ctx context.Context, id pcommon.TraceID, td ptrace.Traces) {
 	tsp.sampledIDCache.Put(id, true)
-	if err := tsp.nextConsumer.ConsumeTraces(ctx, td); err != nil {
-		tsp.logger.Warn(
-			"Error sending spans to destination",
-			zap.Error(err))
+	// Traces only made of log records have no spans to send.
+	if td.ResourceSpans().Len() > 0 {
+		if err := tsp.nextConsumer.ConsumeTraces(ctx, td); err != nil {
+			tsp.logger.Warn(
+				"Error sending spans to destination",
+				zap.Error(err))
+		}
 	}
 	_, ok := tsp.sampledIDCache.Get(id)
 	if ok {
@@ -5,6 +5,8 @@ package tailsamplingprocessor
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -637,6 +639,93 @@ func TestLateSpanUsesNonSampledDecisionCache(t *testing.T) {
 	require.Equal(t, 0, nextConsumer.SpanCount(), "original final decision not honored")
 }
 
+func TestLateSpanUsesDiskDecisionCacheAfterRestart(t *testing.T) {
+	nextConsumer := new(consumertest.TracesSink)
+	mpe := &mockPolicyEvaluator{}
+	policies := []*policy{
+		{name: "mock-policy-1", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy-1"))},
+	}
+	decisionCache := DecisionCacheConfig{
+		SampledCacheSize: 200,
+		Type:             DiskDecisionCache,
+		Disk:             DiskDecisionCacheConfig{Directory: t.TempDir()},
+	}
+	newProcessor := func() *tailSamplingSpanProcessor {
+		cfg := Config{
+			DecisionWait:  defaultTestDecisionWait * 10,
+			NumTraces:     defaultNumTraces,
+			DecisionCache: decisionCache,
+			Options: []Option{
+				withDecisionBatcher(newSyncIDBatcher()),
+				withPolicies(policies),
+			},
+		}
+		p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), nextConsumer, cfg)
+		require.NoError(t, err)
+		require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+		return p.(*tailSamplingSpanProcessor)
+	}
+
+	traceID := uInt64ToTraceID(1)
+	spanIndexToTraces := func(spanIndex uint64) ptrace.Traces {
+		traces := ptrace.NewTraces()
+		span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.SetTraceID(traceID)
+		span.SetSpanID(uInt64ToSpanID(spanIndex))
+		return traces
+	}
+
+	// The first span is sampled and the decision persisted.
+	mpe.NextDecision = sampling.Sampled
+	tsp := newProcessor()
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), spanIndexToTraces(1)))
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+	require.Equal(t, 1, mpe.EvaluationCount)
+	require.Equal(t, 1, nextConsumer.SpanCount())
+	require.NoError(t, tsp.Shutdown(context.Background()))
+
+	// After a restart, the late span gets the persisted decision without evaluating the policies.
+	mpe.NextDecision = sampling.NotSampled
+	tsp = newProcessor()
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), spanIndexToTraces(2)))
+	require.Equal(t, 1, mpe.EvaluationCount)
+	require.Equal(t, 2, nextConsumer.SpanCount(), "persisted decision not honored")
+}
+
+func TestDecisionCacheConfigErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		decisionCache DecisionCacheConfig
+		expectedErr   string
+	}{
+		{
+			name:          "unknown type",
+			decisionCache: DecisionCacheConfig{SampledCacheSize: 10, Type: "redis"},
+			expectedErr:   `unknown decision cache type "redis"`,
+		},
+		{
+			name:          "disk without directory",
+			decisionCache: DecisionCacheConfig{NonSampledCacheSize: 10, Type: DiskDecisionCache},
+			expectedErr:   "a directory is required by the disk decision cache",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				DecisionWait:  defaultTestDecisionWait,
+				NumTraces:     defaultNumTraces,
+				DecisionCache: tt.decisionCache,
+			}
+			_, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
 func TestSampleOnFirstMatch(t *testing.T) {
 	nextConsumer := new(consumertest.TracesSink)
 	idb := newSyncIDBatcher()
@@ -694,3 +783,206 @@ func TestSampleOnFirstMatch(t *testing.T) {
 	// The final decision SHOULD be Sampled.
 	require.Equal(t, 1, nextConsumer.SpanCount())
 }
+
+func TestDecisionModes(t *testing.T) {
+	tests := []struct {
+		name              string
+		mode              DecisionMode
+		decisions         []sampling.Decision
+		expected          sampling.Decision
+		expectedDroppedBy string
+		expectedEvaluated int
+		// abstainOnNoMatch is set on the first policy.
+		abstainOnNoMatch bool
+	}{
+		{
+			name:              "any samples on a single match",
+			mode:              DecisionModeAny,
+			decisions:         []sampling.Decision{sampling.NotSampled, sampling.Sampled, sampling.NotSampled},
+			expected:          sampling.Sampled,
+			expectedEvaluated: 3,
+		},
+		{
+			name:              "any ignores abstaining policies",
+			mode:              DecisionModeAny,
+			decisions:         []sampling.Decision{sampling.Abstain, sampling.Abstain},
+			expected:          sampling.NotSampled,
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "all samples when every policy matches",
+			mode:              DecisionModeAll,
+			decisions:         []sampling.Decision{sampling.Sampled, sampling.InvertSampled, sampling.Sampled},
+			expected:          sampling.Sampled,
+			expectedEvaluated: 3,
+		},
+		{
+			name:              "all stops on the first policy not matching",
+			mode:              DecisionModeAll,
+			decisions:         []sampling.Decision{sampling.Sampled, sampling.NotSampled, sampling.Sampled},
+			expected:          sampling.NotSampled,
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "all ignores abstaining policies",
+			mode:              DecisionModeAll,
+			decisions:         []sampling.Decision{sampling.Abstain, sampling.Sampled, sampling.Abstain},
+			expected:          sampling.Sampled,
+			expectedEvaluated: 3,
+		},
+		{
+			name:              "all records the policy not sampling an inverted match",
+			mode:              DecisionModeAll,
+			decisions:         []sampling.Decision{sampling.Sampled, sampling.InvertNotSampled},
+			expected:          sampling.NotSampled,
+			expectedDroppedBy: "mock-policy-2",
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "priority samples on the first decision",
+			mode:              DecisionModePriority,
+			decisions:         []sampling.Decision{sampling.Abstain, sampling.Sampled, sampling.Dropped},
+			expected:          sampling.Sampled,
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "priority doesn't sample when the first policy deciding doesn't",
+			mode:              DecisionModePriority,
+			decisions:         []sampling.Decision{sampling.Abstain, sampling.NotSampled, sampling.Sampled},
+			expected:          sampling.NotSampled,
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "priority drops on the first inverted match",
+			mode:              DecisionModePriority,
+			decisions:         []sampling.Decision{sampling.Abstain, sampling.InvertNotSampled, sampling.Sampled},
+			expected:          sampling.NotSampled,
+			expectedDroppedBy: "mock-policy-2",
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "priority skips a policy abstaining on no match",
+			mode:              DecisionModePriority,
+			decisions:         []sampling.Decision{sampling.NotSampled, sampling.Sampled},
+			abstainOnNoMatch:  true,
+			expected:          sampling.Sampled,
+			expectedEvaluated: 2,
+		},
+		{
+			name:              "priority doesn't sample when all policies abstain",
+			mode:              DecisionModePriority,
+			decisions:         []sampling.Decision{sampling.Abstain, sampling.Abstain},
+			expected:          sampling.NotSampled,
+			expectedEvaluated: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var policies []*policy
+			var evaluators []*mockPolicyEvaluator
+			for i, decision := range tt.decisions {
+				name := fmt.Sprintf("mock-policy-%d", i+1)
+				mpe := &mockPolicyEvaluator{NextDecision: decision}
+				evaluators = append(evaluators, mpe)
+				policies = append(policies, &policy{name: name, evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", name))})
+			}
+			policies[0].abstainOnNoMatch = tt.abstainOnNoMatch
+
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    defaultNumTraces,
+				DecisionMode: tt.mode,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies(policies),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+			require.NoError(t, err)
+			tsp := p.(*tailSamplingSpanProcessor)
+
+			trace := &sampling.TraceData{SpanCount: &atomic.Int64{}, ReceivedBatches: simpleTraces()}
+			decision := tsp.makeDecision(uInt64ToTraceID(1), trace, tsp.policies, true, &policyMetrics{})
+			assert.Equal(t, tt.expected, decision)
+			assert.Equal(t, tt.expectedDroppedBy, trace.DroppedBy)
+
+			evaluated := 0
+			for _, mpe := range evaluators {
+				evaluated += mpe.EvaluationCount
+			}
+			assert.Equal(t, tt.expectedEvaluated, evaluated)
+		})
+	}
+}
+
+func TestPriorityOrdersPolicies(t *testing.T) {
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		DecisionMode: DecisionModePriority,
+		PolicyCfgs: []PolicyCfg{
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "low", Type: AlwaysSample}},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "drop", Type: Drop}},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "high", Type: AlwaysSample}, Priority: 10},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "shadow", Type: AlwaysSample}, Shadow: true},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "low-2", Type: AlwaysSample}},
+		},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+	require.NoError(t, err)
+
+	var names []string
+	for _, policy := range p.(*tailSamplingSpanProcessor).policies {
+		names = append(names, policy.name)
+		// Drop policies abstain instead of deciding on the traces they don't drop.
+		assert.Equal(t, policy.drop, policy.abstainOnNoMatch, policy.name)
+	}
+	// Shadow policies come first, then policies by priority, drop policies first among the same priority.
+	assert.Equal(t, []string{"shadow", "high", "drop", "low", "low-2"}, names)
+}
+
+func TestDecisionModeConfigErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		expectedErr string
+	}{
+		{
+			name:        "unknown mode",
+			cfg:         Config{DecisionMode: "most"},
+			expectedErr: `unknown decision mode "most"`,
+		},
+		{
+			name:        "sample on first match with another mode",
+			cfg:         Config{DecisionMode: DecisionModeAll, SampleOnFirstMatch: true},
+			expectedErr: `sample_on_first_match can't be combined with the "all" decision mode`,
+		},
+		{
+			name: "policy decision wait with priority",
+			cfg: Config{
+				DecisionMode: DecisionModePriority,
+				PolicyCfgs: []PolicyCfg{
+					{sharedPolicyCfg: sharedPolicyCfg{Name: "early", Type: AlwaysSample}, DecisionWait: defaultTestDecisionWait / 2},
+				},
+			},
+			expectedErr: `the decision_wait of policy "early" isn't supported by the "priority" decision mode`,
+		},
+		{
+			name:        "late span re-evaluation with all",
+			cfg:         Config{DecisionMode: DecisionModeAll, LateSpanReevaluation: LateSpanReevaluationCfg{Enabled: true}},
+			expectedErr: `late span re-evaluation isn't supported by the "all" decision mode`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.DecisionWait = defaultTestDecisionWait
+			cfg.NumTraces = defaultNumTraces
+			_, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
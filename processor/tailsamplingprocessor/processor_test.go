@@ -660,7 +660,7 @@ func TestDecisionPolicyMetrics(t *testing.T) {
 			ReceivedBatches: batches[i],
 		}
 
-		_ = tsp.makeDecision(id, sb, metrics)
+		_ = tsp.makeDecision(id, sb, tsp.policies, true, metrics)
 	}
 
 	assert.EqualValues(t, 5, metrics.decisionSampled)
@@ -823,3 +823,71 @@ func simpleTracesWithID(traceID pcommon.TraceID) ptrace.Traces {
 	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetTraceID(traceID)
 	return traces
 }
+
+// slowPolicyEvaluator samples all traces after a delay, letting spans be received while the traces are evaluated.
+type slowPolicyEvaluator struct {
+	delay time.Duration
+}
+
+func (e *slowPolicyEvaluator) Evaluate(context.Context, pcommon.TraceID, *sampling.TraceData) (sampling.Decision, error) {
+	time.Sleep(e.delay)
+	return sampling.Sampled, nil
+}
+
+func TestDecisionDoesNotRaceWithIngestion(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	policies := []*policy{
+		{
+			name:      "slow-policy",
+			evaluator: &slowPolicyEvaluator{delay: 10 * time.Millisecond},
+			attribute: metric.WithAttributes(attribute.String("policy", "slow-policy")),
+		},
+	}
+	cfg := Config{
+		DecisionWait:   defaultTestDecisionWait,
+		NumTraces:      defaultNumTraces,
+		RecordDecision: RecordDecisionCfg{Policy: true},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies(policies),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+	}()
+
+	const traceCount = 5
+	for i := uint64(1); i <= traceCount; i++ {
+		require.NoError(t, p.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(i))))
+	}
+
+	// Spans of the traces keep being received while they're decided.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			for i := uint64(1); i <= traceCount; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				assert.NoError(t, p.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(i))))
+			}
+		}
+	}()
+
+	// The first tick always gets an empty batch, the traces are decided on the second one.
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+	close(done)
+	wg.Wait()
+
+	assert.Positive(t, sink.SpanCount())
+}
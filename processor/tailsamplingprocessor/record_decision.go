@@ -0,0 +1,125 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+const (
+	// policyAttribute is the span attribute holding the name of the policy that sampled the trace.
+	policyAttribute = "tailsampling.policy"
+	// probabilityAttribute is the span attribute holding the probability with which the trace was sampled.
+	probabilityAttribute = "tailsampling.probability"
+)
+
+// reportedSampling is the sampling probability and, for policies sampling consistently, the sampling threshold
+// reported by a policy evaluator when sampling a trace.
+type reportedSampling struct {
+	probability float64
+	threshold   string
+}
+
+// inclusionProbability returns the probability with which a trace was sampled by the given policies: the maximum of
+// their probabilities when any of them samples the trace, or the minimum when all of them must. Policies that don't
+// sample probabilistically sample with probability 1. It returns zero when none of them samples probabilistically.
+func inclusionProbability(reports []reportedSampling, all bool) float64 {
+	probability := 0.0
+	probabilistic := false
+	for i, r := range reports {
+		p := r.probability
+		if p > 0 {
+			probabilistic = true
+		} else {
+			p = 1
+		}
+		switch {
+		case i == 0:
+			probability = p
+		case all:
+			probability = min(probability, p)
+		default:
+			probability = max(probability, p)
+		}
+	}
+	if !probabilistic {
+		return 0
+	}
+	return probability
+}
+
+// consistentThreshold returns the consistent sampling threshold of a trace sampled by the given policies: the lowest of
+// their thresholds when any of them samples the trace, or the highest when all of them must. It returns an empty
+// threshold when the trace wasn't sampled consistently, i.e.: when a policy that sampled it has no threshold, except
+// for policies that don't sample probabilistically when all of them must sample the trace.
+func consistentThreshold(reports []reportedSampling, all bool) string {
+	threshold := ""
+	for _, r := range reports {
+		switch {
+		case r.threshold == "" && all && r.probability == 0:
+			continue
+		case r.threshold == "":
+			return ""
+		case threshold == "",
+			all && sampling.CompareThresholds(r.threshold, threshold) > 0,
+			!all && sampling.CompareThresholds(r.threshold, threshold) < 0:
+			threshold = r.threshold
+		}
+	}
+	return threshold
+}
+
+// recordDecisionOnSpans records the policy that sampled a trace and the probability it was sampled with on its
+// spans, as configured. A zero probability means the policy does not sample probabilistically, so none is recorded.
+// The threshold of policies sampling consistently is always recorded in the tracestate, as required by the
+// OpenTelemetry consistent probability sampling specification.
+func (tsp *tailSamplingSpanProcessor) recordDecisionOnSpans(td ptrace.Traces, policyName string, sampledWith reportedSampling) {
+	recordPolicy := tsp.recordDecision.Policy && policyName != ""
+	recordProbability := tsp.recordDecision.Probability != "" && sampledWith.probability > 0
+	recordThreshold := sampledWith.threshold != ""
+	if !recordPolicy && !recordProbability && !recordThreshold {
+		return
+	}
+
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				if recordPolicy {
+					span.Attributes().PutStr(policyAttribute, policyName)
+				}
+				if recordThreshold {
+					span.TraceState().FromRaw(sampling.SetTraceStateThreshold(span.TraceState().AsRaw(), sampledWith.threshold))
+				}
+				if recordProbability {
+					tsp.recordProbability(span, sampledWith)
+				}
+			}
+		}
+	}
+}
+
+func (tsp *tailSamplingSpanProcessor) recordProbability(span ptrace.Span, sampledWith reportedSampling) {
+	switch {
+	case tsp.recordDecision.Probability == ProbabilityAttribute:
+		span.Attributes().PutDouble(probabilityAttribute, sampledWith.probability)
+		return
+	case sampledWith.threshold != "":
+		// Already recorded in the tracestate.
+		return
+	}
+
+	traceState, err := sampling.UpdateTraceStateProbability(span.TraceState().AsRaw(), sampledWith.probability)
+	if err != nil {
+		tsp.logger.Debug("Failed to record the sampling probability in the tracestate", zap.Error(err))
+		return
+	}
+	span.TraceState().FromRaw(traceState)
+}
@@ -0,0 +1,270 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/cache"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+// probabilisticPolicyEvaluator samples all traces, reporting the given sampling probability and threshold.
+type probabilisticPolicyEvaluator struct {
+	probability float64
+	threshold   string
+}
+
+func (e *probabilisticPolicyEvaluator) Evaluate(_ context.Context, _ pcommon.TraceID, trace *sampling.TraceData) (sampling.Decision, error) {
+	trace.SetSampling(e.probability, e.threshold)
+	return sampling.Sampled, nil
+}
+
+func TestRecordDecision(t *testing.T) {
+	tests := []struct {
+		name           string
+		recordDecision RecordDecisionCfg
+		evaluator      sampling.PolicyEvaluator
+		// other is an optional policy evaluated after the deciding one.
+		other      sampling.PolicyEvaluator
+		traceState string
+		check      func(t *testing.T, span ptrace.Span)
+	}{
+		{
+			name:           "disabled",
+			recordDecision: RecordDecisionCfg{},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25},
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, 0, span.Attributes().Len())
+				assert.Empty(t, span.TraceState().AsRaw())
+			},
+		},
+		{
+			name:           "policy",
+			recordDecision: RecordDecisionCfg{Policy: true},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25},
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, map[string]any{"tailsampling.policy": "deciding-policy"}, span.Attributes().AsRaw())
+			},
+		},
+		{
+			name:           "probability attribute",
+			recordDecision: RecordDecisionCfg{Policy: true, Probability: ProbabilityAttribute},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25},
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, map[string]any{
+					"tailsampling.policy":      "deciding-policy",
+					"tailsampling.probability": 0.25,
+				}, span.Attributes().AsRaw())
+			},
+		},
+		{
+			name:           "probability tracestate",
+			recordDecision: RecordDecisionCfg{Probability: ProbabilityTraceState},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25},
+			traceState:     "vendor=value,ot=th:8",
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, 0, span.Attributes().Len())
+				assert.Equal(t, "ot=th:e,vendor=value", span.TraceState().AsRaw())
+			},
+		},
+		{
+			name:           "consistent threshold",
+			recordDecision: RecordDecisionCfg{},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25, threshold: "c"},
+			traceState:     "vendor=value,ot=rv:f0000000000000",
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, 0, span.Attributes().Len())
+				assert.Equal(t, "ot=rv:f0000000000000;th:c,vendor=value", span.TraceState().AsRaw())
+			},
+		},
+		{
+			name:           "consistent threshold with probability",
+			recordDecision: RecordDecisionCfg{Probability: ProbabilityTraceState},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25, threshold: "c"},
+			traceState:     "ot=th:8",
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, "ot=th:c", span.TraceState().AsRaw())
+			},
+		},
+		{
+			name:           "maximum probability of the sampling policies",
+			recordDecision: RecordDecisionCfg{Probability: ProbabilityAttribute},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25},
+			other:          &probabilisticPolicyEvaluator{probability: 0.5},
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, map[string]any{"tailsampling.probability": 0.5}, span.Attributes().AsRaw())
+			},
+		},
+		{
+			name:           "probability 1 when a non probabilistic policy samples too",
+			recordDecision: RecordDecisionCfg{Probability: ProbabilityAttribute},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25},
+			other:          &mockPolicyEvaluator{NextDecision: sampling.Sampled},
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, map[string]any{"tailsampling.probability": 1.0}, span.Attributes().AsRaw())
+			},
+		},
+		{
+			name:           "lowest threshold of the sampling policies",
+			recordDecision: RecordDecisionCfg{},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25, threshold: "c"},
+			other:          &probabilisticPolicyEvaluator{probability: 0.5, threshold: "8"},
+			traceState:     "ot=rv:f0000000000000",
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, "ot=rv:f0000000000000;th:8", span.TraceState().AsRaw())
+			},
+		},
+		{
+			name:           "no threshold when a non probabilistic policy samples too",
+			recordDecision: RecordDecisionCfg{},
+			evaluator:      &probabilisticPolicyEvaluator{probability: 0.25, threshold: "c"},
+			other:          &mockPolicyEvaluator{NextDecision: sampling.Sampled},
+			traceState:     "ot=rv:f0000000000000",
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, "ot=rv:f0000000000000", span.TraceState().AsRaw())
+			},
+		},
+		{
+			name:           "no probability for non probabilistic policy",
+			recordDecision: RecordDecisionCfg{Probability: ProbabilityAttribute},
+			evaluator:      &mockPolicyEvaluator{NextDecision: sampling.Sampled},
+			check: func(t *testing.T, span ptrace.Span) {
+				assert.Equal(t, 0, span.Attributes().Len())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := new(consumertest.TracesSink)
+			policies := []*policy{
+				{
+					// Reports a probability without sampling, which must not be taken into account.
+					name:      "not-sampling-policy",
+					evaluator: &probabilisticNotSampledEvaluator{},
+					attribute: metric.WithAttributes(attribute.String("policy", "not-sampling-policy")),
+				},
+				{
+					name:      "deciding-policy",
+					evaluator: tt.evaluator,
+					attribute: metric.WithAttributes(attribute.String("policy", "deciding-policy")),
+				},
+			}
+			if tt.other != nil {
+				policies = append(policies, &policy{
+					name:      "other-policy",
+					evaluator: tt.other,
+					attribute: metric.WithAttributes(attribute.String("policy", "other-policy")),
+				})
+			}
+			cfg := Config{
+				DecisionWait:   defaultTestDecisionWait,
+				NumTraces:      defaultNumTraces,
+				RecordDecision: tt.recordDecision,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies(policies),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+			tsp := p.(*tailSamplingSpanProcessor)
+
+			traceID := pcommon.TraceID([16]byte{1, 2, 3, 4})
+			traces := simpleTracesWithID(traceID)
+			traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceState().FromRaw(tt.traceState)
+			require.NoError(t, tsp.ConsumeTraces(context.Background(), traces))
+			tsp.policyTicker.OnTick()
+			tsp.policyTicker.OnTick()
+
+			// Late spans get the same records.
+			lateTraces := simpleTracesWithID(traceID)
+			lateTraces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceState().FromRaw(tt.traceState)
+			require.NoError(t, tsp.ConsumeTraces(context.Background(), lateTraces))
+
+			require.Len(t, sink.AllTraces(), 2)
+			for _, td := range sink.AllTraces() {
+				tt.check(t, td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0))
+			}
+		})
+	}
+}
+
+// probabilisticNotSampledEvaluator does not sample any trace, while reporting a sampling probability.
+type probabilisticNotSampledEvaluator struct{}
+
+func (*probabilisticNotSampledEvaluator) Evaluate(_ context.Context, _ pcommon.TraceID, trace *sampling.TraceData) (sampling.Decision, error) {
+	trace.SetSampling(0.5, "")
+	return sampling.NotSampled, nil
+}
+
+func TestRecordDecisionNotOnSampledCacheHits(t *testing.T) {
+	sink := new(consumertest.TracesSink)
+	sampledCache, err := cache.NewLRUDecisionCache[bool](10)
+	require.NoError(t, err)
+	cfg := Config{
+		DecisionWait:   defaultTestDecisionWait,
+		NumTraces:      defaultNumTraces,
+		RecordDecision: RecordDecisionCfg{Policy: true, Probability: ProbabilityAttribute},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			WithSampledDecisionCache(sampledCache),
+			withPolicies([]*policy{
+				{
+					name:      "deciding-policy",
+					evaluator: &probabilisticPolicyEvaluator{probability: 0.25},
+					attribute: metric.WithAttributes(attribute.String("policy", "deciding-policy")),
+				},
+			}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+	}()
+	tsp := p.(*tailSamplingSpanProcessor)
+
+	traceID := pcommon.TraceID([16]byte{1, 2, 3, 4})
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(traceID)))
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+
+	// The trace was dropped from memory once cached, its late spans are released as they are.
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(traceID)))
+
+	require.Len(t, sink.AllTraces(), 2)
+	assert.Equal(t, map[string]any{
+		"tailsampling.policy":      "deciding-policy",
+		"tailsampling.probability": 0.25,
+	}, sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().AsRaw())
+	assert.Equal(t, 0, sink.AllTraces()[1].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Len())
+}
+
+func TestRecordDecisionInvalidProbabilityFormat(t *testing.T) {
+	cfg := Config{
+		DecisionWait:   defaultTestDecisionWait,
+		NumTraces:      defaultNumTraces,
+		RecordDecision: RecordDecisionCfg{Probability: "unknown"},
+	}
+	assert.EqualError(t, cfg.Validate(), `unknown sampling probability format "unknown"`)
+}
@@ -0,0 +1,119 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/routing"
+)
+
+const (
+	defaultRoutingTimeout     = 5 * time.Second
+	defaultRoutingDNSPort     = "4317"
+	defaultRoutingDNSInterval = 5 * time.Second
+	defaultRoutingDNSTimeout  = time.Second
+)
+
+var (
+	attrRouteLocal     = metric.WithAttributes(attribute.String("route", "local"))
+	attrRouteForwarded = metric.WithAttributes(attribute.String("route", "forwarded"))
+	attrRouteFallback  = metric.WithAttributes(attribute.String("route", "fallback"))
+)
+
+// newRouter returns the router of spans among the peers, or nil if routing is disabled.
+func newRouter(logger *zap.Logger, cfg RoutingCfg) (*routing.Router, error) {
+	var resolver routing.Resolver
+	switch {
+	case len(cfg.Static.Endpoints) > 0 && cfg.DNS.Hostname != "":
+		return nil, errors.New("routing peers must be either static or resolved from DNS, not both")
+	case len(cfg.Static.Endpoints) > 0:
+		resolver = routing.NewStaticResolver(cfg.Static.Endpoints)
+	case cfg.DNS.Hostname != "":
+		port := cfg.DNS.Port
+		if port == "" {
+			port = defaultRoutingDNSPort
+		}
+		interval := cfg.DNS.Interval
+		if interval <= 0 {
+			interval = defaultRoutingDNSInterval
+		}
+		timeout := cfg.DNS.Timeout
+		if timeout <= 0 {
+			timeout = defaultRoutingDNSTimeout
+		}
+		resolver = routing.NewDNSResolver(logger, cfg.DNS.Hostname, port, interval, timeout)
+	default:
+		return nil, nil
+	}
+
+	if cfg.Self == "" {
+		return nil, errors.New("routing requires the endpoint of this instance among the peers")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRoutingTimeout
+	}
+	return routing.NewRouter(logger, cfg.Self, resolver, timeout), nil
+}
+
+// startRouter starts resolving the peers, connecting to them with the gRPC client settings of the configuration.
+func (tsp *tailSamplingSpanProcessor) startRouter(ctx context.Context, host component.Host) error {
+	return tsp.router.Start(ctx, func(ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
+		cfg := tsp.routingClient
+		cfg.Endpoint = endpoint
+		return cfg.ToClientConn(ctx, host, tsp.set.TelemetrySettings)
+	})
+}
+
+// forwarding is the spans forwarded to a peer, and the error forwarding them.
+type forwarding struct {
+	endpoint string
+	td       ptrace.Traces
+	err      error
+}
+
+// routeTraces forwards the spans of traces owned by other peers to them, concurrently, and returns the spans to be
+// sampled by this instance. Spans that can't be forwarded are sampled by this instance.
+func (tsp *tailSamplingSpanProcessor) routeTraces(ctx context.Context, td ptrace.Traces) ptrace.Traces {
+	local, remote := tsp.router.Split(td)
+	forwardings := make([]forwarding, 0, len(remote))
+	for endpoint, peerTd := range remote {
+		forwardings = append(forwardings, forwarding{endpoint: endpoint, td: peerTd})
+	}
+	var wg sync.WaitGroup
+	for i := range forwardings {
+		f := &forwardings[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.err = tsp.router.Forward(ctx, f.endpoint, f.td)
+		}()
+	}
+	wg.Wait()
+
+	for _, f := range forwardings {
+		spanCount := int64(f.td.SpanCount())
+		if f.err != nil {
+			tsp.logger.Warn("Failed to forward spans to their tail sampling peer, sampling them locally",
+				zap.String("peer", f.endpoint), zap.Error(f.err))
+			f.td.ResourceSpans().MoveAndAppendTo(local.ResourceSpans())
+			tsp.telemetry.ProcessorTailSamplingRoutedSpans.Add(tsp.ctx, spanCount, attrRouteFallback)
+			continue
+		}
+		tsp.telemetry.ProcessorTailSamplingRoutedSpans.Add(tsp.ctx, spanCount, attrRouteForwarded)
+	}
+	tsp.telemetry.ProcessorTailSamplingRoutedSpans.Add(tsp.ctx, int64(local.SpanCount()), attrRouteLocal)
+	return local
+}
@@ -0,0 +1,214 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configgrpc"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/routing"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+// insecureClient returns the gRPC client settings of the connections to the test peers, without TLS.
+func insecureClient(t *testing.T) configgrpc.ClientConfig {
+	cfg := configgrpc.NewDefaultClientConfig()
+	require.NoError(t, confmap.NewFromStringMap(map[string]any{"tls": map[string]any{"insecure": true}}).Unmarshal(cfg))
+	return *cfg
+}
+
+// peerReceiver is an OTLP/gRPC server passing the spans it receives to a sink.
+type peerReceiver struct {
+	ptraceotlp.UnimplementedGRPCServer
+	sink *consumertest.TracesSink
+}
+
+func (r *peerReceiver) Export(ctx context.Context, req ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	return ptraceotlp.NewExportResponse(), r.sink.ConsumeTraces(ctx, req.Traces())
+}
+
+// startPeerReceiver starts an OTLP/gRPC server and returns its endpoint.
+func startPeerReceiver(t *testing.T, sink *consumertest.TracesSink) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	ptraceotlp.RegisterGRPCServer(srv, &peerReceiver{sink: sink})
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// unusedEndpoint returns an endpoint nothing listens on.
+func unusedEndpoint(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	return endpoint
+}
+
+func TestRouting(t *testing.T) {
+	tests := []struct {
+		name            string
+		peerUp          bool
+		expectForwarded bool
+	}{
+		{
+			name:            "forwarded to peer",
+			peerUp:          true,
+			expectForwarded: true,
+		},
+		{
+			name:   "sampled locally when peer is down",
+			peerUp: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peerSink := new(consumertest.TracesSink)
+			peer := unusedEndpoint(t)
+			if tt.peerUp {
+				peer = startPeerReceiver(t, peerSink)
+			}
+			self := unusedEndpoint(t)
+
+			sink := new(consumertest.TracesSink)
+			mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    defaultNumTraces,
+				Routing: RoutingCfg{
+					Self:    self,
+					Static:  StaticRoutingCfg{Endpoints: []string{self, peer}},
+					Timeout: time.Second,
+					Client:  insecureClient(t),
+				},
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{
+						{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			const numTraces = 100
+			for i := uint64(1); i <= numTraces; i++ {
+				require.NoError(t, p.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(i))))
+			}
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.policyTicker.OnTick() // the first tick always gets an empty batch
+			tsp.policyTicker.OnTick()
+
+			assert.Equal(t, numTraces, sink.SpanCount()+peerSink.SpanCount())
+			if tt.expectForwarded {
+				assert.Positive(t, peerSink.SpanCount())
+				assert.Positive(t, sink.SpanCount())
+				assert.Equal(t, sink.SpanCount(), mpe.EvaluationCount)
+			} else {
+				assert.Zero(t, peerSink.SpanCount())
+				assert.Equal(t, numTraces, mpe.EvaluationCount)
+			}
+		})
+	}
+}
+
+func TestForwardedSpansAreSampledLocally(t *testing.T) {
+	peerSink := new(consumertest.TracesSink)
+	peer := startPeerReceiver(t, peerSink)
+	self := unusedEndpoint(t)
+
+	sink := new(consumertest.TracesSink)
+	mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		Routing: RoutingCfg{
+			Self:    self,
+			Static:  StaticRoutingCfg{Endpoints: []string{self, peer}},
+			Timeout: time.Second,
+			Client:  insecureClient(t),
+		},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{
+				{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+			}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+	}()
+
+	// The spans were forwarded by a peer, they aren't forwarded again even though this instance sees another owner.
+	ctx := client.NewContext(context.Background(), client.Info{
+		Metadata: client.NewMetadata(map[string][]string{routing.ForwardedMetadataKey: {"true"}}),
+	})
+	const numTraces = 100
+	for i := uint64(1); i <= numTraces; i++ {
+		require.NoError(t, p.ConsumeTraces(ctx, simpleTracesWithID(uInt64ToTraceID(i))))
+	}
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.policyTicker.OnTick() // the first tick always gets an empty batch
+	tsp.policyTicker.OnTick()
+
+	assert.Zero(t, peerSink.SpanCount())
+	assert.Equal(t, numTraces, sink.SpanCount())
+}
+
+func TestRoutingConfigErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		routing     RoutingCfg
+		expectedErr string
+	}{
+		{
+			name:        "static and dns",
+			routing:     RoutingCfg{Self: "a:4317", Static: StaticRoutingCfg{Endpoints: []string{"a:4317"}}, DNS: DNSRoutingCfg{Hostname: "collector"}},
+			expectedErr: "routing peers must be either static or resolved from DNS, not both",
+		},
+		{
+			name:        "missing self",
+			routing:     RoutingCfg{DNS: DNSRoutingCfg{Hostname: "collector"}},
+			expectedErr: "routing requires the endpoint of this instance among the peers",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    defaultNumTraces,
+				Routing:      tt.routing,
+			}
+			_, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
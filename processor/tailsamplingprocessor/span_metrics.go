@@ -0,0 +1,123 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spanmetrics"
+)
+
+const defaultSpanMetricsInterval = 15 * time.Second
+
+// defaultSpanMetricsBuckets are the default bounds of the buckets of the duration histogram, the same as the
+// spanmetrics connector.
+var defaultSpanMetricsBuckets = []time.Duration{
+	2 * time.Millisecond,
+	4 * time.Millisecond,
+	6 * time.Millisecond,
+	8 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	400 * time.Millisecond,
+	800 * time.Millisecond,
+	time.Second,
+	1400 * time.Millisecond,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+}
+
+// defaultSpanMetricsMaxSeries is the default maximum number of series of the metrics of the spans.
+const defaultSpanMetricsMaxSeries = 1000
+
+// newSpanMetrics returns the aggregator of the metrics of the spans.
+func newSpanMetrics(cfg SpanMetricsCfg) *spanmetrics.Aggregator {
+	buckets := cfg.Buckets
+	if len(buckets) == 0 {
+		buckets = defaultSpanMetricsBuckets
+	}
+	maxSeries := cfg.MaxSeries
+	if maxSeries <= 0 {
+		maxSeries = defaultSpanMetricsMaxSeries
+	}
+	return spanmetrics.New(metadata.ScopeName, buckets, maxSeries)
+}
+
+// recordSpanMetrics aggregates the metrics of the spans of a trace, if the processor is part of a metrics pipeline.
+func (tsp *tailSamplingSpanProcessor) recordSpanMetrics(td ptrace.Traces, sampled bool) {
+	if tsp.spanMetrics != nil {
+		tsp.spanMetrics.Add(td, sampled)
+	}
+}
+
+// recordLateSpanMetrics aggregates the metrics of late spans, if the processor is part of a metrics pipeline.
+func (tsp *tailSamplingSpanProcessor) recordLateSpanMetrics(resourceSpans ptrace.ResourceSpans, spans []spanAndScope, sampled bool) {
+	if tsp.spanMetrics == nil {
+		return
+	}
+	traceTd := ptrace.NewTraces()
+	appendToTraces(traceTd, resourceSpans, spans)
+	tsp.spanMetrics.Add(traceTd, sampled)
+}
+
+// startSpanMetrics emits the metrics of the spans every interval, until stopSpanMetrics is called.
+func (tsp *tailSamplingSpanProcessor) startSpanMetrics() {
+	if tsp.spanMetrics == nil {
+		return
+	}
+	tsp.spanMetricsStop = make(chan struct{})
+	tsp.spanMetricsDone = make(chan struct{})
+	go func() {
+		defer close(tsp.spanMetricsDone)
+		ticker := time.NewTicker(tsp.spanMetricsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tsp.spanMetricsStop:
+				return
+			case <-ticker.C:
+				tsp.emitSpanMetrics(context.Background())
+			}
+		}
+	}()
+}
+
+// stopSpanMetrics stops emitting the metrics of the spans, and emits them a last time.
+func (tsp *tailSamplingSpanProcessor) stopSpanMetrics(ctx context.Context) {
+	if tsp.spanMetricsStop == nil {
+		return
+	}
+	close(tsp.spanMetricsStop)
+	<-tsp.spanMetricsDone
+	tsp.spanMetricsStop = nil
+	tsp.emitSpanMetrics(ctx)
+}
+
+func (tsp *tailSamplingSpanProcessor) emitSpanMetrics(ctx context.Context) {
+	md := tsp.spanMetrics.Metrics()
+	if md.ResourceMetrics().Len() == 0 {
+		return
+	}
+	if err := tsp.metricsConsumer.ConsumeMetrics(ctx, md); err != nil {
+		tsp.logger.Warn(
+			"Error sending span metrics to destination",
+			zap.Error(err))
+	}
+}
+
+// ConsumeMetrics is required by the processor.Metrics interface. Metrics are passed through, the processor only adds
+// the metrics of the spans to them.
+func (tsp *tailSamplingSpanProcessor) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	return tsp.metricsConsumer.ConsumeMetrics(ctx, md)
+}
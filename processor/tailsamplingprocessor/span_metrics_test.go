@@ -0,0 +1,185 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spanmetrics"
+)
+
+func TestSpanMetricsOfAllTraces(t *testing.T) {
+	traceSink := new(consumertest.TracesSink)
+	metricSink := new(consumertest.MetricsSink)
+	mpe := &mockPolicyEvaluator{}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))}}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), traceSink, cfg)
+	require.NoError(t, err)
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.metricsConsumer = metricSink
+	tsp.spanMetrics = newSpanMetrics(SpanMetricsCfg{})
+	tsp.spanMetricsInterval = defaultSpanMetricsInterval
+	require.NoError(t, tsp.Start(context.Background(), componenttest.NewNopHost()))
+
+	mpe.NextDecision = sampling.NotSampled
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(1))))
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+	mpe.NextDecision = sampling.Sampled
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(2))))
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+
+	// Nothing is emitted before the interval.
+	assert.Empty(t, metricSink.AllMetrics())
+	assert.Equal(t, 1, traceSink.SpanCount())
+
+	// The metrics are emitted a last time on shutdown.
+	require.NoError(t, tsp.Shutdown(context.Background()))
+	require.Len(t, metricSink.AllMetrics(), 1)
+	md := metricSink.AllMetrics()[0]
+	require.Equal(t, 1, md.ResourceMetrics().Len())
+	metrics := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 2, metrics.Len())
+
+	calls := metrics.At(0)
+	assert.Equal(t, spanmetrics.CallsMetric, calls.Name())
+	require.Equal(t, 1, calls.Sum().DataPoints().Len())
+	assert.Equal(t, int64(2), calls.Sum().DataPoints().At(0).IntValue())
+
+	durations := metrics.At(1)
+	assert.Equal(t, spanmetrics.DurationMetric, durations.Name())
+	hdp := durations.Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(2), hdp.Count())
+	require.Equal(t, 1, hdp.Exemplars().Len())
+	assert.Equal(t, uInt64ToTraceID(2), hdp.Exemplars().At(0).TraceID())
+}
+
+func TestSpanMetricsOfLateSpans(t *testing.T) {
+	metricSink := new(consumertest.MetricsSink)
+	mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))}}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+	require.NoError(t, err)
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.metricsConsumer = metricSink
+	tsp.spanMetrics = newSpanMetrics(SpanMetricsCfg{})
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+
+	traceID := uInt64ToTraceID(1)
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(traceID)))
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(traceID)))
+
+	tsp.emitSpanMetrics(context.Background())
+	require.Len(t, metricSink.AllMetrics(), 1)
+	hdp := metricSink.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(1).Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(2), hdp.Count())
+	assert.Equal(t, 2, hdp.Exemplars().Len())
+}
+
+func TestSpanMetricsOfEvictedTraces(t *testing.T) {
+	metricSink := new(consumertest.MetricsSink)
+	mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    1,
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))}}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+	require.NoError(t, err)
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.metricsConsumer = metricSink
+	tsp.spanMetrics = newSpanMetrics(SpanMetricsCfg{})
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+
+	// The first trace is evicted by the second one before its decision, its span is counted without exemplar.
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(1))))
+	require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(2))))
+
+	tsp.emitSpanMetrics(context.Background())
+	require.Len(t, metricSink.AllMetrics(), 1)
+	hdp := metricSink.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(1).Histogram().DataPoints().At(0)
+	assert.Equal(t, uint64(1), hdp.Count())
+	assert.Equal(t, 0, hdp.Exemplars().Len())
+}
+
+func TestSeveralMetricsPipelines(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	params := processortest.NewNopSettings(metadata.Type)
+
+	mp, err := factory.CreateMetrics(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	_, err = factory.CreateMetrics(context.Background(), params, cfg, consumertest.NewNop())
+	require.ErrorIs(t, err, errSeveralMetricsPipelines)
+
+	// A second traces pipeline can't be used along with the metrics pipeline either.
+	tp, err := factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	_, err = factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.ErrorIs(t, err, errSharedWithSeveralTracesPipelines)
+
+	require.NoError(t, mp.Shutdown(context.Background()))
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func TestMetricsArePassedThrough(t *testing.T) {
+	metricSink := new(consumertest.MetricsSink)
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	params := processortest.NewNopSettings(metadata.Type)
+	tp, err := factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	mp, err := factory.CreateMetrics(context.Background(), params, cfg, metricSink)
+	require.NoError(t, err)
+	assert.Same(t, tp.(*tracesProcessor).tsp, mp.(*metricsProcessor).tsp)
+
+	host := componenttest.NewNopHost()
+	require.NoError(t, tp.Start(context.Background(), host))
+	require.NoError(t, mp.Start(context.Background(), host))
+
+	md := pmetric.NewMetrics()
+	md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetName("requests")
+	require.NoError(t, mp.ConsumeMetrics(context.Background(), md))
+
+	require.NoError(t, mp.Shutdown(context.Background()))
+	require.NoError(t, tp.Shutdown(context.Background()))
+	require.Len(t, metricSink.AllMetrics(), 1)
+	assert.Equal(t, md, metricSink.AllMetrics()[0])
+}
@@ -0,0 +1,132 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/spill"
+)
+
+// newSpillStore returns the store of spilled traces of the processor with the given ID, or nil if spilling is disabled.
+func newSpillStore(cfg SpillCfg, id component.ID) (*spill.Store, error) {
+	if cfg.Directory == "" {
+		return nil, nil
+	}
+	dir, err := instanceDirectory(cfg.Directory, id)
+	if err != nil {
+		return nil, err
+	}
+	return spill.New(dir, cfg.MaxSizeMiB<<20)
+}
+
+// instanceDirectory returns the subdirectory of the given directory holding the files of the processor with the given
+// ID, creating it if needed, so that processors configured with the same directory don't overwrite each other's files.
+// The given directory must exist.
+func instanceDirectory(dir string, id component.ID) (string, error) {
+	instanceDir := filepath.Join(dir, strings.ReplaceAll(id.String(), "/", "_"))
+	if err := os.Mkdir(instanceDir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", err
+	}
+	return instanceDir, nil
+}
+
+// closeSpillStore removes the spilled traces, if any.
+func closeSpillStore(s *spill.Store) error {
+	if s == nil {
+		return nil
+	}
+	return s.Close()
+}
+
+// evictTrace removes a trace from memory to make room for a new one. The spans of a trace pending a decision are
+// spilled to disk if enabled, to be reloaded when the decision is made. Otherwise, they are counted in the metrics of the
+// spans now, as they won't be decided.
+//
+// The trace is removed from memory under its lock, so that spans added concurrently are either spilled with the trace,
+// or added after it was spilled, see processTraces.
+func (tsp *tailSamplingSpanProcessor) evictTrace(id pcommon.TraceID, deletionTime time.Time) {
+	d, ok := tsp.idToTrace.Load(id)
+	if !ok {
+		tsp.logger.Debug("Attempt to evict trace ID not on table", zap.Stringer("id", id))
+		return
+	}
+	trace := d.(*sampling.TraceData)
+
+	trace.Lock()
+	defer trace.Unlock()
+	if trace.FinalDecision == sampling.Unspecified && !tsp.spillTrace(id, trace) {
+		tsp.recordSpanMetrics(trace.ReceivedBatches, false)
+	}
+	tsp.dropTrace(id, deletionTime)
+}
+
+// spillTrace spills the spans of a trace pending a decision, which must be locked. It returns false if spilling is
+// disabled or failed.
+func (tsp *tailSamplingSpanProcessor) spillTrace(id pcommon.TraceID, trace *sampling.TraceData) bool {
+	if tsp.spill == nil {
+		return false
+	}
+	if err := tsp.spill.Spill(id, trace.ReceivedBatches, trace.ArrivalTime, trace.SpanCount.Load(), trace.Stages); err != nil {
+		tsp.logger.Warn("Failed to spill trace, dropping it", zap.Stringer("id", id), zap.Error(err))
+		return false
+	}
+	trace.ReceivedBatches = ptrace.NewTraces()
+	tsp.telemetry.ProcessorTailSamplingSpilledTraces.Add(tsp.ctx, 1)
+	return true
+}
+
+// appendToSpilledTrace adds spans to a spilled trace. It returns false if the trace isn't spilled. The spans are dropped
+// if they can't be spilled, e.g.: when the spill directory is full.
+func (tsp *tailSamplingSpanProcessor) appendToSpilledTrace(id pcommon.TraceID, resourceSpans ptrace.ResourceSpans, spans []spanAndScope) bool {
+	if tsp.spill == nil || !tsp.spill.Contains(id) {
+		return false
+	}
+
+	traceTd := ptrace.NewTraces()
+	appendToTraces(traceTd, resourceSpans, spans)
+	ok, err := tsp.spill.Append(id, traceTd, int64(len(spans)))
+	if err != nil {
+		tsp.logger.Warn("Failed to spill spans, dropping them", zap.Stringer("id", id), zap.Error(err))
+		tsp.telemetry.ProcessorTailSamplingSpilledSpansDropped.Add(tsp.ctx, int64(len(spans)))
+	}
+	return ok
+}
+
+// reloadSpilledTrace removes a trace from the spilled traces and returns it, or nil if the trace isn't spilled.
+func (tsp *tailSamplingSpanProcessor) reloadSpilledTrace(id pcommon.TraceID) *sampling.TraceData {
+	if tsp.spill == nil {
+		return nil
+	}
+
+	spilled, ok, err := tsp.spill.Load(id)
+	if !ok {
+		return nil
+	}
+	if err != nil {
+		tsp.logger.Warn("Failed to reload spilled trace", zap.Stringer("id", id), zap.Error(err))
+	}
+
+	spanCount := &atomic.Int64{}
+	spanCount.Store(spilled.SpanCount)
+	stages, _ := spilled.State.(sampling.EvaluatedStages)
+	return &sampling.TraceData{
+		ArrivalTime:     spilled.ArrivalTime,
+		SpanCount:       spanCount,
+		ReceivedBatches: spilled.Batches,
+		Stages:          stages,
+	}
+}
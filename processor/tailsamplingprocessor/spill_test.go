@@ -0,0 +1,228 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func TestSpillPendingTraces(t *testing.T) {
+	tests := []struct {
+		name          string
+		spill         SpillCfg
+		expectedSpans int
+	}{
+		{
+			name:          "disabled",
+			expectedSpans: 2, // the first span of the first trace is dropped, then the second trace when the late span arrives
+		},
+		{
+			name:          "enabled",
+			spill:         SpillCfg{Directory: t.TempDir(), MaxSizeMiB: 1},
+			expectedSpans: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := new(consumertest.TracesSink)
+			mpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    2,
+				Spill:        tt.spill,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies([]*policy{
+						{name: "mock-policy", evaluator: mpe, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			// The third trace evicts the first one from memory, which later receives a span.
+			for _, id := range []uint64{1, 2, 3, 1} {
+				require.NoError(t, p.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(id))))
+			}
+
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.policyTicker.OnTick() // the first tick always gets an empty batch
+			tsp.policyTicker.OnTick()
+
+			assert.Equal(t, tt.expectedSpans, sink.SpanCount())
+			if tt.spill.Directory != "" {
+				assert.Zero(t, tsp.spill.Size())
+				files, err := os.ReadDir(filepath.Join(tt.spill.Directory, metadata.Type.String()))
+				require.NoError(t, err)
+				assert.Empty(t, files)
+			}
+		})
+	}
+}
+
+func TestSpillConfigErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		spill SpillCfg
+	}{
+		{
+			name:  "missing directory",
+			spill: SpillCfg{Directory: filepath.Join(t.TempDir(), "missing"), MaxSizeMiB: 1},
+		},
+		{
+			name:  "invalid max size",
+			spill: SpillCfg{Directory: t.TempDir()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    defaultNumTraces,
+				Spill:        tt.spill,
+			}
+			_, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSpillDirectorySharedByProcessors(t *testing.T) {
+	dir := t.TempDir()
+	newProcessor := func(name string, sink *consumertest.TracesSink) *tailSamplingSpanProcessor {
+		cfg := Config{
+			DecisionWait: defaultTestDecisionWait,
+			NumTraces:    1,
+			Spill:        SpillCfg{Directory: dir, MaxSizeMiB: 1},
+			DecisionCache: DecisionCacheConfig{
+				Type:             DiskDecisionCache,
+				SampledCacheSize: 10,
+				Disk:             DiskDecisionCacheConfig{Directory: dir},
+			},
+			Options: []Option{
+				withDecisionBatcher(newSyncIDBatcher()),
+				withPolicies([]*policy{
+					{name: "mock-policy", evaluator: &mockPolicyEvaluator{NextDecision: sampling.Sampled}, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+				}),
+			},
+		}
+		set := processortest.NewNopSettings(metadata.Type)
+		set.ID = component.NewIDWithName(metadata.Type, name)
+		p, err := newTracesProcessor(context.Background(), set, sink, cfg)
+		require.NoError(t, err)
+		require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+		t.Cleanup(func() {
+			require.NoError(t, p.Shutdown(context.Background()))
+		})
+		return p.(*tailSamplingSpanProcessor)
+	}
+
+	// The first trace of the processor is spilled by the second one.
+	sinkA := new(consumertest.TracesSink)
+	tspA := newProcessor("a", sinkA)
+	for _, id := range []uint64{1, 2} {
+		require.NoError(t, tspA.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(id))))
+	}
+	require.Positive(t, tspA.spill.Size())
+
+	// Another processor using the same directories keeps its files apart.
+	newProcessor("b", new(consumertest.TracesSink))
+	for _, name := range []string{"tail_sampling_a", "tail_sampling_b"} {
+		_, err := os.Stat(filepath.Join(dir, name, "sampled_decisions.log"))
+		require.NoError(t, err)
+	}
+
+	tspA.policyTicker.OnTick()
+	tspA.policyTicker.OnTick()
+	assert.Equal(t, 2, sinkA.SpanCount())
+}
+
+func TestSpillRejectsSeveralTracesPipelines(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Spill.Directory = t.TempDir()
+	params := processortest.NewNopSettings(metadata.Type)
+
+	tp, err := factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	_, err = factory.CreateTraces(context.Background(), params, cfg, consumertest.NewNop())
+	require.ErrorIs(t, err, errDiskSharedWithSeveralTracesPipelines)
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func TestSpillDropsSpansWhenFull(t *testing.T) {
+	s := setupTestTelemetry()
+	sink := new(consumertest.TracesSink)
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    1,
+		Spill:        SpillCfg{Directory: t.TempDir(), MaxSizeMiB: 1},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+			withPolicies([]*policy{
+				{name: "mock-policy", evaluator: &mockPolicyEvaluator{NextDecision: sampling.Sampled}, attribute: metric.WithAttributes(attribute.String("policy", "mock-policy"))},
+			}),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), s.newSettings(), sink, cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+	}()
+
+	largeTraces := func(id uint64) ptrace.Traces {
+		td := simpleTracesWithID(uInt64ToTraceID(id))
+		td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().PutStr("payload", strings.Repeat("x", 600<<10))
+		return td
+	}
+	// The second trace evicts the first one, which is spilled. The next span of the first trace doesn't fit in the
+	// spill directory and is dropped.
+	require.NoError(t, p.ConsumeTraces(context.Background(), largeTraces(1)))
+	require.NoError(t, p.ConsumeTraces(context.Background(), simpleTracesWithID(uInt64ToTraceID(2))))
+	require.NoError(t, p.ConsumeTraces(context.Background(), largeTraces(1)))
+
+	tsp := p.(*tailSamplingSpanProcessor)
+	tsp.policyTicker.OnTick() // the first tick always gets an empty batch
+	tsp.policyTicker.OnTick()
+
+	assert.Equal(t, 2, sink.SpanCount())
+
+	var md metricdata.ResourceMetrics
+	require.NoError(t, s.reader.Collect(context.Background(), &md))
+	m := metricdata.Metrics{
+		Name:        "otelcol_processor_tail_sampling_spilled_spans_dropped",
+		Description: "Count of spans of spilled traces dropped because they couldn't be written to disk, such as when the spill directory is full.",
+		Unit:        "{spans}",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  []metricdata.DataPoint[int64]{{Value: 1}},
+		},
+	}
+	metricdatatest.AssertEqual(t, m, s.getMetric(m.Name, md), metricdatatest.IgnoreTimestamp())
+}
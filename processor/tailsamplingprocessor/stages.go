@@ -0,0 +1,151 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/idbatcher"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+// decisionStage evaluates the policies with a decision_wait shorter than the one of the processor. Each trace goes
+// through all the stages, in order of decision_wait, until one samples or drops it. Traces left undecided are decided
+// by the remaining policies after the decision_wait of the processor, combined with the decisions of the stages.
+type decisionStage struct {
+	decisionWait time.Duration
+	batcher      idbatcher.Batcher
+}
+
+// newDecisionStages returns a stage for each distinct decision_wait of the policies shorter than the one of the
+// processor.
+func newDecisionStages(cfg Config) ([]*decisionStage, error) {
+	var waits []time.Duration
+	for _, policyCfg := range cfg.PolicyCfgs {
+		if policyCfg.DecisionWait > 0 && policyCfg.DecisionWait < cfg.DecisionWait {
+			waits = append(waits, policyCfg.DecisionWait)
+		}
+	}
+	slices.Sort(waits)
+	waits = slices.Compact(waits)
+
+	stages := make([]*decisionStage, 0, len(waits))
+	for _, wait := range waits {
+		numDecisionBatches := math.Max(1, wait.Seconds())
+		batcher, err := idbatcher.New(uint64(numDecisionBatches), cfg.ExpectedNewTracesPerSec, uint64(2*runtime.NumCPU()))
+		if err != nil {
+			stopDecisionStages(stages)
+			return nil, errors.Join(errors.New("failed to create a decision stage"), err)
+		}
+		stages = append(stages, &decisionStage{decisionWait: wait, batcher: batcher})
+	}
+	return stages, nil
+}
+
+func stopDecisionStages(stages []*decisionStage) {
+	for _, stage := range stages {
+		stage.batcher.Stop()
+	}
+}
+
+// withDecisionStages sets the stages evaluating the policies with a shorter decision_wait.
+func withDecisionStages(stages []*decisionStage) Option {
+	return func(tsp *tailSamplingSpanProcessor) {
+		tsp.decisionStages = stages
+	}
+}
+
+// hasDecisionStage returns whether there's a stage with the given decision_wait.
+func (tsp *tailSamplingSpanProcessor) hasDecisionStage(decisionWait time.Duration) bool {
+	for _, stage := range tsp.decisionStages {
+		if stage.decisionWait == decisionWait {
+			return true
+		}
+	}
+	return false
+}
+
+func (tsp *tailSamplingSpanProcessor) addToDecisionStages(id pcommon.TraceID) {
+	for _, stage := range tsp.decisionStages {
+		stage.batcher.AddToCurrentBatch(id)
+	}
+}
+
+// decideStages makes the sampling decision on the traces due at each stage, if the policies of the stage sample or
+// drop them.
+func (tsp *tailSamplingSpanProcessor) decideStages(ctx context.Context, metrics *policyMetrics) {
+	for i, stage := range tsp.decisionStages {
+		batch, _ := stage.batcher.CloseCurrentAndTakeFirstBatch()
+		policies := stagePolicies(tsp.policies, stage.decisionWait)
+		if len(policies) == 0 {
+			continue
+		}
+		for _, id := range batch {
+			d, ok := tsp.idToTrace.Load(id)
+			if !ok {
+				// The trace was dropped or spilled, it's left to the last stage.
+				continue
+			}
+			trace := d.(*sampling.TraceData)
+			if decided(trace) {
+				continue
+			}
+			tsp.decideTrace(ctx, id, trace, policies, false, metrics)
+			// A trace evicted concurrently is spilled along with its stages.
+			trace.Lock()
+			trace.Stages.Count = i + 1
+			trace.Unlock()
+		}
+	}
+}
+
+// remainingPolicies returns the policies left to evaluate on a trace once its decision_wait elapsed, or once complete:
+// the ones not evaluated by the stages that evaluated the trace, and the drop policies. Shadow policies are evaluated
+// apart, once the decision is made.
+func (tsp *tailSamplingSpanProcessor) remainingPolicies(trace *sampling.TraceData) []*policy {
+	count := min(trace.Stages.Count, len(tsp.decisionStages))
+	if count == 0 {
+		return tsp.policies
+	}
+	evaluated := tsp.decisionStages[count-1].decisionWait
+	policies := make([]*policy, 0, len(tsp.policies))
+	for _, p := range tsp.policies {
+		if p.shadow || (!p.drop && p.decisionWait > 0 && p.decisionWait <= evaluated) {
+			continue
+		}
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// stagePolicies returns the policies evaluated by the stage with the given decision_wait: the ones with this
+// decision_wait, and the drop policies, so that a trace they drop is never sampled by an earlier stage. Shadow policies
+// are evaluated apart, once the decision is made.
+func stagePolicies(policies []*policy, decisionWait time.Duration) []*policy {
+	var stage []*policy
+	hasSampling := false
+	for _, p := range policies {
+		switch {
+		case p.shadow:
+			// Shadow policies are evaluated once the decision is made.
+		case p.drop:
+			stage = append(stage, p)
+		case p.decisionWait == decisionWait:
+			stage = append(stage, p)
+			hasSampling = true
+		}
+	}
+	if !hasSampling {
+		// Only the drop policies would be evaluated, they are left to the last stage.
+		return nil
+	}
+	return stage
+}
@@ -0,0 +1,163 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/idbatcher"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func TestDecisionStages(t *testing.T) {
+	tests := []struct {
+		name             string
+		stageDecision    sampling.Decision
+		finalDecision    sampling.Decision
+		dropDecision     sampling.Decision
+		expectedSpans    int
+		stageEvaluations int
+		finalEvaluations int
+	}{
+		{
+			name:             "sampled by the stage",
+			stageDecision:    sampling.Sampled,
+			finalDecision:    sampling.Sampled,
+			dropDecision:     sampling.NotSampled,
+			expectedSpans:    2, // the late span inherits the decision
+			stageEvaluations: 1,
+		},
+		{
+			name:             "sampled by the last stage",
+			stageDecision:    sampling.NotSampled,
+			finalDecision:    sampling.Sampled,
+			dropDecision:     sampling.NotSampled,
+			expectedSpans:    2, // the late span is buffered until the last stage
+			stageEvaluations: 1, // the stage policy is not evaluated again
+			finalEvaluations: 1,
+		},
+		{
+			name:             "inverted match of the stage sampled by the last stage",
+			stageDecision:    sampling.InvertSampled,
+			finalDecision:    sampling.Abstain,
+			dropDecision:     sampling.Abstain,
+			expectedSpans:    2,
+			stageEvaluations: 1,
+			finalEvaluations: 1,
+		},
+		{
+			name:             "inverted match of the last stage not sampled by the stage",
+			stageDecision:    sampling.NotSampled,
+			finalDecision:    sampling.InvertSampled,
+			dropDecision:     sampling.Abstain,
+			stageEvaluations: 1,
+			finalEvaluations: 1,
+		},
+		{
+			name:             "dropped by the stage",
+			stageDecision:    sampling.Sampled,
+			finalDecision:    sampling.Sampled,
+			dropDecision:     sampling.Dropped,
+			stageEvaluations: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := new(consumertest.TracesSink)
+			stageMpe := &mockPolicyEvaluator{NextDecision: tt.stageDecision}
+			finalMpe := &mockPolicyEvaluator{NextDecision: tt.finalDecision}
+			dropMpe := &mockPolicyEvaluator{NextDecision: tt.dropDecision}
+			shadowMpe := &mockPolicyEvaluator{NextDecision: sampling.Sampled}
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    defaultNumTraces,
+				Options: []Option{
+					// The last stage takes a tick more than the stage.
+					withDecisionBatcher(newDelayedSyncIDBatcher(2)),
+					withDecisionStages([]*decisionStage{{decisionWait: time.Second, batcher: newSyncIDBatcher()}}),
+					withPolicies([]*policy{
+						{name: "shadow-policy", evaluator: shadowMpe, attribute: metric.WithAttributes(attribute.String("policy", "shadow-policy")), decisionWait: time.Second, shadow: true},
+						{name: "drop-policy", evaluator: dropMpe, attribute: metric.WithAttributes(attribute.String("policy", "drop-policy")), drop: true},
+						{name: "stage-policy", evaluator: stageMpe, attribute: metric.WithAttributes(attribute.String("policy", "stage-policy")), decisionWait: time.Second},
+						{name: "final-policy", evaluator: finalMpe, attribute: metric.WithAttributes(attribute.String("policy", "final-policy"))},
+					}),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			traceID := uInt64ToTraceID(1)
+			tsp := p.(*tailSamplingSpanProcessor)
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(traceID, 1, 0)))
+			tsp.policyTicker.OnTick() // the first tick always gets an empty batch
+			tsp.policyTicker.OnTick() // the stage
+			require.NoError(t, p.ConsumeTraces(context.Background(), tracesWithParent(traceID, 2, 1)))
+			tsp.policyTicker.OnTick() // the last stage
+
+			assert.Equal(t, tt.expectedSpans, sink.SpanCount())
+			assert.Equal(t, tt.stageEvaluations, stageMpe.EvaluationCount)
+			assert.Equal(t, tt.finalEvaluations, finalMpe.EvaluationCount)
+			// Whichever stage decides on the trace evaluates the shadow policy.
+			assert.Equal(t, 1, shadowMpe.EvaluationCount)
+		})
+	}
+}
+
+func TestNewDecisionStages(t *testing.T) {
+	cfg := Config{
+		DecisionWait: 30 * time.Second,
+		PolicyCfgs: []PolicyCfg{
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "latency", Type: Latency}},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "errors", Type: StatusCode}, DecisionWait: 2 * time.Second},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "attribute", Type: StringAttribute}, DecisionWait: 10 * time.Second},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "more-errors", Type: StatusCode}, DecisionWait: 2 * time.Second},
+			{sharedPolicyCfg: sharedPolicyCfg{Name: "spans", Type: SpanCount}, DecisionWait: 30 * time.Second},
+		},
+	}
+	stages, err := newDecisionStages(cfg)
+	require.NoError(t, err)
+	defer stopDecisionStages(stages)
+
+	require.Len(t, stages, 2)
+	assert.Equal(t, 2*time.Second, stages[0].decisionWait)
+	assert.Equal(t, 10*time.Second, stages[1].decisionWait)
+}
+
+func TestInvalidPolicyDecisionWait(t *testing.T) {
+	for _, decisionWait := range []time.Duration{-time.Second, 2 * defaultTestDecisionWait} {
+		cfg := Config{
+			DecisionWait: defaultTestDecisionWait,
+			NumTraces:    defaultNumTraces,
+			PolicyCfgs: []PolicyCfg{
+				{sharedPolicyCfg: sharedPolicyCfg{Name: "always", Type: AlwaysSample}, DecisionWait: decisionWait},
+			},
+		}
+		_, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), consumertest.NewNop(), cfg)
+		require.ErrorContains(t, err, `invalid decision_wait of policy "always"`)
+	}
+}
+
+// newDelayedSyncIDBatcher returns a syncIDBatcher returning the IDs added to a batch after the given number of batches.
+func newDelayedSyncIDBatcher(numBatches int) idbatcher.Batcher {
+	batches := make(chan idbatcher.Batch, numBatches)
+	for i := 0; i < numBatches; i++ {
+		batches <- nil
+	}
+	return &syncIDBatcher{batchPipe: batches}
+}
@@ -0,0 +1,133 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/debugextension"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+// trajectoryzPath is the path of the pages listing the trajectories known to the stratified policies, followed by the
+// ID of the processor, e.g.: "/debug/trajectoryz/tail_sampling".
+const trajectoryzPath = "/debug/trajectoryz/"
+
+// policyCatalog is the trajectory catalog of a policy.
+type policyCatalog struct {
+	name    string
+	catalog sampling.TrajectoryCatalog
+}
+
+// policyTrajectories is the content of the trajectoryz page for a single policy.
+type policyTrajectories struct {
+	Policy       string                    `json:"policy"`
+	Trajectories []sampling.TrajectoryInfo `json:"trajectories"`
+}
+
+var trajectoryzTemplate = template.Must(template.New("trajectoryz").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Trajectories</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.edges { font-family: monospace; white-space: pre; }
+</style>
+</head>
+<body>
+<h1>Trajectories</h1>
+<p>Also available as <a href="?format=json">JSON</a>.</p>
+{{range .}}
+<h2>{{.Policy}}</h2>
+<table>
+<tr><th rowspan="2">Trajectory</th><th rowspan="2">Edges</th><th colspan="3">Current window</th><th colspan="3">Previous window</th></tr>
+<tr><th>Seen</th><th>Sampled</th><th>Ratio</th><th>Seen</th><th>Sampled</th><th>Ratio</th></tr>
+{{range .Trajectories}}
+<tr>
+<td>{{.Hash}}</td>
+<td class="edges">{{range .Edges}}{{.}}
+{{end}}</td>
+<td>{{.Current.Seen}}</td><td>{{.Current.Sampled}}</td><td>{{printf "%.3f" .Current.SampledRatio}}</td>
+<td>{{.Previous.Seen}}</td><td>{{.Previous.Sampled}}</td><td>{{printf "%.3f" .Previous.SampledRatio}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No policy keeps track of trajectories.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// setTrajectoryCatalogs keeps track of the policies exposing a trajectory catalog, to be listed on the trajectoryz
+// page. The sub-policies of a policy are listed after it, e.g.: "errors/stratified".
+func (tsp *tailSamplingSpanProcessor) setTrajectoryCatalogs(policies []*policy) {
+	var catalogs []policyCatalog
+	for _, p := range policies {
+		if catalog, ok := p.evaluator.(sampling.TrajectoryCatalog); ok {
+			catalogs = append(catalogs, policyCatalog{name: p.name, catalog: catalog})
+			continue
+		}
+		for _, catalog := range sampling.TrajectoryCatalogs(p.evaluator) {
+			catalogs = append(catalogs, policyCatalog{name: p.name + "/" + catalog.PolicyName(), catalog: catalog})
+		}
+	}
+
+	tsp.catalogMux.Lock()
+	defer tsp.catalogMux.Unlock()
+	tsp.catalogs = catalogs
+}
+
+func (tsp *tailSamplingSpanProcessor) trajectories() []policyTrajectories {
+	tsp.catalogMux.Lock()
+	catalogs := tsp.catalogs
+	tsp.catalogMux.Unlock()
+
+	result := make([]policyTrajectories, 0, len(catalogs))
+	for _, c := range catalogs {
+		result = append(result, policyTrajectories{Policy: c.name, Trajectories: c.catalog.Trajectories()})
+	}
+	return result
+}
+
+// handleTrajectoryz serves the trajectories known to the stratified policies, as HTML or, with the format=json query
+// parameter, as JSON.
+func (tsp *tailSamplingSpanProcessor) handleTrajectoryz(w http.ResponseWriter, r *http.Request) {
+	trajectories := tsp.trajectories()
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(trajectories); err != nil {
+			tsp.logger.Debug("Failed to write trajectoryz page", zap.Error(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := trajectoryzTemplate.Execute(w, trajectories); err != nil {
+		tsp.logger.Debug("Failed to write trajectoryz page", zap.Error(err))
+	}
+}
+
+// registerTrajectoryz adds the trajectoryz page of the processor to the pages served by the given extension.
+func (tsp *tailSamplingSpanProcessor) registerTrajectoryz(host component.Host, id component.ID) error {
+	ext, ok := host.GetExtensions()[id]
+	if !ok {
+		return fmt.Errorf("debug extension %q not found", id)
+	}
+	registry, ok := ext.(debugextension.Registry)
+	if !ok {
+		return fmt.Errorf("extension %q does not serve debug pages", id)
+	}
+	registry.RegisterPage(trajectoryzPath+tsp.set.ID.String(), http.HandlerFunc(tsp.handleTrajectoryz))
+	return nil
+}
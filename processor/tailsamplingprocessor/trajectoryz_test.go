@@ -0,0 +1,176 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/extension"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/processor/processortest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/debugextension"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+)
+
+func newTrajectoryzTestProcessor(t *testing.T, debugExtension *component.ID) *tailSamplingSpanProcessor {
+	cfg := Config{
+		DecisionWait: defaultTestDecisionWait,
+		NumTraces:    defaultNumTraces,
+		PolicyCfgs: []PolicyCfg{
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "always",
+					Type: AlwaysSample,
+				},
+			},
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "stratified",
+					Type: StratifiedProbabilistic,
+				},
+			},
+			{
+				sharedPolicyCfg: sharedPolicyCfg{
+					Name: "errors",
+					Type: Composite,
+				},
+				CompositeCfg: CompositeCfg{
+					MaxTotalSpansPerSecond: 1000,
+					PolicyOrder:            []string{"and"},
+					SubPolicyCfg: []CompositeSubPolicyCfg{
+						{
+							sharedPolicyCfg: sharedPolicyCfg{Name: "and", Type: And},
+							AndCfg: AndCfg{SubPolicyCfg: []AndSubPolicyCfg{
+								{sharedPolicyCfg: sharedPolicyCfg{Name: "nested", Type: StratifiedProbabilistic}},
+							}},
+						},
+					},
+				},
+			},
+		},
+		Debug: DebugCfg{Extension: debugExtension},
+		Options: []Option{
+			withDecisionBatcher(newSyncIDBatcher()),
+		},
+	}
+	p, err := newTracesProcessor(context.Background(), processortest.NewNopSettings(metadata.Type), new(consumertest.TracesSink), cfg)
+	require.NoError(t, err)
+	return p.(*tailSamplingSpanProcessor)
+}
+
+func TestTrajectoryz(t *testing.T) {
+	tsp := newTrajectoryzTestProcessor(t, nil)
+	require.NoError(t, tsp.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+
+	for i := byte(1); i <= 3; i++ {
+		require.NoError(t, tsp.ConsumeTraces(context.Background(), simpleTracesWithID(pcommon.TraceID([16]byte{i}))))
+	}
+	tsp.policyTicker.OnTick()
+	tsp.policyTicker.OnTick()
+
+	rec := httptest.NewRecorder()
+	tsp.handleTrajectoryz(rec, httptest.NewRequest(http.MethodGet, trajectoryzPath+"?format=json", http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got []policyTrajectories
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, "stratified", got[0].Policy)
+	require.Len(t, got[0].Trajectories, 1)
+	assert.Equal(t, []string{`":"->":"`}, got[0].Trajectories[0].Edges)
+	assert.Equal(t, sampling.TrajectoryWindowStats{Seen: 3, Sampled: 1, SampledRatio: 1.0 / 3}, got[0].Trajectories[0].Current)
+	// The stratified sub-policies are listed after their policy.
+	assert.Equal(t, "errors/nested", got[1].Policy)
+	require.Len(t, got[1].Trajectories, 1)
+	assert.Equal(t, int64(3), got[1].Trajectories[0].Current.Seen)
+
+	rec = httptest.NewRecorder()
+	tsp.handleTrajectoryz(rec, httptest.NewRequest(http.MethodGet, trajectoryzPath, http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<h2>stratified</h2>")
+	assert.Contains(t, rec.Body.String(), got[0].Trajectories[0].Hash)
+}
+
+// debugHost is a host with the given extensions.
+type debugHost struct {
+	component.Host
+	extensions map[component.ID]component.Component
+}
+
+func (h debugHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
+
+func TestTrajectoryzDebugExtension(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	endpoint := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	factory := debugextension.NewFactory()
+	extCfg := factory.CreateDefaultConfig().(*debugextension.Config)
+	extCfg.TCPAddr.Endpoint = endpoint
+	debugID := component.NewID(debugextension.Type)
+	ext, err := factory.Create(context.Background(), extension.Settings{
+		ID:                debugID,
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+	}, extCfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() {
+		require.NoError(t, ext.Shutdown(context.Background()))
+	}()
+	host := debugHost{Host: componenttest.NewNopHost(), extensions: map[component.ID]component.Component{debugID: ext}}
+
+	tsp := newTrajectoryzTestProcessor(t, &debugID)
+	require.NoError(t, tsp.Start(context.Background(), host))
+	defer func() {
+		require.NoError(t, tsp.Shutdown(context.Background()))
+	}()
+
+	resp, err := http.Get("http://" + endpoint + trajectoryzPath + tsp.set.ID.String())
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "<h2>errors/nested</h2>")
+}
+
+func TestTrajectoryzDebugExtensionErrors(t *testing.T) {
+	storageID := component.MustNewID("storage")
+	host := debugHost{Host: componenttest.NewNopHost(), extensions: map[component.ID]component.Component{
+		storageID: &struct {
+			component.StartFunc
+			component.ShutdownFunc
+		}{},
+	}}
+
+	debugID := component.NewID(debugextension.Type)
+	tsp := newTrajectoryzTestProcessor(t, &debugID)
+	assert.EqualError(t, tsp.Start(context.Background(), host), `debug extension "tail_sampling_debug" not found`)
+	require.NoError(t, tsp.Shutdown(context.Background()))
+
+	tsp = newTrajectoryzTestProcessor(t, &storageID)
+	assert.EqualError(t, tsp.Start(context.Background(), host), `extension "storage" does not serve debug pages`)
+	require.NoError(t, tsp.Shutdown(context.Background()))
+}
@@ -0,0 +1,39 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor // import "github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+
+import (
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/trim"
+)
+
+const defaultTrimSpansPerGroup = 1
+
+// newTrimmer returns the trimmer of the traces sampled by a policy, or nil if trimming is disabled.
+func newTrimmer(cfg TrimCfg) *trim.Trimmer {
+	if cfg.MinSpans <= 0 {
+		return nil
+	}
+	spansPerGroup := cfg.SpansPerGroup
+	if spansPerGroup <= 0 {
+		spansPerGroup = defaultTrimSpansPerGroup
+	}
+	return trim.New(cfg.MinSpans, cfg.LatencyThreshold, spansPerGroup)
+}
+
+// trimSampledTrace trims the spans of a sampled trace, if the policy that sampled it trims large traces.
+func (tsp *tailSamplingSpanProcessor) trimSampledTrace(sampledBy string, policies []*policy, td ptrace.Traces) {
+	for _, p := range policies {
+		if p.name != sampledBy || p.shadow {
+			continue
+		}
+		if p.trimmer != nil {
+			if removed := p.trimmer.Trim(td); removed > 0 {
+				tsp.telemetry.ProcessorTailSamplingTrimmedSpans.Add(tsp.ctx, removed, p.attribute)
+			}
+		}
+		return
+	}
+}
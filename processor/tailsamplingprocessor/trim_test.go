@@ -0,0 +1,136 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package tailsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/sampling"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor/internal/trim"
+)
+
+func TestTrimSampledTraces(t *testing.T) {
+	tests := []struct {
+		name          string
+		trim          TrimCfg
+		expectedSpans int
+	}{
+		{
+			name:          "trimmed",
+			trim:          TrimCfg{MinSpans: 2},
+			expectedSpans: 2,
+		},
+		{
+			name:          "spans per group",
+			trim:          TrimCfg{MinSpans: 2, SpansPerGroup: 2},
+			expectedSpans: 3,
+		},
+		{
+			name:          "not large enough",
+			trim:          TrimCfg{MinSpans: 4},
+			expectedSpans: 4,
+		},
+		{
+			name:          "disabled",
+			expectedSpans: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupTestTelemetry()
+			sink := new(consumertest.TracesSink)
+			policies := []*policy{
+				{
+					name:      "trimming",
+					evaluator: &mockPolicyEvaluator{NextDecision: sampling.Sampled},
+					attribute: metric.WithAttributes(attribute.String("policy", "trimming")),
+					trimmer:   newTrimmer(tt.trim),
+				},
+			}
+			cfg := Config{
+				DecisionWait: defaultTestDecisionWait,
+				NumTraces:    defaultNumTraces,
+				Options: []Option{
+					withDecisionBatcher(newSyncIDBatcher()),
+					withPolicies(policies),
+				},
+			}
+			p, err := newTracesProcessor(context.Background(), s.newSettings(), sink, cfg)
+			require.NoError(t, err)
+			require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+			defer func() {
+				require.NoError(t, p.Shutdown(context.Background()))
+			}()
+
+			traceID := uInt64ToTraceID(1)
+			td := tracesWithParent(traceID, 1, 0)
+			td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).SetName("root")
+			for i := uint64(2); i <= 4; i++ {
+				tracesWithParent(traceID, i, 1).ResourceSpans().MoveAndAppendTo(td.ResourceSpans())
+			}
+			require.NoError(t, p.ConsumeTraces(context.Background(), td))
+
+			tsp := p.(*tailSamplingSpanProcessor)
+			tsp.policyTicker.OnTick()
+			tsp.policyTicker.OnTick()
+
+			require.Equal(t, tt.expectedSpans, sink.SpanCount())
+			if tt.expectedSpans == 4 {
+				return
+			}
+
+			root := findSpan(sink.AllTraces()[0], uInt64ToSpanID(1))
+			trimmed, ok := root.Attributes().Get(trim.TrimmedSpansAttribute)
+			require.True(t, ok)
+			assert.Equal(t, int64(4-tt.expectedSpans), trimmed.Int())
+
+			var md metricdata.ResourceMetrics
+			require.NoError(t, s.reader.Collect(context.Background(), &md))
+			expected := metricdata.Metrics{
+				Name:        "otelcol_processor_tail_sampling_trimmed_spans",
+				Description: "Count of spans removed from the large traces sampled by a policy, when trimming them.",
+				Unit:        "{spans}",
+				Data: metricdata.Sum[int64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints: []metricdata.DataPoint[int64]{
+						{
+							Attributes: attribute.NewSet(attribute.String("policy", "trimming")),
+							Value:      int64(4 - tt.expectedSpans),
+						},
+					},
+				},
+			}
+			metricdatatest.AssertEqual(t, expected, s.getMetric(expected.Name, md), metricdatatest.IgnoreTimestamp())
+		})
+	}
+}
+
+func findSpan(td ptrace.Traces, id pcommon.SpanID) ptrace.Span {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).ScopeSpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if spans.At(k).SpanID() == id {
+					return spans.At(k)
+				}
+			}
+		}
+	}
+	return ptrace.NewSpan()
+}